@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/k1LoW/mo/internal/archive"
+	"github.com/k1LoW/mo/internal/server"
+)
+
+// archiveGroup returns the group an archive opens in unless --target is
+// given: its file name without the archive extension.
+func archiveGroup(p, fallback string) string {
+	name := filepath.Base(p)
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			name = name[:len(name)-len(ext)]
+			break
+		}
+	}
+	group, err := server.ResolveGroupName(name)
+	if err != nil {
+		return fallback
+	}
+	return group
+}
+
+// resolveArchiveArgs separates the zip and tar files in args from the other
+// arguments. Each archive opens in group, or in a group named after it when
+// namedGroups is set.
+func resolveArchiveArgs(args []string, group string, namedGroups bool) ([]server.ArchiveData, []string, error) {
+	var archives []server.ArchiveData
+	var rest []string
+	for _, arg := range args {
+		if !archive.IsArchive(arg) {
+			rest = append(rest, arg)
+			continue
+		}
+		fi, err := os.Stat(arg)
+		if err != nil || !fi.Mode().IsRegular() {
+			// Leave it to resolveArgs, which reports missing files.
+			rest = append(rest, arg)
+			continue
+		}
+		absPath, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		g := group
+		if namedGroups {
+			g = archiveGroup(absPath, group)
+		}
+		archives = append(archives, server.ArchiveData{
+			Group:      g,
+			Path:       absPath,
+			Extensions: extensions,
+		})
+	}
+	return archives, rest, nil
+}
+
+// filterValidArchives drops restored archives that are gone.
+func filterValidArchives(archives []server.ArchiveData) []server.ArchiveData {
+	var valid []server.ArchiveData
+	for _, ad := range archives {
+		if _, err := os.Stat(ad.Path); err != nil {
+			slog.Info("skipping missing archive from backup", "path", ad.Path)
+			continue
+		}
+		valid = append(valid, ad)
+	}
+	return valid
+}
+
+// postArchive mounts an archive on a running mo server and returns the
+// deeplinks of the documents inside.
+func postArchive(client *http.Client, addr string, ad server.ArchiveData) ([]deeplinkEntry, error) {
+	body, err := json.Marshal(map[string]any{
+		"path":       ad.Path,
+		"group":      ad.Group,
+		"extensions": ad.Extensions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Post(fmt.Sprintf("http://%s/_/api/archives", addr), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if errText := strings.TrimSpace(string(errBody)); errText != "" {
+			return nil, fmt.Errorf("failed to open %s: %s", ad.Path, errText)
+		}
+		return nil, fmt.Errorf("failed to open %s: %s", ad.Path, resp.Status)
+	}
+	var archiveResp server.AddArchiveResponse
+	if err := json.NewDecoder(resp.Body).Decode(&archiveResp); err != nil {
+		return nil, err
+	}
+	entries := make([]deeplinkEntry, 0, len(archiveResp.Files))
+	for _, f := range archiveResp.Files {
+		entries = append(entries, deeplinkEntry{
+			URL:  buildDeeplink(addr, ad.Group, f.ID),
+			Name: f.Name,
+		})
+	}
+	return entries, nil
+}
+
+// postArchives mounts archives on a running mo server and returns the
+// deeplinks of the documents inside and the number of archives accepted.
+func postArchives(client *http.Client, addr string, archives []server.ArchiveData) ([]deeplinkEntry, int) {
+	var entries []deeplinkEntry
+	mounted := 0
+	for _, ad := range archives {
+		e, err := postArchive(client, addr, ad)
+		if err != nil {
+			slog.Warn("failed to add archive", "path", ad.Path, "error", err)
+			fmt.Fprintf(os.Stderr, "mo: %v\n", err)
+			continue
+		}
+		entries = append(entries, e...)
+		mounted++
+	}
+	return entries, mounted
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/k1LoW/mo/internal/config"
+	"github.com/k1LoW/mo/internal/server"
+	"github.com/k1LoW/mo/internal/session"
+	"github.com/spf13/cobra"
+)
+
+// completionAddr returns the address of the server the command line being
+// completed talks to. run is not called when completing, so the user config
+// is applied here.
+func completionAddr(cmd *cobra.Command) string {
+	if cfg, err := config.Load(); err == nil {
+		applyConfig(cfg, cmd.Flags().Changed)
+	}
+	return net.JoinHostPort(strings.Trim(bind, "[]"), strconv.Itoa(port))
+}
+
+// completeTarget completes --target with the groups of the running server.
+func completeTarget(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	groups, err := fetchGroups(completionAddr(cmd))
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	var names []string
+	for _, g := range groups {
+		if strings.HasPrefix(g.Name, toComplete) {
+			names = append(names, g.Name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSession completes --load-session and --delete-session with the
+// saved sessions.
+func completeSession(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	infos, err := session.List()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name, toComplete) {
+			names = append(names, info.Name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeArgs completes the arguments of --close with the files open in the
+// target group, those of --unwatch with its watch patterns, and those of
+// --move and --copy with both, from the source group. Other arguments, and
+// all of them when no server is running, complete as files.
+func completeArgs(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	transfer := moveFiles || copyFiles
+	if !closeFiles && !unwatchMode && !transfer {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	addr := completionAddr(cmd)
+	name := target
+	if transfer {
+		name = transferSource()
+	}
+	group, err := server.ResolveGroupName(name)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+
+	var paths []string
+	if closeFiles || transfer {
+		groups, err := fetchGroups(addr)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		for _, g := range groups {
+			if g.Name != group {
+				continue
+			}
+			for _, f := range g.Files {
+				if !f.Uploaded && f.Path != "" {
+					paths = append(paths, f.Path)
+				}
+			}
+		}
+	}
+	if transfer {
+		patterns, _ := fetchRegisteredPatterns(addr, group)
+		paths = append(paths, patterns...)
+	} else if unwatchMode {
+		patterns, err := fetchRegisteredPatterns(addr, group)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		paths = patterns
+	}
+	return completionCandidates(paths, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completionCandidates returns the paths that start with toComplete, relative
+// to the working directory when they are inside it.
+func completionCandidates(paths []string, toComplete string) []string {
+	cwd, _ := os.Getwd()
+	var result []string
+	for _, p := range paths {
+		c := p
+		if cwd != "" {
+			if rel, err := filepath.Rel(cwd, p); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				c = rel
+			}
+		}
+		if strings.HasPrefix(c, toComplete) {
+			result = append(result, c)
+		} else if strings.HasPrefix(p, toComplete) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// fetchGroups returns the groups of the mo server at addr, failing fast when
+// there is none.
+func fetchGroups(addr string) ([]server.Group, error) {
+	client := &http.Client{Timeout: probeTimeoutFast}
+	resp, err := client.Get(fmt.Sprintf("http://%s/_/api/groups", addr))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server on %s returned %s", addr, resp.Status)
+	}
+	var groups []server.Group
+	if err := json.NewDecoder(resp.Body).Decode(&groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/k1LoW/mo/internal/config"
+	"github.com/k1LoW/mo/internal/server"
+)
+
+// applyConfig sets the options not given on the command line from cfg, the
+// user config file with its environment overrides. changed reports whether a
+// flag was given.
+func applyConfig(cfg config.Config, changed func(name string) bool) {
+	if cfg.Port != "" && !changed("port") {
+		// Validated by config.Load; auto is resolved with --port auto.
+		(&portValue{p: &port}).Set(cfg.Port) //nolint:errcheck
+	}
+	if cfg.Bind != "" && !changed("bind") {
+		bind = cfg.Bind
+	}
+	if !changed("open") && !changed("no-open") {
+		switch cfg.Open {
+		case config.OpenAlways:
+			open = true
+		case config.OpenNever:
+			noOpen = true
+		}
+	}
+	if cfg.Target != "" && !changed("target") {
+		target = cfg.Target
+	}
+	targetSet = changed("target") || cfg.Target != ""
+	if len(cfg.Extensions) > 0 && !changed("ext") {
+		extensions = cfg.Extensions
+	}
+	if len(cfg.Renderers) > 0 && !changed("renderer") {
+		rendererSpecs = cfg.Renderers
+	}
+	ignorePatterns = cfg.Ignore
+	uiDefaults = server.UIDefaults{Theme: cfg.Theme, FontSize: cfg.FontSize}
+	backupRetention = time.Duration(cfg.BackupRetention)
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/k1LoW/mo/internal/backup"
+	"github.com/k1LoW/mo/internal/server"
+)
+
+const (
+	// followFlushInterval is how often complete lines read with --follow are
+	// sent to the server.
+	followFlushInterval = 200 * time.Millisecond
+	// followMaxPending is how much input is held back waiting for the end of
+	// a line before it is sent anyway.
+	followMaxPending = 64 << 10
+)
+
+// doFollow streams r into a new uploaded entry of the mo server at addr,
+// starting a server when none is running, until r is closed or ctx is done.
+func doFollow(ctx context.Context, addr string, r io.Reader) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var client *http.Client
+	isNewGroup := true
+	if result, err := probeServer(addr, probeTimeoutFast); err == nil {
+		client = result.client
+		isNewGroup = !slices.Contains(result.groups, target)
+	} else {
+		if !isLoopbackBind(bind) && !dangerouslyAllowRemoteAccess {
+			return fmt.Errorf("cannot use redirected stdin with non-loopback bind without --dangerously-allow-remote-access")
+		}
+		var rd server.RestoreData
+		if err := backup.Load(port, &rd); err != nil {
+			slog.Warn("failed to load backup", "error", err)
+		}
+		pid, _, err := spawnBackground(addr, rd)
+		switch {
+		case err == nil:
+			fmt.Fprintf(os.Stderr, "mo: serving at http://%s (pid %d)\n", addr, pid)
+		case errors.Is(err, errServerConflict):
+			// Another mo server won the port; follow into it.
+		default:
+			return err
+		}
+		client = &http.Client{Timeout: probeTimeoutDefault}
+	}
+
+	name := uploadName
+	if name == "" {
+		name = stdinName(strconv.FormatInt(time.Now().UnixNano(), 10))
+	}
+	entry, err := postStream(client, addr, target, name, maxLines)
+	if err != nil {
+		return err
+	}
+	emitServeOutput(addr, []deeplinkEntry{entry}, false)
+	fmt.Fprintf(os.Stderr, "mo: following stdin into http://%s (Ctrl+C to stop)\n", addr)
+	if isNewGroup || open {
+		openURL(entry.URL)
+	}
+	return followStream(ctx, client, addr, entry, r)
+}
+
+// followStream sends what is read from r to the stream entry, a line at a
+// time, and ends the stream once r is closed or ctx is done.
+func followStream(ctx context.Context, client *http.Client, addr string, entry deeplinkEntry, r io.Reader) error {
+	chunks := make(chan []byte)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(chunks)
+		buf := make([]byte, 32<<10)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				select {
+				case chunks <- bytes.Clone(buf[:n]):
+				case <-done:
+					return
+				}
+			}
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					readErr <- fmt.Errorf("failed to read stdin: %w", err)
+				}
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(followFlushInterval)
+	defer ticker.Stop()
+	var pending []byte
+	for {
+		select {
+		case b, ok := <-chunks:
+			if !ok {
+				if err := postAppend(client, addr, entry, pending, true); err != nil {
+					return err
+				}
+				select {
+				case err := <-readErr:
+					return err
+				default:
+					return nil
+				}
+			}
+			pending = append(pending, b...)
+			if len(pending) >= followMaxPending {
+				if err := postAppend(client, addr, entry, pending, false); err != nil {
+					return err
+				}
+				pending = nil
+			}
+		case <-ticker.C:
+			// Hold back a partial line until it is complete.
+			i := bytes.LastIndexByte(pending, '\n')
+			if i < 0 {
+				continue
+			}
+			if err := postAppend(client, addr, entry, pending[:i+1], false); err != nil {
+				return err
+			}
+			pending = slices.Clone(pending[i+1:])
+		case <-ctx.Done():
+			return postAppend(client, addr, entry, pending, true)
+		}
+	}
+}
+
+// postStream adds an empty stream entry named name to group on the mo server
+// at addr.
+func postStream(client *http.Client, addr, group, name string, maxLines int) (deeplinkEntry, error) {
+	body, err := json.Marshal(map[string]any{"name": name, "maxLines": maxLines})
+	if err != nil {
+		return deeplinkEntry{}, err
+	}
+	resp, err := client.Post(
+		fmt.Sprintf("http://%s/_/api/groups/%s/streams", addr, url.PathEscape(group)),
+		"application/json",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return deeplinkEntry{}, fmt.Errorf("failed to add stream: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return deeplinkEntry{}, fmt.Errorf("failed to add stream: %s", responseError(resp))
+	}
+	var fe server.FileEntry
+	if err := json.NewDecoder(resp.Body).Decode(&fe); err != nil {
+		return deeplinkEntry{}, err
+	}
+	return deeplinkEntry{
+		URL:   buildDeeplink(addr, group, fe.ID),
+		Name:  fe.Name,
+		Group: group,
+		ID:    fe.ID,
+	}, nil
+}
+
+// postAppend appends content to the stream entry, ending it if end is set.
+func postAppend(client *http.Client, addr string, entry deeplinkEntry, content []byte, end bool) error {
+	if len(content) == 0 && !end {
+		return nil
+	}
+	body, err := json.Marshal(map[string]any{"content": string(content), "end": end})
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(
+		fmt.Sprintf("http://%s/_/api/groups/%s/files/%s/append", addr, url.PathEscape(entry.Group), url.PathEscape(entry.ID)),
+		"application/json",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to send stdin: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("%s was closed on the server", entry.Name)
+	default:
+		return fmt.Errorf("failed to send stdin: %s", responseError(resp))
+	}
+}
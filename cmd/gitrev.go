@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/k1LoW/mo/internal/git"
+	"github.com/k1LoW/mo/internal/server"
+)
+
+// splitRevisionArg reports whether arg refers to a file at a git revision,
+// either because --rev is set or because it has the form path@rev. An
+// existing file whose name contains "@" is always taken as-is. The revision
+// may contain "@" itself (e.g. HEAD@{1}, main@{upstream}): arg is split at
+// the first "@" after an existing file, or else at the last "@" that does
+// not start a reflog suffix.
+func splitRevisionArg(arg, rev string) (path, argRev string, ok bool) {
+	if isRemoteArg(arg) {
+		return "", "", false
+	}
+	if rev != "" {
+		return arg, rev, true
+	}
+	if _, err := os.Stat(arg); err == nil {
+		return "", "", false
+	}
+	split := -1
+	for i := 1; i < len(arg)-1; i++ {
+		if arg[i] != '@' || arg[i+1] == '{' {
+			continue
+		}
+		// "node_modules/@scope/..." and similar are not revisions.
+		if arg[i-1] == '/' || arg[i-1] == filepath.Separator {
+			continue
+		}
+		split = i
+		if fi, err := os.Stat(arg[:i]); err == nil && fi.Mode().IsRegular() {
+			break
+		}
+	}
+	if split < 0 {
+		return "", "", false
+	}
+	return arg[:split], arg[split+1:], true
+}
+
+// resolveRevisionArgs reads the arguments that refer to files at a git
+// revision and returns them as read-only uploaded entries for group. The
+// remaining arguments are returned unchanged.
+func resolveRevisionArgs(args []string, rev, group string) ([]server.UploadedFileData, []string, error) {
+	var files []server.UploadedFileData
+	var rest []string
+	for _, arg := range args {
+		path, argRev, ok := splitRevisionArg(arg, rev)
+		if !ok {
+			rest = append(rest, arg)
+			continue
+		}
+		// Globs are expanded against the work tree.
+		if hasGlobChars(path) {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return nil, nil, fmt.Errorf("cannot resolve path %s: %w", path, err)
+			}
+			matches, err := expandGlobPattern(abs)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(matches) == 0 {
+				return nil, nil, fmt.Errorf("no files matched %s", path)
+			}
+			for _, m := range matches {
+				f, err := readRevisionFile(m, argRev, group)
+				if err != nil {
+					return nil, nil, err
+				}
+				files = append(files, f)
+			}
+			continue
+		}
+		f, err := readRevisionFile(path, argRev, group)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rest, nil
+}
+
+// readRevisionFile reads path as of rev from the git repository containing it.
+// The file does not need to exist in the work tree.
+func readRevisionFile(path, rev, group string) (server.UploadedFileData, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return server.UploadedFileData{}, fmt.Errorf("cannot resolve path %s: %w", path, err)
+	}
+	if fi, err := os.Stat(abs); err == nil && fi.IsDir() {
+		return server.UploadedFileData{}, fmt.Errorf("%s is a directory; pass files or globs with a revision", path)
+	}
+
+	// The file may have been deleted since rev, so look up the repository
+	// from the closest existing parent directory.
+	dir := filepath.Dir(abs)
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	root, err := git.Root(dir)
+	if err != nil {
+		return server.UploadedFileData{}, fmt.Errorf("%s is not in a git repository: %w", path, err)
+	}
+	commit, err := git.ResolveCommit(root, rev)
+	if err != nil {
+		return server.UploadedFileData{}, err
+	}
+	rel, err := git.RelPath(root, abs)
+	if err != nil {
+		return server.UploadedFileData{}, err
+	}
+	content, err := git.Show(root, commit, rel)
+	if err != nil {
+		if errors.Is(err, git.ErrNotFound) {
+			return server.UploadedFileData{}, fmt.Errorf("%s does not exist at %s", path, rev)
+		}
+		return server.UploadedFileData{}, err
+	}
+	if bytes.IndexByte(content, 0) >= 0 {
+		return server.UploadedFileData{}, fmt.Errorf("%s@%s: %w", path, rev, server.ErrBinaryFile)
+	}
+
+	return server.UploadedFileData{
+		Name:    filepath.Base(abs) + "@" + rev,
+		Content: string(content),
+		Group:   group,
+		Git: &server.GitSource{
+			Root:   root,
+			Commit: commit,
+			Path:   rel,
+			Rev:    rev,
+		},
+	}, nil
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"text/tabwriter"
+)
+
+// jsonListEntry is a file in the JSON output of --list.
+type jsonListEntry struct {
+	Group    string `json:"group"`
+	Name     string `json:"name"`
+	Title    string `json:"title"`
+	ID       string `json:"id"`
+	Path     string `json:"path"`
+	Uploaded bool   `json:"uploaded"`
+	URL      string `json:"url"`
+}
+
+// doList prints the files open on the mo server at addr, in every group or
+// only in group when it is not empty.
+func doList(addr, group string) error {
+	client := &http.Client{Timeout: probeTimeoutDefault}
+	resp, err := client.Get(fmt.Sprintf("http://%s/_/api/status", addr))
+	if err != nil {
+		return fmt.Errorf("no mo server found on %s", addr)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server on %s returned %s", addr, resp.Status)
+	}
+	var status statusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil || status.Version == "" {
+		return fmt.Errorf("server on %s is not a mo instance", addr)
+	}
+
+	entries, err := listEntries(addr, &status, group)
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		writeJSON(entries)
+		return nil
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, "mo: no files open")
+		return nil
+	}
+	printList(os.Stdout, entries)
+	return nil
+}
+
+// listEntries returns the files of status in the order the sidebar shows
+// them, with names disambiguated within each group.
+func listEntries(addr string, status *statusResponse, group string) ([]jsonListEntry, error) {
+	entries := []jsonListEntry{}
+	found := false
+	for _, g := range status.Groups {
+		if group != "" && g.Name != group {
+			continue
+		}
+		found = true
+		links := make([]deeplinkEntry, len(g.Files))
+		for i, f := range g.Files {
+			links[i] = deeplinkEntry{Path: f.Path, Name: f.Name}
+		}
+		names := deeplinkDisplayNames(links)
+		for i, f := range g.Files {
+			entries = append(entries, jsonListEntry{
+				Group:    g.Name,
+				Name:     names[i],
+				Title:    f.Title,
+				ID:       f.ID,
+				Path:     f.Path,
+				Uploaded: f.Uploaded,
+				URL:      buildDeeplink(addr, g.Name, f.ID),
+			})
+		}
+	}
+	if group != "" && !found {
+		return nil, fmt.Errorf("no group %q on http://%s", group, addr)
+	}
+	return entries, nil
+}
+
+// printList writes entries to w as a table.
+func printList(w io.Writer, entries []jsonListEntry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GROUP\tID\tNAME\tTITLE\tPATH\tURL")
+	for _, e := range entries {
+		title := e.Title
+		if title == "" {
+			title = "-"
+		}
+		path := e.Path
+		if e.Uploaded {
+			path = "(uploaded)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Group, e.ID, e.Name, title, path, e.URL)
+	}
+	tw.Flush() //nolint:errcheck
+}
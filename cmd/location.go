@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/k1LoW/mo/internal/server"
+)
+
+// locations holds the location given for a file on the command line
+// (README.md#installation, spec.md:120), by absolute path.
+var locations map[string]server.Location
+
+var (
+	lineSuffixRe = regexp.MustCompile(`^(.+):(\d+)$`)
+	lineAnchorRe = regexp.MustCompile(`^L(\d+)$`)
+)
+
+// splitLocations takes the heading anchors and line numbers off the file
+// arguments in args. A suffix is only taken off when the argument as given
+// does not exist but the file without it does, so names containing # or :
+// still open as they are.
+func splitLocations(args []string) ([]string, map[string]server.Location, error) {
+	var locs map[string]server.Location
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		p, loc, ok, err := splitLocation(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		rest = append(rest, p)
+		if !ok {
+			continue
+		}
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot resolve path %s: %w", p, err)
+		}
+		if locs == nil {
+			locs = make(map[string]server.Location)
+		}
+		locs[abs] = loc
+	}
+	return rest, locs, nil
+}
+
+func splitLocation(arg string) (string, server.Location, bool, error) {
+	if isRemoteArg(arg) || hasGlobChars(arg) || isFile(arg) {
+		return arg, server.Location{}, false, nil
+	}
+	if i := strings.LastIndex(arg, "#"); i > 0 && i < len(arg)-1 && isFile(arg[:i]) {
+		fragment := arg[i+1:]
+		if m := lineAnchorRe.FindStringSubmatch(fragment); m != nil {
+			line, err := parseLine(m[1], arg)
+			return arg[:i], server.Location{Line: line}, err == nil, err
+		}
+		return arg[:i], server.Location{Anchor: fragment}, true, nil
+	}
+	if m := lineSuffixRe.FindStringSubmatch(arg); m != nil && isFile(m[1]) {
+		line, err := parseLine(m[2], arg)
+		return m[1], server.Location{Line: line}, err == nil, err
+	}
+	return arg, server.Location{}, false, nil
+}
+
+func parseLine(s, arg string) (int, error) {
+	line, err := strconv.Atoi(s)
+	if err != nil || line < 1 {
+		return 0, fmt.Errorf("invalid line number in %s", arg)
+	}
+	return line, nil
+}
+
+func isFile(p string) bool {
+	fi, err := os.Stat(p)
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// locationFragment returns the URL fragment the SPA scrolls to: #L120 for a
+// line, or the heading anchor.
+func locationFragment(loc server.Location) string {
+	if loc.Line > 0 {
+		return fmt.Sprintf("#L%d", loc.Line)
+	}
+	if loc.Anchor != "" {
+		return "#" + url.PathEscape(loc.Anchor)
+	}
+	return ""
+}
+
+// locateDeeplinks adds the locations given on the command line to the
+// deeplinks of their files, and returns the first located entry, or nil.
+func locateDeeplinks(entries []deeplinkEntry) *deeplinkEntry {
+	var first *deeplinkEntry
+	for i := range entries {
+		loc, ok := locations[entries[i].Path]
+		if !ok || entries[i].Path == "" {
+			continue
+		}
+		entries[i].URL += locationFragment(loc)
+		if first == nil {
+			first = &entries[i]
+		}
+	}
+	return first
+}
+
+// showLocation brings a browser to located, the first file given with a
+// location. A browser connected to the server at addr is navigated over SSE;
+// otherwise the deeplink opens in a new tab.
+func showLocation(client *http.Client, addr string, located *deeplinkEntry) {
+	delivered, err := postNavigate(client, addr, located)
+	if err != nil {
+		slog.Warn("failed to navigate", "path", located.Path, "error", err)
+	}
+	if !delivered {
+		openURL(located.URL)
+	}
+}
+
+// postNavigate asks the server at addr to navigate its browsers to e and
+// reports whether a browser was connected to receive it.
+func postNavigate(client *http.Client, addr string, e *deeplinkEntry) (bool, error) {
+	body, err := json.Marshal(locations[e.Path])
+	if err != nil {
+		return false, err
+	}
+	resp, err := client.Post(
+		fmt.Sprintf("http://%s/_/api/groups/%s/files/%s/navigate", addr, url.PathEscape(e.Group), url.PathEscape(e.ID)),
+		"application/json",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.New(responseError(resp))
+	}
+	var nav server.NavigateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&nav); err != nil {
+		return false, err
+	}
+	return nav.Delivered, nil
+}
@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// transferSource returns the group --move and --copy take files from: --from,
+// or the --target group.
+func transferSource() string {
+	if moveFrom != "" {
+		return moveFrom
+	}
+	return target
+}
+
+// transferItems are the files and watch patterns of a group selected by
+// --move or --copy arguments.
+type transferItems struct {
+	files    []statusFileResponse
+	patterns []string
+}
+
+// resolveTransferArgs selects the files and patterns of g named by args: file
+// IDs, paths, globs matched against the open files, or registered watch
+// patterns (a directory stands for its pattern as in --unwatch). Files are
+// returned in the order of the group, without those a selected pattern
+// already covers.
+func resolveTransferArgs(args []string, g statusGroupResponse) (transferItems, error) {
+	var items transferItems
+	picked := make(map[string]bool)
+	var joinedErr error
+	for _, arg := range args {
+		if slices.ContainsFunc(g.Files, func(f statusFileResponse) bool { return f.ID == arg }) {
+			picked[arg] = true
+			continue
+		}
+		absPath, err := filepath.Abs(arg)
+		if err != nil {
+			joinedErr = errors.Join(joinedErr, fmt.Errorf("cannot resolve path %s: %w", arg, err))
+			continue
+		}
+		pattern := absPath
+		if fi, err := os.Stat(absPath); err == nil && fi.IsDir() {
+			pattern = filepath.Join(absPath, markdownGlobFor(recursive))
+		}
+		if slices.Contains(g.Patterns, pattern) {
+			if !slices.Contains(items.patterns, pattern) {
+				items.patterns = append(items.patterns, pattern)
+			}
+			continue
+		}
+		if pattern != absPath || hasGlobChars(arg) {
+			matched := false
+			for _, f := range g.Files {
+				if ok, _ := doublestar.Match(filepath.ToSlash(pattern), filepath.ToSlash(f.Path)); ok && !f.Uploaded {
+					picked[f.ID] = true
+					matched = true
+				}
+			}
+			if !matched {
+				joinedErr = errors.Join(joinedErr, fmt.Errorf("no files in group %q match %s", g.Name, arg))
+			}
+			continue
+		}
+		found := false
+		for _, f := range g.Files {
+			if f.Path == absPath {
+				picked[f.ID] = true
+				found = true
+			}
+		}
+		if !found {
+			joinedErr = errors.Join(joinedErr, fmt.Errorf("file %q not found in group %q (use --list to see files)", absPath, g.Name))
+		}
+	}
+
+	for _, f := range g.Files {
+		if !picked[f.ID] {
+			continue
+		}
+		covered := slices.ContainsFunc(items.patterns, func(p string) bool {
+			ok, _ := doublestar.Match(filepath.ToSlash(p), filepath.ToSlash(f.Path))
+			return ok && !f.Uploaded
+		})
+		if !covered {
+			items.files = append(items.files, f)
+		}
+	}
+	return items, joinedErr
+}
+
+// doTransfer moves, or with copyMode copies, the files and watch patterns
+// named by args from one group to another on the server at addr. Files keep
+// their relative order.
+func doTransfer(addr string, args []string, from, to string, copyMode bool) error {
+	result, err := probeServer(addr)
+	if err != nil {
+		return err
+	}
+	resp, err := result.client.Get(fmt.Sprintf("http://%s/_/api/status", addr))
+	if err != nil {
+		return fmt.Errorf("failed to get server status: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return fmt.Errorf("failed to get server status: %s", responseError(resp))
+	}
+	var status statusResponse
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to decode status: %w", err)
+	}
+	i := slices.IndexFunc(status.Groups, func(g statusGroupResponse) bool { return g.Name == from })
+	if i < 0 {
+		return fmt.Errorf("no group %q on http://%s", from, addr)
+	}
+
+	items, joinedErr := resolveTransferArgs(args, status.Groups[i])
+	verb := "moved"
+	if copyMode {
+		verb = "copied"
+	}
+
+	var done []string
+	for _, p := range items.patterns {
+		if err := transferPattern(result.client, addr, p, from, to, copyMode); err != nil {
+			joinedErr = errors.Join(joinedErr, err)
+			continue
+		}
+		done = append(done, p)
+	}
+	patternsDone := len(done)
+	for _, f := range items.files {
+		if err := transferFile(result.client, addr, f, from, to, copyMode); err != nil {
+			joinedErr = errors.Join(joinedErr, err)
+			continue
+		}
+		if f.Uploaded {
+			done = append(done, f.Name)
+		} else {
+			done = append(done, f.Path)
+		}
+	}
+
+	if len(done) > 0 {
+		for _, name := range displayNames(done) {
+			fmt.Printf("  %s\n", name)
+		}
+		fmt.Fprintf(os.Stderr, "mo: %s %d file(s) and %d pattern(s) from %q to %q on http://%s\n", verb, len(done)-patternsDone, patternsDone, from, to, addr)
+	}
+	return joinedErr
+}
+
+func transferFile(client *http.Client, addr string, f statusFileResponse, from, to string, copyMode bool) error {
+	if copyMode {
+		if f.Uploaded {
+			return fmt.Errorf("cannot copy %s: only files on disk can be copied", f.Name)
+		}
+		if entries := postFiles(client, addr, to, []string{f.Path}); len(entries) == 0 {
+			return fmt.Errorf("failed to copy %q (check log file for details)", f.Path)
+		}
+		return nil
+	}
+	body, err := json.Marshal(map[string]string{"group": to})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut,
+		fmt.Sprintf("http://%s/_/api/groups/%s/files/%s/group", addr, url.PathEscape(from), url.PathEscape(f.ID)),
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to move %q: %w", f.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to move %q: %s", f.Name, responseError(resp))
+	}
+	return nil
+}
+
+func transferPattern(client *http.Client, addr, pattern, from, to string, copyMode bool) error {
+	body, err := json.Marshal(map[string]any{
+		"pattern": pattern,
+		"from":    from,
+		"to":      to,
+		"copy":    copyMode,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("http://%s/_/api/patterns/group", addr), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to move pattern %q: %w", pattern, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to move pattern %q: %s", pattern, responseError(resp))
+	}
+	return nil
+}
+
+// responseError returns the error text of a failed API response.
+func responseError(resp *http.Response) string {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if text := strings.TrimSpace(string(b)); text != "" {
+		return text
+	}
+	return resp.Status
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// autoPort is the port value of --port auto: a free port is picked when the
+// server starts.
+const autoPort = 0
+
+// portValue is the value of --port: a port number or "auto".
+type portValue struct {
+	p *int
+}
+
+func newPortValue(def int, p *int) *portValue {
+	*p = def
+	return &portValue{p: p}
+}
+
+func (v *portValue) Set(s string) error {
+	if strings.EqualFold(s, "auto") {
+		*v.p = autoPort
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("must be a port number or auto")
+	}
+	*v.p = n
+	return nil
+}
+
+func (v *portValue) String() string {
+	if *v.p == autoPort {
+		return "auto"
+	}
+	return strconv.Itoa(*v.p)
+}
+
+func (v *portValue) Type() string {
+	return "port"
+}
+
+// pickFreePort returns a port that is free on bind.
+func pickFreePort(bind string) (int, error) {
+	ln, err := net.Listen("tcp", net.JoinHostPort(bind, "0"))
+	if err != nil {
+		return 0, fmt.Errorf("cannot find a free port on %s: %w", bind, err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
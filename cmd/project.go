@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/k1LoW/mo/internal/config"
+	"github.com/k1LoW/mo/internal/project"
+	"github.com/k1LoW/mo/internal/server"
+)
+
+// projectGroup is a group of a project file with its paths resolved.
+type projectGroup struct {
+	name     string
+	files    []string
+	patterns []string
+	// ignore holds absolute ignore patterns (see config.AbsIgnore).
+	ignore []string
+}
+
+// findProject loads the project file of the current directory, if any.
+func findProject() (*project.Project, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	p, err := project.Find(cwd)
+	if err != nil || p == "" {
+		return nil, err
+	}
+	return project.Load(p)
+}
+
+// resolveProject resolves the files and watch patterns of the groups of
+// proj. An entry that cannot be resolved is reported and skipped, so one
+// stale path does not keep the rest of the session from opening.
+func resolveProject(proj *project.Project) ([]projectGroup, error) {
+	groups := make([]projectGroup, 0, len(proj.Groups))
+	for _, g := range proj.Groups {
+		name, err := server.ResolveGroupName(g.Name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group name %q in %s: %w", g.Name, project.FileName, err)
+		}
+		pg := projectGroup{
+			name:   name,
+			ignore: config.AbsIgnore(proj.Dir, append(slices.Clone(proj.Ignore), g.Ignore...)),
+		}
+		for _, f := range g.Files {
+			abs := projectPath(proj.Dir, f)
+			files, _, _, err := resolveArgs([]string{abs}, false, false)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "mo: %s: %v\n", project.FileName, err)
+				continue
+			}
+			expanded := len(files) != 1 || files[0] != abs
+			for _, p := range files {
+				// Files listed by name open even when an ignore rule matches.
+				if expanded && config.IgnoredAbs(pg.ignore, p) {
+					continue
+				}
+				if !slices.Contains(pg.files, p) {
+					pg.files = append(pg.files, p)
+				}
+			}
+		}
+		for _, w := range g.Watch {
+			abs := projectPath(proj.Dir, w)
+			files, patterns, _, err := resolveArgs([]string{abs}, true, false)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "mo: %s: %v\n", project.FileName, err)
+				continue
+			}
+			if len(files) > 0 {
+				fmt.Fprintf(os.Stderr, "mo: %s: %s is not a glob pattern or directory\n", project.FileName, w)
+				continue
+			}
+			pg.patterns = append(pg.patterns, patterns...)
+		}
+		groups = append(groups, pg)
+	}
+	return groups, nil
+}
+
+func projectPath(dir, p string) string {
+	if filepath.IsAbs(p) {
+		return filepath.Clean(p)
+	}
+	return filepath.Join(dir, filepath.FromSlash(p))
+}
+
+// patternIgnores returns the ignore patterns of the watch patterns of
+// groups, by group and pattern.
+func patternIgnores(groups []projectGroup) map[string]map[string][]string {
+	result := make(map[string]map[string][]string)
+	for _, pg := range groups {
+		if len(pg.ignore) == 0 || len(pg.patterns) == 0 {
+			continue
+		}
+		result[pg.name] = make(map[string][]string, len(pg.patterns))
+		for _, pat := range pg.patterns {
+			result[pg.name][pat] = pg.ignore
+		}
+	}
+	return result
+}
+
+// postProject sets up the groups of a project file on a running mo server
+// and returns the deeplinks of the files opened and the number of files and
+// patterns accepted.
+func postProject(client *http.Client, addr string, groups []projectGroup) ([]deeplinkEntry, int) {
+	ignores := patternIgnores(groups)
+	var deeplinks []deeplinkEntry
+	added := 0
+	for _, pg := range groups {
+		entries := postFiles(client, addr, pg.name, pg.files)
+		deeplinks = append(deeplinks, entries...)
+		added += len(entries)
+		patternEntries, patternsAdded := postPatterns(client, addr, pg.name, pg.patterns, ignores[pg.name])
+		deeplinks = append(deeplinks, patternEntries...)
+		added += patternsAdded
+		if len(pg.files) > 0 {
+			if err := reorderGroup(client, addr, pg.name, pg.files); err != nil {
+				fmt.Fprintf(os.Stderr, "mo: failed to order group %s: %v\n", pg.name, err)
+			}
+		}
+	}
+	return deeplinks, added
+}
+
+// reorderGroup moves the files at paths to the top of group on a running mo
+// server, in the order given, keeping the order of the other files.
+func reorderGroup(client *http.Client, addr, group string, paths []string) error {
+	resp, err := client.Get(fmt.Sprintf("http://%s/_/api/groups", addr))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+	var groups []server.Group
+	if err := json.NewDecoder(resp.Body).Decode(&groups); err != nil {
+		return err
+	}
+	i := slices.IndexFunc(groups, func(g server.Group) bool { return g.Name == group })
+	if i < 0 {
+		return nil
+	}
+	current := make([]string, 0, len(groups[i].Files))
+	for _, f := range groups[i].Files {
+		current = append(current, f.ID)
+	}
+	ids := orderFiles(groups[i].Files, paths)
+	if slices.Equal(ids, current) {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string][]string{"fileIds": ids})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("http://%s/_/api/groups/%s/reorder", addr, url.PathEscape(group)), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	putResp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer putResp.Body.Close()
+	if putResp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("server returned %s", putResp.Status)
+	}
+	return nil
+}
+
+// orderFiles returns the IDs of files with the files at paths first, in the
+// order of paths, followed by the others in their current order.
+func orderFiles(files []*server.FileEntry, paths []string) []string {
+	ids := make([]string, 0, len(files))
+	placed := make(map[string]bool, len(files))
+	for _, p := range paths {
+		for _, f := range files {
+			if f.Path == p && !placed[f.ID] {
+				ids = append(ids, f.ID)
+				placed[f.ID] = true
+			}
+		}
+	}
+	for _, f := range files {
+		if !placed[f.ID] {
+			ids = append(ids, f.ID)
+		}
+	}
+	return ids
+}
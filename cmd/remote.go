@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/k1LoW/mo/internal/server"
+)
+
+// isRemoteArg reports whether arg is an http(s) URL to be fetched by the
+// server rather than a local path.
+func isRemoteArg(arg string) bool {
+	_, ok := server.ParseRemoteURL(arg)
+	return ok
+}
+
+// remoteUploads returns urls as not-yet-fetched remote entries of group for
+// a new server to fetch on startup.
+func remoteUploads(urls []string, interval time.Duration, group string) []server.UploadedFileData {
+	files := make([]server.UploadedFileData, 0, len(urls))
+	for _, u := range urls {
+		files = append(files, server.UploadedFileData{
+			Group:  group,
+			Remote: &server.RemoteSource{URL: u, Interval: interval},
+		})
+	}
+	return files
+}
+
+// postRemoteFiles asks a running mo server to fetch urls into group and
+// returns the deeplinks of the accepted ones.
+func postRemoteFiles(client *http.Client, addr, group string, urls []string, interval time.Duration) []deeplinkEntry {
+	var entries []deeplinkEntry
+	for _, u := range urls {
+		entry, err := postRemoteFile(client, addr, group, server.RemoteSource{URL: u, Interval: interval})
+		if err != nil {
+			slog.Warn("failed to add remote file", "url", u, "error", err)
+			fmt.Fprintf(os.Stderr, "mo: %v\n", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func postRemoteFile(client *http.Client, addr, group string, src server.RemoteSource) (deeplinkEntry, error) {
+	body, err := json.Marshal(map[string]any{
+		"url":      src.URL,
+		"interval": src.Interval,
+	})
+	if err != nil {
+		return deeplinkEntry{}, err
+	}
+	resp, err := client.Post(
+		fmt.Sprintf("http://%s/_/api/groups/%s/files/remote", addr, url.PathEscape(group)),
+		"application/json",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return deeplinkEntry{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if errText := strings.TrimSpace(string(errBody)); errText != "" {
+			return deeplinkEntry{}, fmt.Errorf("failed to open %s: %s", src.URL, errText)
+		}
+		return deeplinkEntry{}, fmt.Errorf("failed to open %s: %s", src.URL, resp.Status)
+	}
+	var entry server.FileEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entry); err != nil {
+		return deeplinkEntry{}, err
+	}
+	return deeplinkEntry{
+		URL:  buildDeeplink(addr, group, entry.ID),
+		Name: entry.Name,
+	}, nil
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"github.com/k1LoW/mo/internal/fencerender"
+)
+
+// parseRenderers parses the --renderer flags. A later renderer for the same
+// language replaces an earlier one.
+func parseRenderers(specs []string) ([]fencerender.Renderer, error) {
+	var result []fencerender.Renderer
+	for _, spec := range specs {
+		r, err := fencerender.Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// rendererArgs returns the flags passing the renderers on to a spawned server.
+func rendererArgs() []string {
+	var args []string
+	for _, r := range renderers {
+		args = append(args, "--renderer", r.String())
+	}
+	if renderTimeout != fencerender.DefaultTimeout {
+		args = append(args, "--render-timeout", renderTimeout.String())
+	}
+	return args
+}
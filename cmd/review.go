@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/k1LoW/mo/internal/git"
+	"github.com/k1LoW/mo/internal/server"
+)
+
+// defaultReviewGroup is the group --changed opens files in unless --target
+// is given.
+const defaultReviewGroup = "review"
+
+// resolveReview builds the review for the git repository containing dir.
+// args holds the optional base revision; without it the repository's default
+// branch is used.
+func resolveReview(dir string, args []string, group string) (server.ReviewData, error) {
+	if len(args) > 1 {
+		return server.ReviewData{}, fmt.Errorf("--changed accepts at most one base revision")
+	}
+	root, err := git.Root(dir)
+	if err != nil {
+		return server.ReviewData{}, fmt.Errorf("--changed must be run inside a git repository: %w", err)
+	}
+	var base string
+	if len(args) == 1 {
+		base = args[0]
+	} else if base, err = git.DefaultBranch(root); err != nil {
+		return server.ReviewData{}, err
+	}
+	if _, err := git.MergeBase(root, base); err != nil {
+		return server.ReviewData{}, err
+	}
+	return server.ReviewData{
+		Group:      group,
+		Root:       root,
+		Base:       base,
+		Extensions: extensions,
+	}, nil
+}
+
+// filterValidReviews drops restored reviews whose repository is gone.
+func filterValidReviews(reviews []server.ReviewData) []server.ReviewData {
+	var valid []server.ReviewData
+	for _, rv := range reviews {
+		if _, err := os.Stat(rv.Root); err != nil {
+			slog.Info("skipping missing repository from backup", "path", rv.Root)
+			continue
+		}
+		valid = append(valid, rv)
+	}
+	return valid
+}
+
+// postReview registers a review group with a running mo server and returns
+// the deeplinks of the files it opened.
+func postReview(client *http.Client, addr string, rv server.ReviewData) ([]deeplinkEntry, error) {
+	body, err := json.Marshal(map[string]any{
+		"root":       rv.Root,
+		"base":       rv.Base,
+		"group":      rv.Group,
+		"extensions": rv.Extensions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Post(fmt.Sprintf("http://%s/_/api/reviews", addr), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if errText := strings.TrimSpace(string(errBody)); errText != "" {
+			return nil, fmt.Errorf("review failed: %s: %s", resp.Status, errText)
+		}
+		return nil, fmt.Errorf("review failed: %s", resp.Status)
+	}
+	var reviewResp server.AddReviewResponse
+	if err := json.NewDecoder(resp.Body).Decode(&reviewResp); err != nil {
+		return nil, err
+	}
+	entries := make([]deeplinkEntry, 0, len(reviewResp.Files))
+	for _, f := range reviewResp.Files {
+		entries = append(entries, deeplinkEntry{
+			URL:  buildDeeplink(addr, rv.Group, f.ID),
+			Path: f.Path,
+			Name: f.Name,
+		})
+	}
+	return entries, nil
+}
+
+// doRemoveReview stops the review group on a running mo server.
+func doRemoveReview(addr, groupName string) error {
+	result, err := probeServer(addr)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("http://%s/_/api/reviews/%s", addr, url.PathEscape(groupName)), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := result.client.Do(req) //nolint:gosec // URL is constructed from local addr, not user-supplied
+	if err != nil {
+		return fmt.Errorf("failed to send unwatch request: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("no review in group %q", groupName)
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected response from server: %s", resp.Status)
+	}
+	slog.Info("review removed", "group", groupName)
+	fmt.Fprintf(os.Stderr, "mo: stopped review in group %s\n", groupName)
+	return nil
+}
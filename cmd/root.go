@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"maps"
 	"net"
@@ -16,7 +17,6 @@ import (
 	"os/signal"
 	"path/filepath"
 	"slices"
-	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -27,7 +27,10 @@ import (
 
 	"github.com/k1LoW/donegroup"
 	"github.com/k1LoW/mo/internal/backup"
+	"github.com/k1LoW/mo/internal/config"
+	"github.com/k1LoW/mo/internal/fencerender"
 	"github.com/k1LoW/mo/internal/logfile"
+	"github.com/k1LoW/mo/internal/registry"
 	"github.com/k1LoW/mo/internal/server"
 	"github.com/k1LoW/mo/version"
 	"github.com/muesli/termenv"
@@ -47,8 +50,7 @@ const (
 	// failure is reported.
 	deadChildGrace = 1 * time.Second
 
-	markdownGlob          = "*.md"
-	markdownGlobRecursive = "**/*.md"
+	defaultExtension = "md"
 )
 
 // errServerConflict is returned by waitForReady when a mo server other than
@@ -66,13 +68,42 @@ var (
 	restartServer                bool
 	foreground                   bool
 	statusServer                 bool
+	listFiles                    bool
 	watchMode                    bool
 	unwatchMode                  bool
 	recursive                    bool
 	closeFiles                   bool
+	moveFiles                    bool
+	copyFiles                    bool
+	moveFrom                     string
+	moveTo                       string
 	clearBackup                  bool
+	saveSession                  string
+	loadSession                  string
+	listSessions                 bool
+	deleteSession                string
+	mergeSession                 bool
+	uploadName                   string
+	followMode                   bool
+	maxLines                     int
 	jsonOutput                   bool
 	dangerouslyAllowRemoteAccess bool
+	extensions                   []string
+	gitRev                       string
+	refreshInterval              time.Duration
+	changedMode                  bool
+	rendererSpecs                []string
+	renderTimeout                time.Duration
+	renderers                    []fencerender.Renderer
+
+	// Set from the user config only.
+	ignorePatterns  []string
+	uiDefaults      server.UIDefaults
+	backupRetention time.Duration
+
+	// targetSet reports whether the group was chosen with --target or the
+	// user config, rather than left at its default.
+	targetSet bool
 )
 
 var rootCmd = &cobra.Command{
@@ -90,6 +121,17 @@ Examples:
   mo draft.md --port 6276               Use a different port
   cat notes.md | mo                     Read Markdown from stdin
   cmd | mo --target output              Pipe command output into a group
+  tail -f build.log | mo --follow       Show stdin as it arrives
+  ./summary.sh | mo --name report.md    Update the same entry on each run
+  mo README.md#installation             Open a file at a heading
+  mo spec.md:120                        Open a file at a line
+  mo README.md@HEAD~3                   Open a file as of a git revision
+  mo --rev v1.0.0 docs/*.md             Open files as of a tag (read-only)
+  mo --changed                          Review files changed on this branch
+  mo https://example.com/README.md      Open a URL, re-fetched periodically
+  mo docs-bundle.zip                    Read the Markdown inside an archive
+  mo --renderer 'dot=dot -Tsvg' a.md    Render Graphviz blocks with dot
+  mo --move a.md --from docs --to done  Move an open file to another group
 
 Single Server, Multiple Files:
   By default, mo runs a single server on port 6275.
@@ -102,6 +144,7 @@ Single Server, Multiple Files:
   To run a completely separate session, use a different port:
 
   $ mo draft.md -p 6276
+  $ mo draft.md -p auto   # Picks a free port and prints it
 
 Groups:
   Files can be organized into named groups using the --target (-t) flag.
@@ -136,6 +179,14 @@ Session Restore:
 
   Use --clear to remove a saved session.
 
+  Named sessions save what a server shows and bring it back later:
+
+  $ mo --save-session release-notes      # Save the current state
+  $ mo --load-session onboarding         # Replace it with another session
+  $ mo --load-session notes --merge      # Add a session to the current state
+  $ mo --list-sessions                   # List saved sessions
+  $ mo --delete-session onboarding       # Delete a session
+
 Live-Reload:
   mo watches all opened files for changes using filesystem notifications.
   When a file is saved, the browser automatically re-renders the content.
@@ -172,6 +223,23 @@ Watch mode and glob patterns:
 
   $ mo -R docs/                       Open every .md under docs/ once
 
+  Directory arguments match .md files by default. Use --ext to choose
+  the file extensions that directory arguments expand to.
+
+  $ mo -w docs/ --ext md,qmd,txt      Watch docs/*.{md,qmd,txt}
+  $ mo -R docs/ --ext md,rmd          Open every .md and .rmd under docs/
+
+Project file:
+  A .mo.yaml at the repository root declares groups with their files,
+  watch patterns and ignore rules. Running mo without arguments anywhere
+  in the repository opens them.
+
+Configuration:
+  Defaults can be set in $XDG_CONFIG_HOME/mo/config.yaml (port, bind,
+  open, target, extensions, ignore, theme, font_size, backup_retention)
+  or with MO_* environment variables (e.g. MO_PORT=6276). A flag wins
+  over the environment, which wins over the file.
+
 WARNING: --bind with a non-loopback address:
   Binding to a non-localhost address (e.g. 0.0.0.0) exposes mo to the
   network without any authentication. Remote clients can read any file
@@ -190,7 +258,7 @@ func Execute() {
 
 func init() {
 	rootCmd.Flags().StringVarP(&target, "target", "t", server.DefaultGroup, "Tab group name")
-	rootCmd.Flags().IntVarP(&port, "port", "p", 6275, "Server port")
+	rootCmd.Flags().VarP(newPortValue(6275, &port), "port", "p", "Server port, or auto to pick a free one for a new server")
 	rootCmd.Flags().StringVarP(&bind, "bind", "b", "localhost", "Bind address (e.g. localhost, 0.0.0.0)")
 	rootCmd.Flags().BoolVar(&open, "open", false, "Always open browser (even when adding to existing group)")
 	rootCmd.Flags().BoolVar(&noOpen, "no-open", false, "Do not open browser automatically")
@@ -202,17 +270,71 @@ func init() {
 	rootCmd.Flags().MarkHidden("restore") //nolint:errcheck
 	rootCmd.Flags().BoolVar(&foreground, "foreground", false, "Run mo server in foreground (do not background)")
 	rootCmd.Flags().BoolVar(&statusServer, "status", false, "Show status of all running mo servers")
+	rootCmd.Flags().BoolVar(&listFiles, "list", false, "List the files open on the server, in all groups or the --target group")
+	rootCmd.MarkFlagsMutuallyExclusive("status", "list")
 	rootCmd.Flags().BoolVarP(&watchMode, "watch", "w", false, "Treat directory and glob arguments as watch patterns")
 	rootCmd.Flags().BoolVar(&unwatchMode, "unwatch", false, "Remove watched patterns for the given directory or glob arguments")
 	rootCmd.Flags().BoolVarP(&recursive, "recursive", "R", false, "Recurse into subdirectories when a directory is given")
+	rootCmd.Flags().StringSliceVar(&extensions, "ext", []string{defaultExtension}, "File extensions matched when a directory is given (e.g. md,qmd,txt)")
+	rootCmd.Flags().BoolVar(&changedMode, "changed", false, "Open the files changed since the merge-base with a base revision (default: the default branch) as a live review group")
+	rootCmd.Flags().StringVar(&gitRev, "rev", "", "Read the given files at a git revision (e.g. main, HEAD~3) instead of the work tree")
+	rootCmd.Flags().DurationVar(&refreshInterval, "refresh-interval", server.DefaultRemoteInterval, "How often http(s) URL arguments are re-fetched")
+	rootCmd.Flags().StringArrayVar(&rendererSpecs, "renderer", nil, "Render code blocks of a language with a command that reads the block on stdin and writes SVG (e.g. 'dot=dot -Tsvg'); repeatable")
+	rootCmd.Flags().DurationVar(&renderTimeout, "render-timeout", fencerender.DefaultTimeout, "How long a --renderer command may run")
 	rootCmd.Flags().BoolVar(&closeFiles, "close", false, "Close files instead of opening them")
+	rootCmd.Flags().BoolVar(&moveFiles, "move", false, "Move files (paths, globs or IDs) or watch patterns from the --from group to the --to group")
+	rootCmd.Flags().BoolVar(&copyFiles, "copy", false, "Copy files (paths, globs or IDs) or watch patterns from the --from group to the --to group")
+	rootCmd.Flags().StringVar(&moveFrom, "from", "", "Group --move and --copy take files from (default: the --target group)")
+	rootCmd.Flags().StringVar(&moveTo, "to", "", "Group --move and --copy put files in")
+	rootCmd.MarkFlagsMutuallyExclusive("close", "move", "copy")
 	rootCmd.Flags().BoolVar(&clearBackup, "clear", false, "Clear saved session for the specified port")
+	rootCmd.Flags().StringVar(&saveSession, "save-session", "", "Save the groups, files and watch patterns of the running server as a named session")
+	rootCmd.Flags().StringVar(&loadSession, "load-session", "", "Open a named session, replacing what the running server shows (see --merge)")
+	rootCmd.Flags().BoolVar(&listSessions, "list-sessions", false, "List the named sessions")
+	rootCmd.Flags().StringVar(&deleteSession, "delete-session", "", "Delete a named session")
+	rootCmd.Flags().BoolVar(&mergeSession, "merge", false, "Add the --load-session session to what the server shows instead of replacing it")
+	rootCmd.MarkFlagsMutuallyExclusive("save-session", "load-session", "list-sessions", "delete-session", "clear")
+	rootCmd.Flags().StringVar(&uploadName, "name", "", "Name for stdin content; uploading under the same name again updates that entry in place")
+	rootCmd.Flags().BoolVar(&followMode, "follow", false, "Keep reading redirected stdin and show it as it arrives, in one entry that grows in place")
+	rootCmd.Flags().IntVar(&maxLines, "max-lines", 0, "Keep only the last N lines of --follow input (0: no limit)")
 	rootCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output structured data as JSON to stdout")
 	rootCmd.Flags().BoolVar(&dangerouslyAllowRemoteAccess, "dangerously-allow-remote-access", false, "Allow remote access without authentication. Recommended only for trusted networks.")
+
+	rootCmd.ValidArgsFunction = completeArgs
+	rootCmd.RegisterFlagCompletionFunc("target", completeTarget)          //nolint:errcheck
+	rootCmd.RegisterFlagCompletionFunc("from", completeTarget)            //nolint:errcheck
+	rootCmd.RegisterFlagCompletionFunc("to", completeTarget)              //nolint:errcheck
+	rootCmd.RegisterFlagCompletionFunc("load-session", completeSession)   //nolint:errcheck
+	rootCmd.RegisterFlagCompletionFunc("delete-session", completeSession) //nolint:errcheck
 }
 
 func run(cmd *cobra.Command, args []string) (retErr error) {
-	if !foreground || restore != "" {
+	cfg, err := config.Load()
+	if err != nil {
+		// A broken config must not keep a running server from being
+		// inspected or stopped.
+		if !statusServer && !shutdownServer && !listFiles {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "mo: ignoring config: %v\n", err)
+	}
+	applyConfig(cfg, cmd.Flags().Changed)
+
+	// --status, --list-sessions and --delete-session talk to no server on
+	// the port, so --port auto does not pick one for them.
+	if port == autoPort && !statusServer && !listSessions && deleteSession == "" {
+		if shutdownServer || restartServer || listFiles || closeFiles || unwatchMode || moveFiles || copyFiles || clearBackup || saveSession != "" {
+			return fmt.Errorf("--port auto starts a new server; pass the port of a running server instead (see --status)")
+		}
+		p, err := pickFreePort(strings.Trim(bind, "[]"))
+		if err != nil {
+			return err
+		}
+		port = p
+		fmt.Fprintf(os.Stderr, "mo: using free port %d\n", port)
+	}
+
+	if (!foreground || restore != "") && port != autoPort {
 		logCleanup, err := logfile.Setup(port)
 		if err != nil {
 			slog.Warn("failed to setup log file, using stderr", "error", err)
@@ -234,6 +356,19 @@ func run(cmd *cobra.Command, args []string) (retErr error) {
 	bind = strings.Trim(bind, "[]")
 	addr := net.JoinHostPort(bind, strconv.Itoa(port))
 
+	exts, err := normalizeExtensions(extensions)
+	if err != nil {
+		return err
+	}
+	extensions = exts
+
+	if renderers, err = parseRenderers(rendererSpecs); err != nil {
+		return err
+	}
+	if renderTimeout <= 0 {
+		return fmt.Errorf("--render-timeout must be positive")
+	}
+
 	if clearBackup {
 		wasServerRunning := false
 		if _, err := probeServer(addr, probeTimeoutFast); err == nil {
@@ -290,6 +425,40 @@ func run(cmd *cobra.Command, args []string) (retErr error) {
 		return doStatus()
 	}
 
+	if mergeSession && loadSession == "" {
+		return fmt.Errorf("--merge requires --load-session")
+	}
+	if saveSession != "" || loadSession != "" || listSessions || deleteSession != "" {
+		if len(args) > 0 {
+			return fmt.Errorf("session flags do not take arguments")
+		}
+		switch {
+		case saveSession != "":
+			return doSaveSession(addr, saveSession)
+		case loadSession != "":
+			return doLoadSession(cmd.Context(), addr, loadSession, mergeSession)
+		case listSessions:
+			return doListSessions()
+		default:
+			return doDeleteSession(deleteSession)
+		}
+	}
+
+	if listFiles {
+		if len(args) > 0 {
+			return fmt.Errorf("--list does not take arguments")
+		}
+		group := ""
+		if targetSet {
+			resolved, err := server.ResolveGroupName(target)
+			if err != nil {
+				return fmt.Errorf("invalid target group name %q: %w", target, err)
+			}
+			group = resolved
+		}
+		return doList(addr, group)
+	}
+
 	if shutdownServer {
 		return doShutdown(addr)
 	}
@@ -298,10 +467,21 @@ func run(cmd *cobra.Command, args []string) (retErr error) {
 		return doRestart(addr)
 	}
 
+	if changedMode && !targetSet {
+		target = defaultReviewGroup
+	}
+
 	if unwatchMode {
 		if watchMode {
 			return fmt.Errorf("cannot use --unwatch with --watch")
 		}
+		if changedMode {
+			resolvedTarget, err := server.ResolveGroupName(target)
+			if err != nil {
+				return fmt.Errorf("invalid target group name %q: %w", target, err)
+			}
+			return doRemoveReview(addr, resolvedTarget)
+		}
 		if len(args) == 0 {
 			return fmt.Errorf("--unwatch requires a glob pattern or directory argument")
 		}
@@ -319,6 +499,37 @@ func run(cmd *cobra.Command, args []string) (retErr error) {
 		return doUnwatch(addr, patterns, resolvedTarget)
 	}
 
+	if moveFiles || copyFiles {
+		flag := "--move"
+		if copyFiles {
+			flag = "--copy"
+		}
+		if watchMode {
+			return fmt.Errorf("cannot use %s with --watch", flag)
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("%s requires at least one file, pattern or ID argument", flag)
+		}
+		if moveTo == "" {
+			return fmt.Errorf("%s requires --to", flag)
+		}
+		from, err := server.ResolveGroupName(transferSource())
+		if err != nil {
+			return fmt.Errorf("invalid group name %q: %w", transferSource(), err)
+		}
+		to, err := server.ResolveGroupName(moveTo)
+		if err != nil {
+			return fmt.Errorf("invalid group name %q: %w", moveTo, err)
+		}
+		if from == to {
+			return fmt.Errorf("--from and --to are the same group %q", from)
+		}
+		return doTransfer(addr, args, from, to, copyFiles)
+	}
+	if moveFrom != "" || moveTo != "" {
+		return fmt.Errorf("--from and --to require --move or --copy")
+	}
+
 	if closeFiles {
 		if watchMode {
 			return fmt.Errorf("cannot use --close with --watch")
@@ -344,11 +555,11 @@ func run(cmd *cobra.Command, args []string) (retErr error) {
 	}
 
 	if restore != "" {
-		filesByGroup, patternsByGroup, uploadedFiles, err := loadRestoreData(restore)
+		rd, err := loadRestoreData(restore)
 		if err != nil {
 			return fmt.Errorf("failed to restore state: %w", err)
 		}
-		return startServer(cmd.Context(), addr, filesByGroup, patternsByGroup, uploadedFiles)
+		return startServer(cmd.Context(), addr, rd)
 	}
 
 	resolved, err := server.ResolveGroupName(target)
@@ -361,7 +572,68 @@ func run(cmd *cobra.Command, args []string) (retErr error) {
 		return fmt.Errorf("--recursive (-R) requires a directory argument")
 	}
 
-	files, patterns, err := resolveArgs(args, watchMode, recursive)
+	if gitRev != "" && watchMode {
+		return fmt.Errorf("cannot use --rev with --watch")
+	}
+	if maxLines != 0 && !followMode {
+		return fmt.Errorf("--max-lines requires --follow")
+	}
+	if cmd.Flags().Changed("name") {
+		if err := validateUploadName(uploadName); err != nil {
+			return err
+		}
+	}
+	if followMode {
+		switch {
+		case len(args) > 0:
+			return fmt.Errorf("--follow reads stdin and does not take arguments")
+		case watchMode || changedMode || gitRev != "":
+			return fmt.Errorf("cannot use --follow with --watch, --changed or --rev")
+		case maxLines < 0:
+			return fmt.Errorf("--max-lines must not be negative")
+		case !isStdinRedirected():
+			return fmt.Errorf("--follow requires redirected stdin (e.g. tail -f build.log | mo --follow)")
+		}
+		return doFollow(cmd.Context(), addr, os.Stdin)
+	}
+	if refreshInterval < server.MinRemoteInterval {
+		return fmt.Errorf("--refresh-interval must be at least %s", server.MinRemoteInterval)
+	}
+
+	var reviews []server.ReviewData
+	if changedMode {
+		if watchMode || gitRev != "" || recursive {
+			return fmt.Errorf("cannot use --changed with --watch, --rev or --recursive")
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		rv, err := resolveReview(cwd, args, target)
+		if err != nil {
+			return err
+		}
+		reviews = append(reviews, rv)
+		// The optional argument is the base revision, not a file.
+		args = nil
+	}
+	revFiles, fileArgs, err := resolveRevisionArgs(args, gitRev, target)
+	if err != nil {
+		return err
+	}
+	var archives []server.ArchiveData
+	if !watchMode {
+		fileArgs, locations, err = splitLocations(fileArgs)
+		if err != nil {
+			return err
+		}
+		archives, fileArgs, err = resolveArchiveArgs(fileArgs, target, !targetSet)
+		if err != nil {
+			return err
+		}
+	}
+
+	files, patterns, urls, err := resolveArgs(fileArgs, watchMode, recursive)
 	if err != nil {
 		return err
 	}
@@ -391,11 +663,35 @@ func run(cmd *cobra.Command, args []string) (retErr error) {
 			Content: content,
 			Group:   target,
 		}
+		if uploadName != "" {
+			stdinData.Name = uploadName
+			stdinData.Named = true
+		}
+	} else if uploadName != "" {
+		return fmt.Errorf("--name requires redirected stdin")
+	}
+
+	// Without arguments, a project file in the repository declares the
+	// session.
+	var projectGroups []projectGroup
+	if len(args) == 0 && stdinData == nil && len(reviews) == 0 {
+		proj, err := findProject()
+		if err != nil {
+			return err
+		}
+		if proj != nil {
+			if projectGroups, err = resolveProject(proj); err != nil {
+				return err
+			}
+			if len(projectGroups) > 0 && !targetSet {
+				target = projectGroups[0].name
+			}
+		}
 	}
 
 	// When no files, patterns, or stdin are specified and a server is already
 	// running, just open the browser and exit.
-	if len(files) == 0 && len(patterns) == 0 && stdinData == nil {
+	if len(files) == 0 && len(patterns) == 0 && len(urls) == 0 && len(revFiles) == 0 && len(reviews) == 0 && len(archives) == 0 && len(projectGroups) == 0 && stdinData == nil {
 		if _, err := probeServer(addr, probeTimeoutDefault); err == nil {
 			openBrowser(addr)
 			return nil
@@ -403,20 +699,47 @@ func run(cmd *cobra.Command, args []string) (retErr error) {
 	}
 
 	// Try adding to an existing server.
-	if stdinData != nil || len(files) > 0 || len(patterns) > 0 {
+	if stdinData != nil || len(files) > 0 || len(patterns) > 0 || len(urls) > 0 || len(revFiles) > 0 || len(reviews) > 0 || len(archives) > 0 || len(projectGroups) > 0 {
 		result, probeErr := probeServer(addr, probeTimeoutFast)
 		if probeErr == nil {
 			isNewGroup := !slices.Contains(result.groups, target)
+			for _, ad := range archives {
+				if !slices.Contains(result.groups, ad.Group) {
+					isNewGroup = true
+				}
+			}
+			for _, pg := range projectGroups {
+				if !slices.Contains(result.groups, pg.name) {
+					isNewGroup = true
+				}
+			}
 
 			var deeplinks []deeplinkEntry
 			fileEntries := postFiles(result.client, addr, target, files)
 			deeplinks = append(deeplinks, fileEntries...)
-			patternEntries, patternsAdded := postPatterns(result.client, addr, target, patterns)
+			patternEntries, patternsAdded := postPatterns(result.client, addr, target, patterns, nil)
 			deeplinks = append(deeplinks, patternEntries...)
+			revEntries := postRevisionFiles(result.client, addr, target, revFiles)
+			deeplinks = append(deeplinks, revEntries...)
+			remoteEntries := postRemoteFiles(result.client, addr, target, urls, refreshInterval)
+			deeplinks = append(deeplinks, remoteEntries...)
+			var reviewEntries []deeplinkEntry
+			for _, rv := range reviews {
+				entries, err := postReview(result.client, addr, rv)
+				if err != nil {
+					return err
+				}
+				reviewEntries = append(reviewEntries, entries...)
+			}
+			deeplinks = append(deeplinks, reviewEntries...)
+			archiveEntries, archivesAdded := postArchives(result.client, addr, archives)
+			deeplinks = append(deeplinks, archiveEntries...)
+			projectEntries, projectAdded := postProject(result.client, addr, projectGroups)
+			deeplinks = append(deeplinks, projectEntries...)
 
 			var stdinUploadErr error
 			if stdinData != nil {
-				entry, err := postUploadedFile(result.client, addr, target, stdinData.Name, stdinData.Content)
+				entry, err := postUploadedFile(result.client, addr, target, *stdinData)
 				if err != nil {
 					stdinUploadErr = err
 					slog.Warn("failed to upload stdin content", "error", err)
@@ -432,15 +755,20 @@ func run(cmd *cobra.Command, args []string) (retErr error) {
 			// Count only what was actually accepted by the running server so
 			// the "added N item(s)" line does not overstate on partial POST
 			// failures. postFiles appends exactly one entry per accepted file.
-			added := len(fileEntries) + patternsAdded
+			added := len(fileEntries) + patternsAdded + len(revEntries) + len(remoteEntries) + len(reviewEntries) + archivesAdded + projectAdded
 			if stdinData != nil && stdinUploadErr == nil {
 				added++
 			}
 			slog.Info("added to existing server", "files", len(files), "patterns", len(patterns), "stdin", stdinData != nil, "addr", addr)
+			located := locateDeeplinks(deeplinks)
 			emitServeOutput(addr, deeplinks, false)
 			fmt.Fprintf(os.Stderr, "mo: added %d item(s) to http://%s\n", added, addr)
 
-			if isNewGroup || open {
+			// A file given with a location is shown in a connected browser
+			// when there is one, instead of in a new tab.
+			if located != nil {
+				showLocation(result.client, addr, located)
+			} else if isNewGroup || open {
 				openBrowser(addr)
 			}
 			return nil
@@ -452,22 +780,58 @@ func run(cmd *cobra.Command, args []string) (retErr error) {
 	if len(patterns) > 0 {
 		patternsByGroup = map[string][]string{target: patterns}
 	}
+	for _, pg := range projectGroups {
+		filesByGroup[pg.name] = pg.files
+		if len(pg.patterns) > 0 {
+			if patternsByGroup == nil {
+				patternsByGroup = make(map[string][]string)
+			}
+			patternsByGroup[pg.name] = pg.patterns
+		}
+	}
+	patternIgnoresByGroup := patternIgnores(projectGroups)
 
 	// Restore backup and merge with specified files/patterns
 	var rd server.RestoreData
 	if err := backup.Load(port, &rd); err != nil {
 		slog.Warn("failed to load backup", "error", err)
 	}
+	if backupRetention > 0 {
+		pruneBackups(backupRetention)
+	}
 	restoredFiles, restoredPatterns, restoredUploads := filterValidRestoreData(&rd)
+	restoredReviews := filterValidReviews(rd.Reviews)
+	restoredArchives := filterValidArchives(rd.Archives)
 	var uploadedFiles []server.UploadedFileData
-	if len(restoredFiles) > 0 || len(restoredPatterns) > 0 || len(restoredUploads) > 0 {
+	if len(restoredFiles) > 0 || len(restoredPatterns) > 0 || len(restoredUploads) > 0 || len(restoredReviews) > 0 || len(restoredArchives) > 0 {
 		slog.Info("restoring session from backup", "port", port)
 		fmt.Fprintf(os.Stderr, "mo: restoring previous session for port %d\n", port)
-		filesByGroup = mergeGroups(restoredFiles, filesByGroup)
+		if len(projectGroups) > 0 {
+			// The project file declares the order of its files.
+			filesByGroup = mergeGroups(filesByGroup, restoredFiles)
+		} else {
+			filesByGroup = mergeGroups(restoredFiles, filesByGroup)
+		}
 		patternsByGroup = mergeGroups(restoredPatterns, patternsByGroup)
 		uploadedFiles = restoredUploads
+		// A review given now replaces a restored one for the same group.
+		reviews = append(restoredReviews, reviews...)
+		archives = append(restoredArchives, archives...)
+		for group, byPattern := range rd.PatternIgnores {
+			for pat, ignore := range byPattern {
+				if patternIgnoresByGroup[group] == nil {
+					patternIgnoresByGroup[group] = make(map[string][]string)
+				}
+				if _, ok := patternIgnoresByGroup[group][pat]; !ok {
+					patternIgnoresByGroup[group][pat] = ignore
+				}
+			}
+		}
 	}
 
+	uploadedFiles = append(uploadedFiles, revFiles...)
+	uploadedFiles = append(uploadedFiles, remoteUploads(urls, refreshInterval, target)...)
+
 	// Append stdin content to uploaded files for the new server.
 	if stdinData != nil {
 		uploadedFiles = append(uploadedFiles, *stdinData)
@@ -504,10 +868,11 @@ func run(cmd *cobra.Command, args []string) (retErr error) {
 		}
 	}
 
+	initial := server.RestoreData{Groups: filesByGroup, Patterns: patternsByGroup, PatternIgnores: patternIgnoresByGroup, UploadedFiles: uploadedFiles, Reviews: reviews, Archives: archives}
 	if foreground {
-		return startServer(cmd.Context(), addr, filesByGroup, patternsByGroup, uploadedFiles)
+		return startServer(cmd.Context(), addr, initial)
 	}
-	return startBackground(addr, filesByGroup, patternsByGroup, uploadedFiles)
+	return startBackground(addr, initial)
 }
 
 // mergeGroups merges base and additional group maps, with base entries first.
@@ -554,18 +919,18 @@ func filterValidRestoreData(rd *server.RestoreData) (map[string][]string, map[st
 	return filesByGroup, patternsByGroup, rd.UploadedFiles
 }
 
-func loadRestoreData(path string) (map[string][]string, map[string][]string, []server.UploadedFileData, error) {
+func loadRestoreData(path string) (server.RestoreData, error) {
+	var rd server.RestoreData
 	data, err := os.ReadFile(path) //nolint:gosec
 	if err != nil {
-		return nil, nil, nil, err
+		return rd, err
 	}
 	os.Remove(path)
 
-	var rd server.RestoreData
 	if err := json.Unmarshal(data, &rd); err != nil {
-		return nil, nil, nil, err
+		return rd, err
 	}
-	return rd.Groups, rd.Patterns, rd.UploadedFiles, nil
+	return rd, nil
 }
 
 func isLoopbackBind(bind string) bool {
@@ -630,7 +995,7 @@ func resolveUnwatchArgs(args []string, recursive bool, addr, groupName string) (
 			}
 			patterns = append(patterns, matched...)
 		} else {
-			patterns = append(patterns, filepath.Join(abs, markdownGlob))
+			patterns = append(patterns, filepath.Join(abs, markdownGlobFor(false)))
 		}
 	}
 	seen := make(map[string]struct{}, len(patterns))
@@ -671,11 +1036,15 @@ func fetchRegisteredPatterns(addr, groupName string) ([]string, error) {
 	return nil, fmt.Errorf("group %q not found (use --status to see registered groups)", groupName)
 }
 
-func resolveArgs(args []string, watchMode, recursive bool) (files, patterns []string, err error) {
+func resolveArgs(args []string, watchMode, recursive bool) (files, patterns, urls []string, err error) {
 	for _, arg := range args {
+		if isRemoteArg(arg) {
+			urls = append(urls, arg)
+			continue
+		}
 		abs, err := filepath.Abs(arg)
 		if err != nil {
-			return nil, nil, fmt.Errorf("cannot resolve path %s: %w", arg, err)
+			return nil, nil, nil, fmt.Errorf("cannot resolve path %s: %w", arg, err)
 		}
 
 		if hasGlobChars(arg) {
@@ -685,10 +1054,10 @@ func resolveArgs(args []string, watchMode, recursive bool) (files, patterns []st
 			}
 			matches, err := expandGlobPattern(abs)
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 			if len(matches) == 0 {
-				return nil, nil, fmt.Errorf("no files matched %s", arg)
+				return nil, nil, nil, fmt.Errorf("no files matched %s", arg)
 			}
 			files = append(files, matches...)
 			continue
@@ -697,9 +1066,9 @@ func resolveArgs(args []string, watchMode, recursive bool) (files, patterns []st
 		stat, err := os.Stat(abs)
 		if err != nil {
 			if errors.Is(err, os.ErrNotExist) {
-				return nil, nil, fmt.Errorf("file not found: %s", abs)
+				return nil, nil, nil, fmt.Errorf("file not found: %s", abs)
 			}
-			return nil, nil, fmt.Errorf("cannot stat path %s: %w", abs, err)
+			return nil, nil, nil, fmt.Errorf("cannot stat path %s: %w", abs, err)
 		}
 		if stat.IsDir() {
 			pat := filepath.Join(abs, markdownGlobFor(recursive))
@@ -709,24 +1078,62 @@ func resolveArgs(args []string, watchMode, recursive bool) (files, patterns []st
 			}
 			matches, err := expandGlobPattern(pat)
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 			if len(matches) == 0 {
-				return nil, nil, fmt.Errorf("no .md files in %s", abs)
+				return nil, nil, nil, fmt.Errorf("no %s files in %s", extensionList(), abs)
 			}
 			files = append(files, matches...)
 			continue
 		}
 		files = append(files, abs)
 	}
-	return files, patterns, nil
+	return files, patterns, urls, nil
 }
 
+// markdownGlobFor returns the glob that a directory argument expands to,
+// matching every extension selected with --ext (default: md).
 func markdownGlobFor(recursive bool) string {
+	exts := extensions
+	if len(exts) == 0 {
+		exts = []string{defaultExtension}
+	}
+	glob := "*." + exts[0]
+	if len(exts) > 1 {
+		glob = "*.{" + strings.Join(exts, ",") + "}"
+	}
 	if recursive {
-		return markdownGlobRecursive
+		return "**/" + glob
 	}
-	return markdownGlob
+	return glob
+}
+
+// extensionList formats the --ext extensions for messages (e.g. ".md/.txt").
+func extensionList() string {
+	exts := extensions
+	if len(exts) == 0 {
+		exts = []string{defaultExtension}
+	}
+	return "." + strings.Join(exts, "/.")
+}
+
+// normalizeExtensions strips leading dots and whitespace from --ext values,
+// drops duplicates, and rejects values that would break the generated glob.
+func normalizeExtensions(exts []string) ([]string, error) {
+	var result []string
+	for _, e := range exts {
+		e = strings.TrimPrefix(strings.TrimSpace(e), ".")
+		if e == "" {
+			return nil, fmt.Errorf("--ext must not contain empty extensions")
+		}
+		if strings.ContainsAny(e, "*?[]{},/\\") {
+			return nil, fmt.Errorf("invalid extension %q for --ext", e)
+		}
+		if !slices.Contains(result, e) {
+			result = append(result, e)
+		}
+	}
+	return result, nil
 }
 
 func expandGlobPattern(absPattern string) ([]string, error) {
@@ -735,9 +1142,12 @@ func expandGlobPattern(absPattern string) ([]string, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to expand glob %s: %w", absPattern, err)
 	}
-	matches := make([]string, len(rels))
-	for i, r := range rels {
-		matches[i] = filepath.Join(base, r)
+	matches := make([]string, 0, len(rels))
+	for _, r := range rels {
+		if config.Ignored(ignorePatterns, r) {
+			continue
+		}
+		matches = append(matches, filepath.Join(base, r))
 	}
 	collate.New(language.Und, collate.Numeric).SortStrings(matches)
 	return matches, nil
@@ -775,8 +1185,10 @@ func postFiles(client *http.Client, addr, group string, files []string) []deepli
 		}
 		resp.Body.Close()
 		entries = append(entries, deeplinkEntry{
-			URL:  buildDeeplink(addr, group, entry.ID),
-			Path: entry.Path,
+			URL:   buildDeeplink(addr, group, entry.ID),
+			Path:  entry.Path,
+			Group: group,
+			ID:    entry.ID,
 		})
 	}
 	return entries
@@ -786,14 +1198,16 @@ func postFiles(client *http.Client, addr, group string, files []string) []deepli
 // deeplink entries for every file matched by the successful registrations,
 // plus the number of patterns that were actually registered (which is not
 // derivable from len(entries) because a valid pattern may legitimately match
-// zero files).
-func postPatterns(client *http.Client, addr, group string, patterns []string) ([]deeplinkEntry, int) {
+// zero files). ignores holds the ignore patterns of each pattern (see
+// config.AbsIgnore), if any.
+func postPatterns(client *http.Client, addr, group string, patterns []string, ignores map[string][]string) ([]deeplinkEntry, int) {
 	var entries []deeplinkEntry
 	added := 0
 	for _, pat := range patterns {
-		body, err := json.Marshal(map[string]string{
+		body, err := json.Marshal(map[string]any{
 			"pattern": pat,
 			"group":   group,
+			"ignore":  ignores[pat],
 		})
 		if err != nil {
 			slog.Warn("failed to marshal request", "pattern", pat, "error", err)
@@ -835,6 +1249,9 @@ type deeplinkEntry struct {
 	URL  string
 	Path string // absolute file path (empty for uploaded files)
 	Name string // display name fallback when Path is empty
+	// Group and ID identify a local file, for navigating to its location.
+	Group string
+	ID    string
 }
 
 // JSON output types
@@ -851,9 +1268,16 @@ type jsonServeOutput struct {
 }
 
 type jsonStatusGroupEntry struct {
-	Name     string   `json:"name"`
-	Files    int      `json:"files"`
-	Patterns []string `json:"patterns,omitempty"`
+	Name     string              `json:"name"`
+	Files    int                 `json:"files"`
+	Patterns []string            `json:"patterns,omitempty"`
+	Git      []jsonStatusGitFile `json:"git,omitempty"`
+}
+
+// jsonStatusGitFile is the git metadata of one file in --status --json.
+type jsonStatusGitFile struct {
+	Path string `json:"path"`
+	*server.GitInfo
 }
 
 type jsonStatusEntry struct {
@@ -863,6 +1287,7 @@ type jsonStatusEntry struct {
 	Version  string                 `json:"version,omitempty"`
 	Revision string                 `json:"revision,omitempty"`
 	Groups   []jsonStatusGroupEntry `json:"groups,omitempty"`
+	Watcher  *server.WatcherStatus  `json:"watcher,omitempty"`
 }
 
 func writeJSON(v any) {
@@ -1187,24 +1612,55 @@ func doClose(addr string, paths []string, groupName string) ([]string, error) {
 	return closedPaths, joinedErr
 }
 
+// pruneBackups deletes the backups not written for longer than retention,
+// keeping those of this port and of running servers, which may just be idle.
+func pruneBackups(retention time.Duration) {
+	live := map[int]bool{port: true}
+	servers, err := registry.List(processAlive)
+	if err != nil {
+		slog.Warn("failed to list running servers", "error", err)
+		return
+	}
+	for _, e := range servers {
+		live[e.Port] = true
+	}
+	n, err := backup.Prune(retention, func(p int) bool { return live[p] })
+	if err != nil {
+		slog.Warn("failed to prune backups", "error", err)
+	} else if n > 0 {
+		slog.Info("pruned expired backups", "count", n, "retention", retention)
+	}
+}
+
 type statusResponse struct {
-	Version  string `json:"version"`
-	Revision string `json:"revision"`
-	PID      int    `json:"pid"`
-	Groups   []struct {
-		Name  string `json:"name"`
-		Files []struct {
-			Name string `json:"name"`
-			ID   string `json:"id"`
-			Path string `json:"path"`
-		} `json:"files"`
-		Patterns []string `json:"patterns,omitempty"`
-	} `json:"groups"`
+	Version  string                `json:"version"`
+	Revision string                `json:"revision"`
+	PID      int                   `json:"pid"`
+	Groups   []statusGroupResponse `json:"groups"`
+	Watcher  *server.WatcherStatus `json:"watcher,omitempty"`
+}
+
+type statusGroupResponse struct {
+	Name     string               `json:"name"`
+	Files    []statusFileResponse `json:"files"`
+	Patterns []string             `json:"patterns,omitempty"`
+}
+
+type statusFileResponse struct {
+	Name     string          `json:"name"`
+	Title    string          `json:"title"`
+	ID       string          `json:"id"`
+	Path     string          `json:"path"`
+	Uploaded bool            `json:"uploaded"`
+	Git      *server.GitInfo `json:"git,omitempty"`
 }
 
 func doStatus() error {
-	ports := discoverPorts()
-	if len(ports) == 0 {
+	servers, err := registry.List(processAlive)
+	if err != nil {
+		return err
+	}
+	if len(servers) == 0 {
 		if jsonOutput {
 			writeJSON([]jsonStatusEntry{})
 		} else {
@@ -1217,19 +1673,23 @@ func doStatus() error {
 	found := false
 	var jsonEntries []jsonStatusEntry
 
-	for i, p := range ports {
-		addr := fmt.Sprintf("localhost:%d", p)
+	for i, e := range servers {
+		addr := net.JoinHostPort(dialHost(e.Bind), strconv.Itoa(e.Port))
 		resp, err := client.Get(fmt.Sprintf("http://%s/_/api/status", addr))
 		if err != nil {
+			// The process is alive but does not answer (e.g. still
+			// starting, or hung).
 			found = true
 			if jsonOutput {
 				jsonEntries = append(jsonEntries, jsonStatusEntry{
-					URL:    fmt.Sprintf("http://%s", addr),
-					Status: "stopped",
+					URL:     fmt.Sprintf("http://%s", addr),
+					Status:  "unresponsive",
+					PID:     e.PID,
+					Version: e.Version,
 				})
 			} else {
-				fmt.Fprintf(os.Stdout, "http://%s (stopped)\n", addr)
-				if i < len(ports)-1 {
+				fmt.Fprintf(os.Stdout, "http://%s (pid %d, not responding)\n", addr, e.PID)
+				if i < len(servers)-1 {
 					fmt.Fprintln(os.Stdout)
 				}
 			}
@@ -1251,13 +1711,20 @@ func doStatus() error {
 				PID:      status.PID,
 				Version:  status.Version,
 				Revision: status.Revision,
+				Watcher:  status.Watcher,
 			}
 			for _, g := range status.Groups {
-				entry.Groups = append(entry.Groups, jsonStatusGroupEntry{
+				ge := jsonStatusGroupEntry{
 					Name:     g.Name,
 					Files:    len(g.Files),
 					Patterns: g.Patterns,
-				})
+				}
+				for _, f := range g.Files {
+					if f.Git != nil {
+						ge.Git = append(ge.Git, jsonStatusGitFile{Path: f.Path, GitInfo: f.Git})
+					}
+				}
+				entry.Groups = append(entry.Groups, ge)
 			}
 			jsonEntries = append(jsonEntries, entry)
 		} else {
@@ -1267,12 +1734,23 @@ func doStatus() error {
 			}
 			fmt.Fprintf(os.Stdout, "http://%s (pid %d, %s)\n", addr, status.PID, ver)
 			for _, g := range status.Groups {
-				fmt.Fprintf(os.Stdout, "  %s: %d file(s)\n", g.Name, len(g.Files))
+				uncommitted := 0
+				for _, f := range g.Files {
+					if f.Git != nil && f.Git.Status != "" {
+						uncommitted++
+					}
+				}
+				if uncommitted > 0 {
+					fmt.Fprintf(os.Stdout, "  %s: %d file(s), %d with uncommitted changes\n", g.Name, len(g.Files), uncommitted)
+				} else {
+					fmt.Fprintf(os.Stdout, "  %s: %d file(s)\n", g.Name, len(g.Files))
+				}
 				if len(g.Patterns) > 0 {
 					fmt.Fprintf(os.Stdout, "    watching: %s\n", strings.Join(g.Patterns, ", "))
 				}
 			}
-			if i < len(ports)-1 {
+			printWatcherStatus(os.Stdout, status.Watcher)
+			if i < len(servers)-1 {
 				fmt.Fprintln(os.Stdout)
 			}
 		}
@@ -1290,36 +1768,43 @@ func doStatus() error {
 	return nil
 }
 
-func discoverPorts() []int {
-	dir, err := logfile.Dir()
-	if err != nil {
-		return nil
+// printWatcherStatus prints the watcher health reported by a server. Nothing
+// is printed for servers that predate watcher diagnostics.
+func printWatcherStatus(w io.Writer, ws *server.WatcherStatus) {
+	if ws == nil {
+		return
 	}
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return nil
+	if !ws.Available {
+		fmt.Fprintln(w, "  watcher: unavailable (live-reload is disabled)")
+	} else {
+		fmt.Fprintf(w, "  watcher: %d dir(s), %d file(s)\n", ws.WatchedDirs, ws.WatchedFiles)
 	}
-
-	var ports []int
-	for _, e := range entries {
-		name := e.Name()
-		// Match "mo-{port}.log"
-		if !strings.HasPrefix(name, "mo-") || !strings.HasSuffix(name, ".log") {
-			continue
+	if len(ws.FailedPaths) > 0 {
+		fmt.Fprintf(w, "    failed to watch %d path(s):\n", len(ws.FailedPaths))
+		for _, f := range ws.FailedPaths {
+			fmt.Fprintf(w, "      %s: %s\n", f.Path, f.Error)
 		}
-		// Exclude rotated backups like "mo-6275.log.1"
-		raw := strings.TrimSuffix(strings.TrimPrefix(name, "mo-"), ".log")
-		p, err := strconv.Atoi(raw)
-		if err != nil {
-			continue
-		}
-		ports = append(ports, p)
 	}
-	sort.Ints(ports)
-	return ports
+	if n := len(ws.RecentErrors); n > 0 {
+		last := ws.RecentErrors[n-1]
+		fmt.Fprintf(w, "    %d recent error(s), last at %s: %s\n", n, last.Time.Local().Format(time.DateTime), last.Error)
+	}
+	if ws.Hint != "" {
+		fmt.Fprintf(w, "    hint: %s\n", ws.Hint)
+	}
 }
 
-func startServer(ctx context.Context, addr string, filesByGroup map[string][]string, patternsByGroup map[string][]string, uploadedFiles []server.UploadedFileData) error {
+// dialHost returns the host to reach a server bound to bind from this
+// machine.
+func dialHost(bind string) string {
+	switch bind {
+	case "", "0.0.0.0", "::":
+		return "localhost"
+	}
+	return bind
+}
+
+func startServer(ctx context.Context, addr string, initial server.RestoreData) error {
 	sigCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
@@ -1338,6 +1823,9 @@ func startServer(ctx context.Context, addr string, filesByGroup map[string][]str
 	defer cleanup()
 
 	state := server.NewState(ctx)
+	state.SetRenderers(renderers, renderTimeout)
+	state.SetIgnore(ignorePatterns)
+	state.SetUIDefaults(uiDefaults)
 
 	state.EnableBackup(ctx, func(data server.RestoreData) {
 		if err := backup.Save(port, data); err != nil {
@@ -1345,48 +1833,34 @@ func startServer(ctx context.Context, addr string, filesByGroup map[string][]str
 		}
 	})
 
-	var deeplinks []deeplinkEntry
-	var totalFiles, skippedFiles int
-	for group, files := range filesByGroup {
-		for _, f := range files {
-			totalFiles++
-			entry, err := state.AddFile(f, group)
-			if err != nil {
-				skippedFiles++
-				slog.Warn("skipping file", "path", f, "error", err)
-				continue
-			}
-			deeplinks = append(deeplinks, deeplinkEntry{
-				URL:  buildDeeplink(addr, group, entry.ID),
-				Path: entry.Path,
-			})
-		}
-	}
-	var patternsAdded int
-	for group, pats := range patternsByGroup {
-		for _, pat := range pats {
-			entries, err := state.AddPattern(pat, group)
-			if err != nil {
-				slog.Warn("failed to add pattern", "pattern", pat, "error", err)
-				continue
-			}
-			patternsAdded++
-			for _, entry := range entries {
-				deeplinks = append(deeplinks, deeplinkEntry{
-					URL:  buildDeeplink(addr, group, entry.ID),
-					Path: entry.Path,
-				})
-			}
-		}
+	res := state.Restore(ctx, initial)
+	deeplinks := make([]deeplinkEntry, 0, len(res.Entries))
+	for _, r := range res.Entries {
+		deeplinks = append(deeplinks, deeplinkEntry{
+			URL:   buildDeeplink(addr, r.Group, r.Entry.ID),
+			Path:  r.Entry.Path,
+			Name:  r.Entry.Name,
+			Group: r.Group,
+			ID:    r.Entry.ID,
+		})
 	}
 
-	for _, uf := range uploadedFiles {
-		state.AddUploadedFile(uf.Name, uf.Content, uf.Group)
+	totalFiles := 0
+	for _, files := range initial.Groups {
+		totalFiles += len(files)
 	}
+	skippedFiles, remotesFailed := res.FilesSkipped, res.RemotesFailed
+	patternsAdded, reviewsAdded, archivesAdded := res.PatternsAdded, res.ReviewsAdded, res.ArchivesAdded
 
-	if totalFiles > 0 && skippedFiles == totalFiles && patternsAdded == 0 && len(uploadedFiles) == 0 {
+	if totalFiles > 0 && skippedFiles == totalFiles && patternsAdded == 0 && reviewsAdded == 0 && archivesAdded == 0 && len(initial.UploadedFiles) == remotesFailed {
 		return fmt.Errorf("all %d file(s) were skipped", totalFiles)
 	}
+	if totalFiles == 0 && len(initial.Patterns) == 0 && len(initial.Reviews) == 0 && archivesAdded == 0 && remotesFailed > 0 && remotesFailed == len(initial.UploadedFiles) {
+		return fmt.Errorf("all %d URL(s) failed to load", remotesFailed)
+	}
+	if len(initial.Archives) > 0 && archivesAdded == 0 && totalFiles == 0 && len(initial.Patterns) == 0 && len(initial.Reviews) == 0 && len(initial.UploadedFiles) == 0 {
+		return fmt.Errorf("all %d archive(s) failed to load", len(initial.Archives))
+	}
 
 	handler := server.NewHandler(state)
 
@@ -1403,7 +1877,22 @@ func startServer(ctx context.Context, addr string, filesByGroup map[string][]str
 		state.CloseAllSubscribers()
 		return fmt.Errorf("cannot listen on %s: %w", addr, err)
 	}
+	if err := registry.Register(registry.Entry{
+		PID:       os.Getpid(),
+		Port:      ln.Addr().(*net.TCPAddr).Port,
+		Bind:      strings.Trim(bind, "[]"),
+		StartedAt: time.Now(),
+		Version:   version.Version,
+	}); err != nil {
+		slog.Warn("failed to register server", "error", err)
+	}
+	if err := donegroup.Cleanup(ctx, func() error {
+		return registry.Unregister(ln.Addr().(*net.TCPAddr).Port, os.Getpid())
+	}); err != nil {
+		return fmt.Errorf("failed to register cleanup: %w", err)
+	}
 
+	located := locateDeeplinks(deeplinks)
 	emitServeOutput(addr, deeplinks, true)
 
 	if err := donegroup.Cleanup(ctx, func() error {
@@ -1422,7 +1911,7 @@ func startServer(ctx context.Context, addr string, filesByGroup map[string][]str
 		}
 	}()
 
-	openBrowser(addr)
+	openBrowserAt(addr, located)
 
 	select {
 	case <-ctx.Done():
@@ -1459,6 +1948,7 @@ func spawnNewProcess(addr string, restoreFile string) (*os.Process, error) {
 	if dangerouslyAllowRemoteAccess {
 		args = append(args, "--dangerously-allow-remote-access")
 	}
+	args = append(args, rendererArgs()...)
 	cmd := exec.Command(binPath, args...) //nolint:gosec
 	setSysProcAttr(cmd)
 	if err := cmd.Start(); err != nil {
@@ -1469,16 +1959,54 @@ func spawnNewProcess(addr string, restoreFile string) (*os.Process, error) {
 	return cmd.Process, nil
 }
 
-func startBackground(addr string, filesByGroup map[string][]string, patternsByGroup map[string][]string, uploadedFiles []server.UploadedFileData) error {
-	restoreFile, err := server.WriteRestoreFile(server.RestoreData{Groups: filesByGroup, Patterns: patternsByGroup, UploadedFiles: uploadedFiles})
+func startBackground(addr string, initial server.RestoreData) error {
+	pid, status, err := spawnBackground(addr, initial)
 	if err != nil {
+		if errors.Is(err, errServerConflict) {
+			// Lost a concurrent startup race: another mo server owns the
+			// port. Add our files to the winner instead of reporting a
+			// false success.
+			return addToRunningServer(addr, status, initial)
+		}
 		return err
 	}
 
+	var deeplinks []deeplinkEntry
+	if status != nil {
+		for _, g := range status.Groups {
+			for _, f := range g.Files {
+				deeplinks = append(deeplinks, deeplinkEntry{
+					URL:   buildDeeplink(addr, g.Name, f.ID),
+					Path:  f.Path,
+					Name:  f.Name,
+					Group: g.Name,
+					ID:    f.ID,
+				})
+			}
+		}
+	}
+	located := locateDeeplinks(deeplinks)
+	emitServeOutput(addr, deeplinks, true)
+	fmt.Fprintf(os.Stderr, "mo: serving at http://%s (pid %d)\n", addr, pid)
+
+	openBrowserAt(addr, located)
+
+	return nil
+}
+
+// spawnBackground starts a mo server with initial in a background process and
+// waits for it to be ready. When another mo server wins the port first,
+// errServerConflict is returned along with that server's status.
+func spawnBackground(addr string, initial server.RestoreData) (int, *statusResponse, error) {
+	restoreFile, err := server.WriteRestoreFile(initial)
+	if err != nil {
+		return 0, nil, err
+	}
+
 	proc, err := spawnNewProcess(addr, restoreFile)
 	if err != nil {
 		os.Remove(restoreFile)
-		return err
+		return 0, nil, err
 	}
 	pid := proc.Pid
 	// Detach so the child survives parent exit.
@@ -1495,58 +2023,47 @@ func startBackground(addr string, filesByGroup map[string][]string, patternsByGr
 			os.Remove(restoreFile) //nolint:errcheck // best-effort; the child may have consumed it already
 		}
 		if errors.Is(err, errServerConflict) {
-			// Lost a concurrent startup race: another mo server owns the
-			// port. Add our files to the winner instead of reporting a
-			// false success.
-			return addToRunningServer(addr, status, filesByGroup, patternsByGroup, uploadedFiles)
+			return pid, status, err
 		}
-		return fmt.Errorf("%w (spawned pid %d)", err, pid)
+		return pid, nil, fmt.Errorf("%w (spawned pid %d)", err, pid)
 	}
-
-	var deeplinks []deeplinkEntry
-	if status != nil {
-		for _, g := range status.Groups {
-			for _, f := range g.Files {
-				deeplinks = append(deeplinks, deeplinkEntry{
-					URL:  buildDeeplink(addr, g.Name, f.ID),
-					Path: f.Path,
-					Name: f.Name,
-				})
-			}
-		}
-	}
-	emitServeOutput(addr, deeplinks, true)
-	fmt.Fprintf(os.Stderr, "mo: serving at http://%s (pid %d)\n", addr, pid)
-
-	openBrowser(addr)
-
-	return nil
+	return pid, status, nil
 }
 
 // addToRunningServer posts files, patterns, and uploaded files to a mo server
 // that is already running on addr. Used when a background start loses the
 // port to another mo instance (concurrent startup race).
-func addToRunningServer(addr string, status *statusResponse, filesByGroup map[string][]string, patternsByGroup map[string][]string, uploadedFiles []server.UploadedFileData) error {
+func addToRunningServer(addr string, status *statusResponse, initial server.RestoreData) error {
 	slog.Info("port already served by another mo instance; adding to it", "addr", addr, "pid", status.PID)
 	client := &http.Client{Timeout: probeTimeoutDefault}
 	var deeplinks []deeplinkEntry
 	added := 0
 	attempted := 0
-	for group, files := range filesByGroup {
+	for group, files := range initial.Groups {
 		attempted += len(files)
 		entries := postFiles(client, addr, group, files)
 		deeplinks = append(deeplinks, entries...)
 		added += len(entries)
 	}
-	for group, patterns := range patternsByGroup {
+	for group, patterns := range initial.Patterns {
 		attempted += len(patterns)
-		entries, patternsAdded := postPatterns(client, addr, group, patterns)
+		entries, patternsAdded := postPatterns(client, addr, group, patterns, initial.PatternIgnores[group])
 		deeplinks = append(deeplinks, entries...)
 		added += patternsAdded
 	}
-	for _, uf := range uploadedFiles {
+	for _, uf := range initial.UploadedFiles {
 		attempted++
-		entry, err := postUploadedFile(client, addr, uf.Group, uf.Name, uf.Content)
+		if uf.Remote != nil {
+			entry, err := postRemoteFile(client, addr, uf.Group, *uf.Remote)
+			if err != nil {
+				slog.Warn("failed to add remote file", "url", uf.Remote.URL, "error", err)
+				continue
+			}
+			deeplinks = append(deeplinks, entry)
+			added++
+			continue
+		}
+		entry, err := postUploadedFile(client, addr, uf.Group, uf)
 		if err != nil {
 			slog.Warn("failed to upload file", "name", uf.Name, "error", err)
 			continue
@@ -1554,9 +2071,30 @@ func addToRunningServer(addr string, status *statusResponse, filesByGroup map[st
 		deeplinks = append(deeplinks, entry)
 		added++
 	}
+	for _, rv := range initial.Reviews {
+		attempted++
+		entries, err := postReview(client, addr, rv)
+		if err != nil {
+			slog.Warn("failed to add review", "root", rv.Root, "base", rv.Base, "error", err)
+			continue
+		}
+		deeplinks = append(deeplinks, entries...)
+		added++
+	}
+	for _, ad := range initial.Archives {
+		attempted++
+		entries, err := postArchive(client, addr, ad)
+		if err != nil {
+			slog.Warn("failed to add archive", "path", ad.Path, "error", err)
+			continue
+		}
+		deeplinks = append(deeplinks, entries...)
+		added++
+	}
 	if attempted > 0 && added == 0 {
 		return fmt.Errorf("failed to add any items to the mo server at http://%s (check log file for details)", addr)
 	}
+	located := locateDeeplinks(deeplinks)
 	emitServeOutput(addr, deeplinks, true)
 	fmt.Fprintf(os.Stderr, "mo: another mo server is already running at http://%s (pid %d); added %d item(s) to it\n", addr, status.PID, added)
 
@@ -1567,20 +2105,36 @@ func addToRunningServer(addr string, status *statusResponse, filesByGroup map[st
 			break
 		}
 	}
-	if isNewGroup || open {
+	if located != nil {
+		showLocation(client, addr, located)
+	} else if isNewGroup || open {
 		openBrowser(addr)
 	}
 	return nil
 }
 
 func openBrowser(addr string) {
-	if noOpen {
-		return
-	}
 	url := fmt.Sprintf("http://%s", addr)
 	if target != server.DefaultGroup {
 		url = fmt.Sprintf("%s/%s", url, target)
 	}
+	openURL(url)
+}
+
+// openBrowserAt opens the deeplink of located, or the target group when it
+// is nil.
+func openBrowserAt(addr string, located *deeplinkEntry) {
+	if located == nil {
+		openBrowser(addr)
+		return
+	}
+	openURL(located.URL)
+}
+
+func openURL(url string) {
+	if noOpen {
+		return
+	}
 	if err := browser.OpenURL(url); err != nil {
 		slog.Warn("could not open browser", "error", err)
 	}
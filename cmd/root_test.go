@@ -2,9 +2,12 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -12,11 +15,19 @@ import (
 	"path/filepath"
 	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/k1LoW/donegroup"
+	"github.com/k1LoW/mo/internal/backup"
+	"github.com/k1LoW/mo/internal/config"
+	"github.com/k1LoW/mo/internal/fencerender"
+	"github.com/k1LoW/mo/internal/project"
+	"github.com/k1LoW/mo/internal/registry"
 	"github.com/k1LoW/mo/internal/server"
+	"github.com/spf13/cobra"
 )
 
 func TestRun_UnwatchWithWatch(t *testing.T) {
@@ -211,15 +222,7 @@ func TestResolveUnwatchArgs_RecursiveDirectory(t *testing.T) {
 	// Set up a mock server that returns patterns for the group.
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		resp := statusResponse{
-			Groups: []struct {
-				Name  string `json:"name"`
-				Files []struct {
-					Name string `json:"name"`
-					ID   string `json:"id"`
-					Path string `json:"path"`
-				} `json:"files"`
-				Patterns []string `json:"patterns,omitempty"`
-			}{
+			Groups: []statusGroupResponse{
 				{
 					Name: "default",
 					Patterns: []string{
@@ -258,15 +261,7 @@ func TestResolveUnwatchArgs_RecursiveNoMatch(t *testing.T) {
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		resp := statusResponse{
-			Groups: []struct {
-				Name  string `json:"name"`
-				Files []struct {
-					Name string `json:"name"`
-					ID   string `json:"id"`
-					Path string `json:"path"`
-				} `json:"files"`
-				Patterns []string `json:"patterns,omitempty"`
-			}{
+			Groups: []statusGroupResponse{
 				{
 					Name:     "default",
 					Patterns: []string{"/other/path/*.md"},
@@ -295,15 +290,7 @@ func TestResolveUnwatchArgs_RecursiveDeletedDirectory(t *testing.T) {
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		resp := statusResponse{
-			Groups: []struct {
-				Name  string `json:"name"`
-				Files []struct {
-					Name string `json:"name"`
-					ID   string `json:"id"`
-					Path string `json:"path"`
-				} `json:"files"`
-				Patterns []string `json:"patterns,omitempty"`
-			}{
+			Groups: []statusGroupResponse{
 				{
 					Name: "default",
 					Patterns: []string{
@@ -346,15 +333,7 @@ func TestResolveUnwatchArgs_RecursiveGroupNotFound(t *testing.T) {
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		resp := statusResponse{
-			Groups: []struct {
-				Name  string `json:"name"`
-				Files []struct {
-					Name string `json:"name"`
-					ID   string `json:"id"`
-					Path string `json:"path"`
-				} `json:"files"`
-				Patterns []string `json:"patterns,omitempty"`
-			}{
+			Groups: []statusGroupResponse{
 				{
 					Name:     "other",
 					Patterns: []string{"/other/*.md"},
@@ -985,7 +964,7 @@ func TestAddToRunningServer(t *testing.T) {
 	addr := strings.TrimPrefix(srv.URL, "http://")
 
 	status := &statusResponse{PID: 12345}
-	err := addToRunningServer(addr, status, map[string][]string{"default": {"/tmp/x.md"}}, nil, nil)
+	err := addToRunningServer(addr, status, server.RestoreData{Groups: map[string][]string{"default": {"/tmp/x.md"}}})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1008,7 +987,7 @@ func TestAddToRunningServer_AllPostsFail(t *testing.T) {
 	addr := strings.TrimPrefix(srv.URL, "http://")
 
 	status := &statusResponse{PID: 12345}
-	err := addToRunningServer(addr, status, map[string][]string{"default": {"/tmp/x.md"}}, nil, nil)
+	err := addToRunningServer(addr, status, server.RestoreData{Groups: map[string][]string{"default": {"/tmp/x.md"}}})
 	if err == nil {
 		t.Fatal("expected error when every POST fails, got nil")
 	}
@@ -1034,7 +1013,7 @@ func TestAddToRunningServer_PatternsAllFail(t *testing.T) {
 	addr := strings.TrimPrefix(srv.URL, "http://")
 
 	status := &statusResponse{PID: 12345}
-	err := addToRunningServer(addr, status, nil, map[string][]string{"default": {"*.md"}}, nil)
+	err := addToRunningServer(addr, status, server.RestoreData{Patterns: map[string][]string{"default": {"*.md"}}})
 	if err == nil {
 		t.Fatal("expected error when every pattern POST fails, got nil")
 	}
@@ -1060,7 +1039,7 @@ func TestAddToRunningServer_PatternWithZeroMatches(t *testing.T) {
 	addr := strings.TrimPrefix(srv.URL, "http://")
 
 	status := &statusResponse{PID: 12345}
-	err := addToRunningServer(addr, status, nil, map[string][]string{"default": {"*.md"}}, nil)
+	err := addToRunningServer(addr, status, server.RestoreData{Patterns: map[string][]string{"default": {"*.md"}}})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1106,7 +1085,7 @@ func TestResolveArgs_Directory(t *testing.T) {
 	writeTestFile(t, filepath.Join(dir, "b.md"), []byte("# B"))
 	writeTestFile(t, filepath.Join(dir, "c.txt"), []byte("text"))
 
-	files, patterns, err := resolveArgs([]string{dir}, false, false)
+	files, patterns, _, err := resolveArgs([]string{dir}, false, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1129,7 +1108,7 @@ func TestResolveArgs_DirectoryNaturalOrder(t *testing.T) {
 		writeTestFile(t, filepath.Join(dir, name), []byte("# "+name))
 	}
 
-	files, _, err := resolveArgs([]string{dir}, false, false)
+	files, _, _, err := resolveArgs([]string{dir}, false, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1154,7 +1133,7 @@ func TestResolveArgs_DirectoryWithWatch(t *testing.T) {
 	dir := t.TempDir()
 	writeTestFile(t, filepath.Join(dir, "a.md"), []byte("# A"))
 
-	files, patterns, err := resolveArgs([]string{dir}, true, false)
+	files, patterns, _, err := resolveArgs([]string{dir}, true, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1174,7 +1153,7 @@ func TestResolveArgs_DirectoryWithWatchRecursive(t *testing.T) {
 	dir := t.TempDir()
 	writeTestFile(t, filepath.Join(dir, "a.md"), []byte("# A"))
 
-	files, patterns, err := resolveArgs([]string{dir}, true, true)
+	files, patterns, _, err := resolveArgs([]string{dir}, true, true)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1200,7 +1179,7 @@ func TestResolveArgs_DirectoryRecursive(t *testing.T) {
 	writeTestFile(t, filepath.Join(sub, "b.md"), []byte("# B"))
 	writeTestFile(t, filepath.Join(sub, "c.txt"), []byte("text"))
 
-	files, patterns, err := resolveArgs([]string{dir}, false, true)
+	files, patterns, _, err := resolveArgs([]string{dir}, false, true)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1221,7 +1200,7 @@ func TestResolveArgs_GlobPositional_WatchMode(t *testing.T) {
 	writeTestFile(t, filepath.Join(dir, "a.md"), []byte("# A"))
 	pattern := filepath.Join(dir, "*.md")
 
-	files, patterns, err := resolveArgs([]string{pattern}, true, false)
+	files, patterns, _, err := resolveArgs([]string{pattern}, true, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1242,7 +1221,7 @@ func TestResolveArgs_GlobPositional_NonWatch(t *testing.T) {
 	writeTestFile(t, filepath.Join(dir, "b.md"), []byte("# B"))
 	pattern := filepath.Join(dir, "*.md")
 
-	files, patterns, err := resolveArgs([]string{pattern}, false, false)
+	files, patterns, _, err := resolveArgs([]string{pattern}, false, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1257,7 +1236,7 @@ func TestResolveArgs_GlobPositional_NonWatch(t *testing.T) {
 func TestResolveArgs_EmptyDirectory(t *testing.T) {
 	dir := t.TempDir()
 
-	_, _, err := resolveArgs([]string{dir}, false, false)
+	_, _, _, err := resolveArgs([]string{dir}, false, false)
 	if err == nil {
 		t.Fatal("expected error for empty directory")
 	}
@@ -1266,6 +1245,126 @@ func TestResolveArgs_EmptyDirectory(t *testing.T) {
 	}
 }
 
+func TestResolveArgs_URL(t *testing.T) {
+	dir := t.TempDir()
+	local := filepath.Join(dir, "a.md")
+	writeTestFile(t, local, []byte("# A"))
+
+	files, _, urls, err := resolveArgs([]string{"https://example.com/docs/README.md", local, "http://localhost:8080/a.md"}, false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 1 || files[0] != local {
+		t.Errorf("got files %v, want [%s]", files, local)
+	}
+	want := []string{"https://example.com/docs/README.md", "http://localhost:8080/a.md"}
+	if !slices.Equal(urls, want) {
+		t.Errorf("got urls %v, want %v", urls, want)
+	}
+}
+
+func TestResolveArchiveArgs(t *testing.T) {
+	dir := t.TempDir()
+	local := filepath.Join(dir, "a.md")
+	writeTestFile(t, local, []byte("# A"))
+	bundle := filepath.Join(dir, "docs-bundle.zip")
+	writeTestFile(t, bundle, []byte("PK"))
+	release := filepath.Join(dir, "release-1.0.TAR.GZ")
+	writeTestFile(t, release, []byte{0x1f, 0x8b})
+	missing := filepath.Join(dir, "missing.zip")
+
+	args := []string{local, bundle, release, missing}
+	archives, rest, err := resolveArchiveArgs(args, "default", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(rest, []string{local, missing}) {
+		t.Errorf("got rest %v", rest)
+	}
+	if len(archives) != 2 || archives[0].Path != bundle || archives[0].Group != "docs-bundle" || archives[1].Group != "release-1.0" {
+		t.Errorf("unexpected archives %+v", archives)
+	}
+
+	archives, _, err = resolveArchiveArgs(args, "docs", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, ad := range archives {
+		if ad.Group != "docs" {
+			t.Errorf("got group %q, want docs", ad.Group)
+		}
+	}
+}
+
+func TestParseRenderers(t *testing.T) {
+	got, err := parseRenderers([]string{"dot=dot -Tsvg", "plantuml=plantuml -tsvg -pipe"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[1].Lang != "plantuml" || !slices.Equal(got[1].Command, []string{"plantuml", "-tsvg", "-pipe"}) {
+		t.Errorf("unexpected renderers %+v", got)
+	}
+	if _, err := parseRenderers([]string{"dot"}); err == nil {
+		t.Error("want an error for a renderer without a command")
+	}
+
+	renderers, renderTimeout = got, fencerender.DefaultTimeout
+	t.Cleanup(func() { renderers = nil })
+	want := []string{"--renderer", "dot=dot -Tsvg", "--renderer", "plantuml=plantuml -tsvg -pipe"}
+	if args := rendererArgs(); !slices.Equal(args, want) {
+		t.Errorf("got args %v, want %v", args, want)
+	}
+}
+
+func TestListEntries(t *testing.T) {
+	status := &statusResponse{
+		Version: "0.0.0",
+		Groups: []statusGroupResponse{
+			{
+				Name: "default",
+				Files: []statusFileResponse{
+					{Name: "README.md", Title: "Project", ID: "a1b2c3d4", Path: "/repo/README.md"},
+					{Name: "README.md", ID: "e5f6a7b8", Path: "/repo/docs/README.md"},
+				},
+			},
+			{
+				Name: "notes",
+				Files: []statusFileResponse{
+					{Name: "stdin-1234.md", Title: "Notes", ID: "u1", Uploaded: true},
+				},
+			},
+		},
+	}
+
+	entries, err := listEntries("localhost:6275", status, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []jsonListEntry{
+		{Group: "default", Name: "repo/README.md", Title: "Project", ID: "a1b2c3d4", Path: "/repo/README.md", URL: "http://localhost:6275/?file=a1b2c3d4"},
+		{Group: "default", Name: "docs/README.md", ID: "e5f6a7b8", Path: "/repo/docs/README.md", URL: "http://localhost:6275/?file=e5f6a7b8"},
+		{Group: "notes", Name: "stdin-1234.md", Title: "Notes", ID: "u1", Uploaded: true, URL: "http://localhost:6275/notes?file=u1"},
+	}
+	if !slices.Equal(entries, want) {
+		t.Errorf("got %+v, want %+v", entries, want)
+	}
+
+	var buf bytes.Buffer
+	printList(&buf, entries)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "GROUP") || !strings.Contains(lines[3], "(uploaded)") {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+
+	entries, err = listEntries("localhost:6275", status, "notes")
+	if err != nil || len(entries) != 1 {
+		t.Errorf("got %+v, %v", entries, err)
+	}
+	if _, err := listEntries("localhost:6275", status, "missing"); err == nil {
+		t.Error("want an error for a missing group")
+	}
+}
+
 func TestStdinName(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -1383,7 +1482,7 @@ func TestReadStdin(t *testing.T) {
 func TestResolveArgs_EmptyDirectoryWithWatch(t *testing.T) {
 	dir := t.TempDir()
 
-	files, patterns, err := resolveArgs([]string{dir}, true, false)
+	files, patterns, _, err := resolveArgs([]string{dir}, true, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1402,7 +1501,7 @@ func TestResolveArgs_MixedFilesAndDirs(t *testing.T) {
 	singleFile := filepath.Join(t.TempDir(), "standalone.md")
 	writeTestFile(t, singleFile, []byte("# Standalone"))
 
-	files, patterns, err := resolveArgs([]string{dir, singleFile}, false, false)
+	files, patterns, _, err := resolveArgs([]string{dir, singleFile}, false, false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1413,3 +1512,1066 @@ func TestResolveArgs_MixedFilesAndDirs(t *testing.T) {
 		t.Fatalf("got %d files, want 2: %v", len(files), files)
 	}
 }
+
+func TestPrintWatcherStatus(t *testing.T) {
+	t.Run("nil prints nothing", func(t *testing.T) {
+		var buf bytes.Buffer
+		printWatcherStatus(&buf, nil)
+		if buf.Len() != 0 {
+			t.Errorf("got %q, want empty output", buf.String())
+		}
+	})
+
+	t.Run("healthy watcher", func(t *testing.T) {
+		var buf bytes.Buffer
+		printWatcherStatus(&buf, &server.WatcherStatus{Available: true, WatchedDirs: 2, WatchedFiles: 5})
+		want := "  watcher: 2 dir(s), 5 file(s)\n"
+		if buf.String() != want {
+			t.Errorf("got %q, want %q", buf.String(), want)
+		}
+	})
+
+	t.Run("failures and hint", func(t *testing.T) {
+		var buf bytes.Buffer
+		printWatcherStatus(&buf, &server.WatcherStatus{
+			Available:    true,
+			FailedPaths:  []server.WatchError{{Path: "/docs", Error: "no space left on device"}},
+			RecentErrors: []server.WatchError{{Time: time.Now(), Path: "/docs", Error: "no space left on device"}},
+			LimitReached: true,
+			Hint:         "raise fs.inotify.max_user_watches",
+		})
+		out := buf.String()
+		for _, want := range []string{
+			"failed to watch 1 path(s)",
+			"/docs: no space left on device",
+			"1 recent error(s)",
+			"hint: raise fs.inotify.max_user_watches",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("output %q does not contain %q", out, want)
+			}
+		}
+	})
+
+	t.Run("unavailable watcher", func(t *testing.T) {
+		var buf bytes.Buffer
+		printWatcherStatus(&buf, &server.WatcherStatus{})
+		if !strings.Contains(buf.String(), "unavailable") {
+			t.Errorf("got %q, want unavailable notice", buf.String())
+		}
+	})
+}
+
+func TestNormalizeExtensions(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []string
+		want    []string
+		wantErr bool
+	}{
+		{"plain", []string{"md", "qmd"}, []string{"md", "qmd"}, false},
+		{"strips dots and spaces", []string{".md", " txt "}, []string{"md", "txt"}, false},
+		{"drops duplicates", []string{"md", ".md", "txt"}, []string{"md", "txt"}, false},
+		{"rejects empty", []string{"md", ""}, nil, true},
+		{"rejects glob chars", []string{"m*"}, nil, true},
+		{"rejects braces", []string{"{md}"}, nil, true},
+		{"rejects separators", []string{"a/b"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeExtensions(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("normalizeExtensions(%v) should return error", tt.in)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("normalizeExtensions(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownGlobFor(t *testing.T) {
+	tests := []struct {
+		exts      []string
+		recursive bool
+		want      string
+	}{
+		{nil, false, "*.md"},
+		{nil, true, "**/*.md"},
+		{[]string{"txt"}, false, "*.txt"},
+		{[]string{"md", "qmd", "txt"}, false, "*.{md,qmd,txt}"},
+		{[]string{"md", "rmd"}, true, "**/*.{md,rmd}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			extensions = tt.exts
+			defer func() { extensions = nil }()
+			if got := markdownGlobFor(tt.recursive); got != tt.want {
+				t.Errorf("markdownGlobFor(%v) with %v = %q, want %q", tt.recursive, tt.exts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveArgs_DirectoryWithExtensions(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.md"), []byte("# A"))
+	writeTestFile(t, filepath.Join(dir, "b.qmd"), []byte("# B"))
+	writeTestFile(t, filepath.Join(dir, "c.txt"), []byte("text"))
+	writeTestFile(t, filepath.Join(dir, "d.go"), []byte("package d"))
+
+	extensions = []string{"md", "qmd", "txt"}
+	defer func() { extensions = nil }()
+
+	files, _, _, err := resolveArgs([]string{dir}, false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "a.md"),
+		filepath.Join(dir, "b.qmd"),
+		filepath.Join(dir, "c.txt"),
+	}
+	if !slices.Equal(files, want) {
+		t.Errorf("got files %v, want %v", files, want)
+	}
+
+	_, patterns, _, err := resolveArgs([]string{dir}, true, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(patterns) != 1 || patterns[0] != filepath.Join(dir, "*.{md,qmd,txt}") {
+		t.Errorf("got patterns %v, want [%s]", patterns, filepath.Join(dir, "*.{md,qmd,txt}"))
+	}
+}
+
+func TestResolveArgs_EmptyDirectoryWithExtensions(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.md"), []byte("# A"))
+
+	extensions = []string{"qmd", "rmd"}
+	defer func() { extensions = nil }()
+
+	_, _, _, err := resolveArgs([]string{dir}, false, false)
+	if err == nil {
+		t.Fatal("expected error for directory without matching files")
+	}
+	if !strings.Contains(err.Error(), "no .qmd/.rmd files") {
+		t.Fatalf("got error %q, want extension list in message", err.Error())
+	}
+}
+
+// commitTestFiles writes files into a git repository at dir (initializing it
+// if needed) and commits them.
+func commitTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	gitCmd := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=mo", "GIT_AUTHOR_EMAIL=mo@example.com",
+			"GIT_COMMITTER_NAME=mo", "GIT_COMMITTER_EMAIL=mo@example.com",
+			"GIT_CONFIG_GLOBAL=/dev/null",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		gitCmd("init", "-q", "-b", "main")
+	}
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(dir, name), []byte(content))
+	}
+	gitCmd("add", "-A")
+	gitCmd("commit", "-q", "-m", "update")
+}
+
+func TestSplitRevisionArg(t *testing.T) {
+	dir := t.TempDir()
+	withAt := filepath.Join(dir, "notes@2024.md")
+	writeTestFile(t, withAt, []byte("# Notes"))
+
+	tests := []struct {
+		name     string
+		arg      string
+		rev      string
+		wantPath string
+		wantRev  string
+		wantOK   bool
+	}{
+		{"path@rev", "README.md@HEAD~3", "", "README.md", "HEAD~3", true},
+		{"remote branch", "docs/a.md@origin/main", "", "docs/a.md", "origin/main", true},
+		{"--rev applies to every argument", "README.md", "main", "README.md", "main", true},
+		{"existing file containing @", withAt, "", "", "", false},
+		{"plain file", "README.md", "", "", "", false},
+		{"trailing @", "README.md@", "", "", "", false},
+		{"scoped directory", "node_modules/@scope", "", "", "", false},
+		{"URL with @", "https://git.example.com/raw/README.md@main", "", "", "", false},
+		{"reflog entry", "notes.md@HEAD@{1}", "", "notes.md", "HEAD@{1}", true},
+		{"upstream", "README.md@main@{upstream}", "", "README.md", "main@{upstream}", true},
+		{"upstream of the current branch", "README.md@@{upstream}", "", "README.md", "@{upstream}", true},
+		{"existing file containing @ at a reflog entry", withAt + "@HEAD@{2}", "", withAt, "HEAD@{2}", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, rev, ok := splitRevisionArg(tt.arg, tt.rev)
+			if ok != tt.wantOK || path != tt.wantPath || rev != tt.wantRev {
+				t.Errorf("got (%q, %q, %v), want (%q, %q, %v)", path, rev, ok, tt.wantPath, tt.wantRev, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestResolveRevisionArgs(t *testing.T) {
+	dir := t.TempDir()
+	commitTestFiles(t, dir, map[string]string{"README.md": "# Old", "gone.md": "# Gone"})
+	if err := os.Remove(filepath.Join(dir, "gone.md")); err != nil {
+		t.Fatal(err)
+	}
+	commitTestFiles(t, dir, map[string]string{"README.md": "# New"})
+	other := filepath.Join(dir, "other.md")
+	writeTestFile(t, other, []byte("# Other"))
+
+	readme := filepath.Join(dir, "README.md")
+	files, rest, err := resolveRevisionArgs([]string{readme + "@HEAD~1", filepath.Join(dir, "gone.md") + "@HEAD~1", other}, "", "docs")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(rest, []string{other}) {
+		t.Errorf("got rest %v, want [%s]", rest, other)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d revision files, want 2", len(files))
+	}
+	if files[0].Name != "README.md@HEAD~1" || files[0].Content != "# Old" || files[0].Group != "docs" {
+		t.Errorf("got %+v, want README.md at HEAD~1", files[0])
+	}
+	if files[0].Git == nil || files[0].Git.Path != "README.md" || files[0].Git.Rev != "HEAD~1" {
+		t.Errorf("got git source %+v, want README.md at HEAD~1", files[0].Git)
+	}
+	if files[1].Content != "# Gone" {
+		t.Errorf("got %q, want content of a file deleted from the work tree", files[1].Content)
+	}
+
+	if _, _, err := resolveRevisionArgs([]string{readme}, "no-such-rev", "docs"); err == nil {
+		t.Error("expected error for an unknown revision")
+	}
+	if _, _, err := resolveRevisionArgs([]string{other}, "HEAD", "docs"); err == nil {
+		t.Error("expected error for a file missing at the revision")
+	}
+}
+
+func TestResolveReview(t *testing.T) {
+	dir := t.TempDir()
+	commitTestFiles(t, dir, map[string]string{"README.md": "# Readme"})
+	sub := filepath.Join(dir, "docs")
+	if err := os.Mkdir(sub, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	rv, err := resolveReview(sub, nil, "review")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rv.Base != "main" {
+		t.Errorf("got base %q, want the default branch main", rv.Base)
+	}
+	if root, _ := filepath.EvalSymlinks(dir); rv.Root != dir && rv.Root != root {
+		t.Errorf("got root %q, want %q", rv.Root, dir)
+	}
+	if rv.Group != "review" {
+		t.Errorf("got group %q, want review", rv.Group)
+	}
+
+	if rv, err := resolveReview(dir, []string{"HEAD"}, "review"); err != nil || rv.Base != "HEAD" {
+		t.Errorf("got %+v, %v; want base HEAD", rv, err)
+	}
+	if _, err := resolveReview(dir, []string{"no-such-branch"}, "review"); err == nil {
+		t.Error("expected error for an unknown base")
+	}
+	if _, err := resolveReview(dir, []string{"main", "HEAD"}, "review"); err == nil {
+		t.Error("expected error for more than one base")
+	}
+	if _, err := resolveReview(t.TempDir(), nil, "review"); err == nil {
+		t.Error("expected error outside a git repository")
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	origPort, origBind, origOpen, origNoOpen, origTarget, origExts := port, bind, open, noOpen, target, extensions
+	defer func() {
+		port, bind, open, noOpen, target, extensions = origPort, origBind, origOpen, origNoOpen, origTarget, origExts
+		ignorePatterns, uiDefaults, backupRetention, rendererSpecs, targetSet = nil, server.UIDefaults{}, 0, nil, false
+	}()
+
+	cfg := config.Config{
+		Port:            "7000",
+		Bind:            "0.0.0.0",
+		Open:            config.OpenNever,
+		Target:          "docs",
+		Extensions:      []string{"md", "qmd"},
+		Ignore:          []string{"node_modules"},
+		Theme:           "dark",
+		BackupRetention: config.Duration(time.Hour),
+		Renderers:       []string{"dot=dot -Tsvg"},
+	}
+
+	t.Run("config fills options not given as flags", func(t *testing.T) {
+		port, bind, open, noOpen, target, extensions = 6275, "localhost", false, false, server.DefaultGroup, []string{defaultExtension}
+		applyConfig(cfg, func(string) bool { return false })
+		if port != 7000 || bind != "0.0.0.0" || open || !noOpen || target != "docs" || !slices.Equal(extensions, []string{"md", "qmd"}) {
+			t.Errorf("got port=%d bind=%s open=%v noOpen=%v target=%s ext=%v", port, bind, open, noOpen, target, extensions)
+		}
+		if !targetSet {
+			t.Error("a configured target should count as set")
+		}
+		if !slices.Equal(ignorePatterns, []string{"node_modules"}) || uiDefaults.Theme != "dark" || backupRetention != time.Hour {
+			t.Errorf("got ignore=%v ui=%+v retention=%s", ignorePatterns, uiDefaults, backupRetention)
+		}
+		if !slices.Equal(rendererSpecs, []string{"dot=dot -Tsvg"}) {
+			t.Errorf("got renderers %v", rendererSpecs)
+		}
+	})
+
+	t.Run("config picks a free port", func(t *testing.T) {
+		port = 6275
+		applyConfig(config.Config{Port: "auto"}, func(string) bool { return false })
+		if port != autoPort {
+			t.Errorf("got port=%d, want auto", port)
+		}
+		if targetSet {
+			t.Error("the target should not count as set without a flag or config")
+		}
+	})
+
+	t.Run("flags win over config", func(t *testing.T) {
+		port, bind, open, noOpen, target, extensions = 6276, "localhost", true, false, "notes", []string{"txt"}
+		rendererSpecs = []string{"d2=d2 - -"}
+		applyConfig(cfg, func(string) bool { return true })
+		if port != 6276 || bind != "localhost" || !open || noOpen || target != "notes" || !slices.Equal(extensions, []string{"txt"}) {
+			t.Errorf("got port=%d bind=%s open=%v noOpen=%v target=%s ext=%v", port, bind, open, noOpen, target, extensions)
+		}
+		if !slices.Equal(rendererSpecs, []string{"d2=d2 - -"}) {
+			t.Errorf("got renderers %v", rendererSpecs)
+		}
+	})
+}
+
+func TestExpandGlobPattern_Ignore(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.md"), []byte("# A"))
+	writeTestFile(t, filepath.Join(dir, "a.draft.md"), []byte("# Draft"))
+	if err := os.Mkdir(filepath.Join(dir, "node_modules"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dir, "node_modules", "b.md"), []byte("# B"))
+
+	ignorePatterns = []string{"node_modules", "*.draft.md"}
+	defer func() { ignorePatterns = nil }()
+
+	files, _, _, err := resolveArgs([]string{dir}, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(dir, "a.md")}; !slices.Equal(files, want) {
+		t.Errorf("got %v, want %v", files, want)
+	}
+}
+
+func TestResolveProject(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"README.md", "docs/a.md", "docs/b.md", "docs/archive/old.md", "docs/skip.draft.md"} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		writeTestFile(t, p, []byte("# "+name))
+	}
+	proj := &project.Project{
+		Dir:    dir,
+		Ignore: []string{"*.draft.md"},
+		Groups: []project.Group{
+			{Name: "docs", Files: []string{"docs/b.md", "docs/**/*.md", "missing.md"}, Watch: []string{"docs/"}, Ignore: []string{"docs/archive"}},
+			{Name: "readme", Files: []string{"README.md"}},
+		},
+	}
+
+	groups, err := resolveProject(proj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	docs := groups[0]
+	wantFiles := []string{filepath.Join(dir, "docs", "b.md"), filepath.Join(dir, "docs", "a.md")}
+	if docs.name != "docs" || !slices.Equal(docs.files, wantFiles) {
+		t.Errorf("got %s %v, want docs %v", docs.name, docs.files, wantFiles)
+	}
+	if want := []string{filepath.Join(dir, "docs", "*.md")}; !slices.Equal(docs.patterns, want) {
+		t.Errorf("got patterns %v, want %v", docs.patterns, want)
+	}
+	if !config.IgnoredAbs(docs.ignore, filepath.Join(dir, "docs", "archive", "x.md")) {
+		t.Errorf("got ignore %v", docs.ignore)
+	}
+	if groups[1].name != "readme" || !slices.Equal(groups[1].files, []string{filepath.Join(dir, "README.md")}) {
+		t.Errorf("got %+v", groups[1])
+	}
+}
+
+func TestPostProject(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.md", "b.md", "c.md", "c.draft.md"} {
+		writeTestFile(t, filepath.Join(dir, name), []byte("# "+name))
+	}
+	ctx, cancel := donegroup.WithCancel(context.Background())
+	defer cancel()
+	state := server.NewState(ctx)
+	if _, err := state.AddFile(filepath.Join(dir, "c.md"), "docs"); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server.NewHandler(state))
+	defer ts.Close()
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	groups := []projectGroup{{
+		name:     "docs",
+		files:    []string{filepath.Join(dir, "b.md"), filepath.Join(dir, "a.md")},
+		patterns: []string{filepath.Join(dir, "*.md")},
+		ignore:   config.AbsIgnore(dir, []string{"*.draft.md"}),
+	}}
+	_, added := postProject(&http.Client{Timeout: probeTimeoutDefault}, addr, groups)
+	if added != 3 {
+		t.Errorf("got %d added, want 3", added)
+	}
+
+	var names []string
+	for _, g := range state.Groups() {
+		if g.Name != "docs" {
+			continue
+		}
+		for _, f := range g.Files {
+			names = append(names, f.Name)
+		}
+	}
+	if got := strings.Join(names, ","); got != "b.md,a.md,c.md" {
+		t.Errorf("got %s, want b.md,a.md,c.md", got)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	t.Setenv("MO_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeTestFile(t, filepath.Join(dir, "a.md"), []byte("# A"))
+
+	origPort, origBind, origTarget := port, bind, target
+	defer func() {
+		port, bind, target = origPort, origBind, origTarget
+		closeFiles, unwatchMode = false, false
+	}()
+
+	t.Run("falls back to files without a server", func(t *testing.T) {
+		port, bind = freePort(t), "localhost"
+		if _, directive := completeTarget(rootCmd, nil, ""); directive != cobra.ShellCompDirectiveDefault {
+			t.Errorf("got directive %d, want default", directive)
+		}
+		closeFiles = true
+		defer func() { closeFiles = false }()
+		if _, directive := completeArgs(rootCmd, nil, ""); directive != cobra.ShellCompDirectiveDefault {
+			t.Errorf("got directive %d, want default", directive)
+		}
+	})
+
+	ctx, cancel := donegroup.WithCancel(context.Background())
+	defer cancel()
+	state := server.NewState(ctx)
+	if _, err := state.AddFile(filepath.Join(dir, "a.md"), "docs"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := state.AddPattern(filepath.Join(dir, "*.md"), "notes"); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server.NewHandler(state))
+	defer ts.Close()
+	host, p, _ := strings.Cut(strings.TrimPrefix(ts.URL, "http://"), ":")
+	bind = host
+	port, _ = strconv.Atoi(p)
+
+	t.Run("target", func(t *testing.T) {
+		got, directive := completeTarget(rootCmd, nil, "d")
+		if !slices.Equal(got, []string{"docs"}) || directive != cobra.ShellCompDirectiveNoFileComp {
+			t.Errorf("got %v %d", got, directive)
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		closeFiles, target = true, "docs"
+		defer func() { closeFiles = false }()
+		got, _ := completeArgs(rootCmd, nil, "")
+		if !slices.Equal(got, []string{"a.md"}) {
+			t.Errorf("got %v", got)
+		}
+	})
+
+	t.Run("unwatch", func(t *testing.T) {
+		unwatchMode, target = true, "notes"
+		defer func() { unwatchMode = false }()
+		got, _ := completeArgs(rootCmd, nil, "*")
+		if !slices.Equal(got, []string{"*.md"}) {
+			t.Errorf("got %v", got)
+		}
+	})
+
+	t.Run("other arguments complete as files", func(t *testing.T) {
+		if _, directive := completeArgs(rootCmd, nil, ""); directive != cobra.ShellCompDirectiveDefault {
+			t.Errorf("got directive %d, want default", directive)
+		}
+	})
+}
+
+// freePort returns a port nothing listens on.
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestResolveTransferArgs(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.Mkdir(filepath.Join(dir, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	g := statusGroupResponse{
+		Name: "a",
+		Files: []statusFileResponse{
+			{ID: "1", Name: "z.md", Path: filepath.Join(dir, "z.md")},
+			{ID: "2", Name: "guide.md", Path: filepath.Join(dir, "docs", "guide.md")},
+			{ID: "3", Name: "notes.md", Path: filepath.Join(dir, "notes.md")},
+			{ID: "4", Name: "stdin.md", Uploaded: true},
+		},
+		Patterns: []string{filepath.Join(dir, "docs", "*.md")},
+	}
+
+	items, err := resolveTransferArgs([]string{"notes.md", "4", "*.md", "docs"}, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, f := range items.files {
+		ids = append(ids, f.ID)
+	}
+	if got := strings.Join(ids, ","); got != "1,3,4" {
+		t.Errorf("got files %s, want 1,3,4 in group order", got)
+	}
+	if !slices.Equal(items.patterns, g.Patterns) {
+		t.Errorf("got patterns %v", items.patterns)
+	}
+
+	if _, err := resolveTransferArgs([]string{"missing.md", "*.txt"}, g); err == nil ||
+		!strings.Contains(err.Error(), "missing.md") || !strings.Contains(err.Error(), "*.txt") {
+		t.Errorf("got %v, want errors for both arguments", err)
+	}
+}
+
+func TestDoTransfer(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for _, name := range []string{"a.md", "b.md", "c.md"} {
+		writeTestFile(t, filepath.Join(dir, name), []byte("# "+name))
+	}
+	other := filepath.Join(t.TempDir(), "other.md")
+	writeTestFile(t, other, []byte("# Other"))
+
+	ctx, cancel := donegroup.WithCancel(context.Background())
+	defer cancel()
+	state := server.NewState(ctx)
+	pattern := filepath.Join(dir, "*.md")
+	if _, err := state.AddPattern(pattern, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := state.AddFile(other, "a"); err != nil {
+		t.Fatal(err)
+	}
+	files := state.Groups()[0].Files
+	state.ReorderFiles("a", []string{files[3].ID, files[2].ID, files[1].ID, files[0].ID})
+	ts := httptest.NewServer(server.NewHandler(state))
+	defer ts.Close()
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	groupNames := func(name string) string {
+		var names []string
+		for _, g := range state.Groups() {
+			if g.Name == name {
+				for _, f := range g.Files {
+					names = append(names, f.Name)
+				}
+			}
+		}
+		return strings.Join(names, ",")
+	}
+
+	if err := doTransfer(addr, []string{other}, "a", "c", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := groupNames("c"); got != "other.md" {
+		t.Errorf("got %s in c after copy", got)
+	}
+
+	if err := doTransfer(addr, []string{"*.md"}, "a", "b", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := groupNames("b"); got != "c.md,b.md,a.md" {
+		t.Errorf("got %s in b, want the pattern's files in their order", got)
+	}
+	if got := groupNames("a"); got != "other.md" {
+		t.Errorf("got %s left in a", got)
+	}
+	if ps := state.Patterns(); len(ps) != 1 || ps[0].Group != "b" {
+		t.Errorf("pattern not moved: %+v", ps)
+	}
+}
+
+func TestSplitLocations(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeTestFile(t, filepath.Join(dir, "README.md"), []byte("# Readme"))
+	writeTestFile(t, filepath.Join(dir, "spec.md"), []byte("# Spec"))
+	writeTestFile(t, filepath.Join(dir, "notes#1.md"), []byte("# Notes"))
+
+	tests := []struct {
+		arg     string
+		want    string
+		loc     server.Location
+		located bool
+		wantErr bool
+	}{
+		{arg: "README.md#installation", want: "README.md", loc: server.Location{Anchor: "installation"}, located: true},
+		{arg: "spec.md:120", want: "spec.md", loc: server.Location{Line: 120}, located: true},
+		{arg: "spec.md#L42", want: "spec.md", loc: server.Location{Line: 42}, located: true},
+		{arg: "notes#1.md", want: "notes#1.md"},
+		{arg: "README.md", want: "README.md"},
+		{arg: "missing.md#intro", want: "missing.md#intro"},
+		{arg: "*.md", want: "*.md"},
+		{arg: "spec.md:0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			rest, locs, err := splitLocations([]string{tt.arg})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rest) != 1 || rest[0] != tt.want {
+				t.Errorf("got %v, want %q", rest, tt.want)
+			}
+			loc, ok := locs[filepath.Join(dir, tt.want)]
+			if ok != tt.located || loc != tt.loc {
+				t.Errorf("got %+v (%v), want %+v (%v)", loc, ok, tt.loc, tt.located)
+			}
+		})
+	}
+}
+
+func TestLocateDeeplinks(t *testing.T) {
+	orig := locations
+	t.Cleanup(func() { locations = orig })
+	locations = map[string]server.Location{
+		"/docs/spec.md":   {Line: 120},
+		"/docs/README.md": {Anchor: "installation"},
+	}
+	entries := []deeplinkEntry{
+		{URL: "http://localhost:6275/?file=a", Path: "/docs/other.md"},
+		{URL: "http://localhost:6275/?file=b", Path: "/docs/spec.md"},
+		{URL: "http://localhost:6275/?file=c", Path: "/docs/README.md"},
+		{URL: "http://localhost:6275/?file=d", Name: "stdin.md"},
+	}
+	located := locateDeeplinks(entries)
+	if located != &entries[1] {
+		t.Fatalf("got %+v, want the spec.md entry", located)
+	}
+	want := []string{
+		"http://localhost:6275/?file=a",
+		"http://localhost:6275/?file=b#L120",
+		"http://localhost:6275/?file=c#installation",
+		"http://localhost:6275/?file=d",
+	}
+	for i, e := range entries {
+		if e.URL != want[i] {
+			t.Errorf("entries[%d].URL = %q, want %q", i, e.URL, want[i])
+		}
+	}
+}
+
+func TestPostNavigate(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "README.md")
+	writeTestFile(t, p, []byte("# Readme\n\n## Installation\n"))
+	orig := locations
+	t.Cleanup(func() { locations = orig })
+	locations = map[string]server.Location{p: {Anchor: "installation"}}
+
+	ctx, cancel := donegroup.WithCancel(context.Background())
+	defer cancel()
+	state := server.NewState(ctx)
+	entry, err := state.AddFile(p, server.DefaultGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server.NewHandler(state))
+	defer ts.Close()
+	addr := strings.TrimPrefix(ts.URL, "http://")
+	e := &deeplinkEntry{Path: p, Group: server.DefaultGroup, ID: entry.ID}
+
+	delivered, err := postNavigate(ts.Client(), addr, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivered {
+		t.Error("delivered without a connected browser")
+	}
+
+	e.ID = "missing"
+	if _, err := postNavigate(ts.Client(), addr, e); err == nil {
+		t.Error("want an error for an unknown file")
+	}
+}
+
+func TestSessions(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	release := filepath.Join(dir, "release.md")
+	welcome := filepath.Join(dir, "welcome.md")
+	writeTestFile(t, release, []byte("# Release"))
+	writeTestFile(t, welcome, []byte("# Welcome"))
+
+	ctx, cancel := donegroup.WithCancel(context.Background())
+	defer cancel()
+	state := server.NewState(ctx)
+	if _, err := state.AddFile(release, "notes"); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server.NewHandler(state))
+	defer ts.Close()
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	openPaths := func() string {
+		var paths []string
+		for _, g := range state.Groups() {
+			for _, f := range g.Files {
+				paths = append(paths, g.Name+":"+filepath.Base(f.Path))
+			}
+		}
+		slices.Sort(paths)
+		return strings.Join(paths, ",")
+	}
+
+	if err := doSaveSession(addr, "release-notes"); err != nil {
+		t.Fatal(err)
+	}
+	state.Clear()
+	if _, err := state.AddFile(welcome, "onboarding"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doSaveSession(addr, "onboarding"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := doLoadSession(t.Context(), addr, "release-notes", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := openPaths(); got != "notes:release.md" {
+		t.Errorf("after replacing: got %s", got)
+	}
+	if err := doLoadSession(t.Context(), addr, "onboarding", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := openPaths(); got != "notes:release.md,onboarding:welcome.md" {
+		t.Errorf("after merging: got %s", got)
+	}
+
+	if err := doLoadSession(t.Context(), addr, "missing", false); err == nil {
+		t.Error("want an error for a missing session")
+	}
+	if err := doDeleteSession("onboarding"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doDeleteSession("onboarding"); err == nil {
+		t.Error("want an error for a deleted session")
+	}
+}
+
+func TestMergeRestoreData(t *testing.T) {
+	base := server.RestoreData{
+		Groups:         map[string][]string{"docs": {"/a.md"}},
+		Patterns:       map[string][]string{"docs": {"/docs/*.md"}},
+		PatternIgnores: map[string]map[string][]string{"docs": {"/docs/*.md": {"/docs/draft.md"}}},
+		UploadedFiles:  []server.UploadedFileData{{Name: "stdin.md", Group: "docs"}},
+	}
+	additional := server.RestoreData{
+		Groups:         map[string][]string{"docs": {"/a.md", "/b.md"}, "notes": {"/c.md"}},
+		PatternIgnores: map[string]map[string][]string{"docs": {"/docs/*.md": {"/docs/other.md"}}},
+		UploadedFiles:  []server.UploadedFileData{{Name: "notes.md", Group: "notes"}},
+	}
+	merged := mergeRestoreData(base, additional)
+	if got := strings.Join(merged.Groups["docs"], ","); got != "/a.md,/b.md" {
+		t.Errorf("docs: got %s", got)
+	}
+	if got := strings.Join(merged.Groups["notes"], ","); got != "/c.md" {
+		t.Errorf("notes: got %s", got)
+	}
+	if got := merged.PatternIgnores["docs"]["/docs/*.md"]; len(got) != 1 || got[0] != "/docs/draft.md" {
+		t.Errorf("pattern ignores: got %v", got)
+	}
+	if len(merged.UploadedFiles) != 2 {
+		t.Errorf("got %d uploaded files, want 2", len(merged.UploadedFiles))
+	}
+	if groups, items := sessionSize(merged); groups != 2 || items != 6 {
+		t.Errorf("sessionSize = %d, %d; want 2, 6", groups, items)
+	}
+}
+
+func TestFollowStream(t *testing.T) {
+	ctx, cancel := donegroup.WithCancel(context.Background())
+	defer cancel()
+	state := server.NewState(ctx)
+	ts := httptest.NewServer(server.NewHandler(state))
+	defer ts.Close()
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	content := func(entry deeplinkEntry) string {
+		t.Helper()
+		resp, err := ts.Client().Get(fmt.Sprintf("%s/_/api/groups/%s/files/%s/content", ts.URL, entry.Group, entry.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var fc struct {
+			Content string `json:"content"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&fc); err != nil {
+			t.Fatal(err)
+		}
+		return fc.Content
+	}
+
+	t.Run("until EOF", func(t *testing.T) {
+		entry, err := postStream(ts.Client(), addr, "logs", "stdin.md", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := strings.NewReader("# Build\nstep 1\nstep 2\n")
+		if err := followStream(context.Background(), ts.Client(), addr, entry, r); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := content(entry), "step 1\nstep 2\n"+server.StreamEndedMarker; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		entry, err := postStream(ts.Client(), addr, "logs", "stdin.md", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pr, pw := io.Pipe()
+		defer pw.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		errc := make(chan error, 1)
+		go func() { errc <- followStream(ctx, ts.Client(), addr, entry, pr) }()
+		if _, err := pw.Write([]byte("line 1\npartial")); err != nil {
+			t.Fatal(err)
+		}
+		// Complete lines are sent before the input ends.
+		deadline := time.Now().Add(5 * time.Second)
+		for content(entry) != "line 1\n" {
+			if time.Now().After(deadline) {
+				t.Fatalf("got %q before the input ended", content(entry))
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		cancel()
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+		if got, want := content(entry), "line 1\npartial"+server.StreamEndedMarker; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+		if err := postAppend(ts.Client(), addr, entry, []byte("late\n"), false); err == nil {
+			t.Error("want an error after the stream ended")
+		}
+	})
+}
+
+func TestPostNamedUpload(t *testing.T) {
+	ctx, cancel := donegroup.WithCancel(context.Background())
+	defer cancel()
+	state := server.NewState(ctx)
+	ts := httptest.NewServer(server.NewHandler(state))
+	defer ts.Close()
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	var urls []string
+	for _, content := range []string{"# Summary\n\nrun 1\n", "# Summary\n\nrun 2\n"} {
+		entry, err := postUploadedFile(ts.Client(), addr, "ci", server.UploadedFileData{Name: "report.md", Content: content, Named: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls = append(urls, entry.URL)
+	}
+	if urls[0] != urls[1] {
+		t.Errorf("got deeplinks %v, want the same entry", urls)
+	}
+	groups := state.Groups()
+	if len(groups) != 1 || len(groups[0].Files) != 1 {
+		t.Fatalf("got %+v, want one file", groups)
+	}
+	if snap := state.Snapshot(); len(snap.UploadedFiles) != 1 || snap.UploadedFiles[0].Content != "# Summary\n\nrun 2\n" {
+		t.Errorf("got %+v", snap.UploadedFiles)
+	}
+}
+
+func TestValidateUploadName(t *testing.T) {
+	for _, name := range []string{"report.md", "summary.csv", "build log.md"} {
+		if err := validateUploadName(name); err != nil {
+			t.Errorf("validateUploadName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", " ", "ci/report.md", `ci\report.md`} {
+		if err := validateUploadName(name); err == nil {
+			t.Errorf("validateUploadName(%q) succeeded, want an error", name)
+		}
+	}
+}
+
+func TestPortValue(t *testing.T) {
+	var p int
+	v := newPortValue(6275, &p)
+	if v.String() != "6275" {
+		t.Errorf("got %q, want 6275", v.String())
+	}
+	for _, tt := range []struct {
+		in   string
+		want int
+	}{
+		{"6276", 6276},
+		{"auto", autoPort},
+		{"AUTO", autoPort},
+	} {
+		if err := v.Set(tt.in); err != nil {
+			t.Errorf("Set(%q) returned %v", tt.in, err)
+		}
+		if p != tt.want {
+			t.Errorf("Set(%q): got %d, want %d", tt.in, p, tt.want)
+		}
+	}
+	if v.String() != "auto" {
+		t.Errorf("got %q, want auto", v.String())
+	}
+	for _, in := range []string{"", "-1", "65536", "free"} {
+		if err := v.Set(in); err == nil {
+			t.Errorf("Set(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestDoStatus(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	ctx, cancel := donegroup.WithCancel(context.Background())
+	defer cancel()
+	state := server.NewState(ctx)
+	ts := httptest.NewServer(server.NewHandler(state))
+	defer ts.Close()
+	_, rawPort, err := net.SplitHostPort(strings.TrimPrefix(ts.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	running, err := strconv.Atoi(rawPort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register(registry.Entry{PID: os.Getpid(), Port: running, Bind: "127.0.0.1", StartedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	// A server that died without unregistering.
+	dead := exec.Command("true")
+	if err := dead.Run(); err != nil {
+		t.Skip("cannot run true:", err)
+	}
+	if err := registry.Register(registry.Entry{PID: dead.Process.Pid, Port: freePort(t), Bind: "localhost", StartedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	jsonOutput = true
+	defer func() { jsonOutput = false }()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldStdout := os.Stdout
+	os.Stdout = w
+	statusErr := doStatus()
+	w.Close()
+	os.Stdout = oldStdout
+	if statusErr != nil {
+		t.Fatal(statusErr)
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r) //nolint:errcheck
+	var entries []jsonStatusEntry
+	if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+		t.Fatalf("invalid JSON: %v\noutput: %s", err, buf.String())
+	}
+	if len(entries) != 1 || entries[0].Status != "running" || entries[0].URL != ts.URL {
+		t.Errorf("got %+v, want only the running server", entries)
+	}
+}
+
+func TestPruneBackups(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	origPort := port
+	defer func() { port = origPort }()
+	port = 6275
+
+	past := time.Now().Add(-48 * time.Hour)
+	for _, p := range []int{6275, 6276, 6277} {
+		if err := backup.Save(p, server.RestoreData{}); err != nil {
+			t.Fatal(err)
+		}
+		path, err := backup.Path(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An idle server that is still running.
+	if err := registry.Register(registry.Entry{PID: os.Getpid(), Port: 6276, StartedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	pruneBackups(24 * time.Hour)
+	if !backup.Exists(6275) || !backup.Exists(6276) || backup.Exists(6277) {
+		t.Errorf("want only the backup of port 6277 removed")
+	}
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/k1LoW/mo/internal/backup"
+	"github.com/k1LoW/mo/internal/server"
+	"github.com/k1LoW/mo/internal/session"
+)
+
+// jsonSessionEntry is a saved session in the JSON output of --list-sessions.
+type jsonSessionEntry struct {
+	Name    string    `json:"name"`
+	Groups  int       `json:"groups"`
+	Items   int       `json:"items"`
+	SavedAt time.Time `json:"savedAt"`
+}
+
+// doSaveSession saves the state of the mo server at addr as the session name.
+func doSaveSession(addr, name string) error {
+	if err := session.ValidateName(name); err != nil {
+		return err
+	}
+	result, err := probeServer(addr)
+	if err != nil {
+		return err
+	}
+	resp, err := result.client.Get(fmt.Sprintf("http://%s/_/api/session", addr))
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get session: %s", responseError(resp))
+	}
+	var rd server.RestoreData
+	if err := json.NewDecoder(resp.Body).Decode(&rd); err != nil {
+		return fmt.Errorf("failed to decode session: %w", err)
+	}
+	if err := session.Save(name, rd); err != nil {
+		return err
+	}
+	groups, items := sessionSize(rd)
+	fmt.Fprintf(os.Stderr, "mo: saved session %q (%d group(s), %d item(s))\n", name, groups, items)
+	return nil
+}
+
+// doLoadSession opens the session name on the mo server at addr, replacing
+// what it shows unless merge is set. Without a running server, a new one
+// starts with the session, merged with the port's backup if merge is set.
+func doLoadSession(ctx context.Context, addr, name string, merge bool) error {
+	var rd server.RestoreData
+	if err := session.Load(name, &rd); err != nil {
+		return err
+	}
+
+	if _, err := probeServer(addr, probeTimeoutFast); err == nil {
+		body, err := json.Marshal(map[string]any{"data": rd, "merge": merge})
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("http://%s/_/api/session", addr), bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		// Loading may read many files and archives.
+		client := &http.Client{Timeout: 30 * time.Second}
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to load session: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("failed to load session: %s", responseError(resp))
+		}
+		var loaded server.LoadSessionResponse
+		if err := json.NewDecoder(resp.Body).Decode(&loaded); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "mo: loaded session %q into http://%s (%d item(s))\n", name, addr, loaded.Added)
+		if open {
+			openBrowser(addr)
+		}
+		return nil
+	}
+
+	if !isLoopbackBind(bind) && !dangerouslyAllowRemoteAccess {
+		return fmt.Errorf("cannot start a server for --load-session with non-loopback bind without --dangerously-allow-remote-access")
+	}
+	if merge {
+		var prev server.RestoreData
+		if err := backup.Load(port, &prev); err != nil {
+			slog.Warn("failed to load backup", "error", err)
+		}
+		rd = mergeRestoreData(prev, rd)
+	}
+	fmt.Fprintf(os.Stderr, "mo: starting server with session %q\n", name)
+	if foreground {
+		return startServer(ctx, addr, rd)
+	}
+	return startBackground(addr, rd)
+}
+
+// mergeRestoreData returns base with the groups, patterns, uploaded files,
+// reviews and archives of additional added after its own.
+func mergeRestoreData(base, additional server.RestoreData) server.RestoreData {
+	merged := server.RestoreData{
+		Groups:        mergeGroups(base.Groups, additional.Groups),
+		Patterns:      mergeGroups(base.Patterns, additional.Patterns),
+		UploadedFiles: append(slices.Clone(base.UploadedFiles), additional.UploadedFiles...),
+		Reviews:       append(slices.Clone(base.Reviews), additional.Reviews...),
+		Archives:      append(slices.Clone(base.Archives), additional.Archives...),
+	}
+	for _, ignores := range []map[string]map[string][]string{base.PatternIgnores, additional.PatternIgnores} {
+		for group, byPattern := range ignores {
+			for pat, ignore := range byPattern {
+				if merged.PatternIgnores == nil {
+					merged.PatternIgnores = make(map[string]map[string][]string)
+				}
+				if merged.PatternIgnores[group] == nil {
+					merged.PatternIgnores[group] = make(map[string][]string)
+				}
+				if _, ok := merged.PatternIgnores[group][pat]; !ok {
+					merged.PatternIgnores[group][pat] = ignore
+				}
+			}
+		}
+	}
+	return merged
+}
+
+// doListSessions prints the saved sessions.
+func doListSessions() error {
+	infos, err := session.List()
+	if err != nil {
+		return err
+	}
+	entries := make([]jsonSessionEntry, 0, len(infos))
+	for _, info := range infos {
+		var rd server.RestoreData
+		if err := session.Load(info.Name, &rd); err != nil {
+			slog.Warn("failed to read session", "name", info.Name, "error", err)
+			continue
+		}
+		groups, items := sessionSize(rd)
+		entries = append(entries, jsonSessionEntry{
+			Name:    info.Name,
+			Groups:  groups,
+			Items:   items,
+			SavedAt: info.ModTime,
+		})
+	}
+	if jsonOutput {
+		writeJSON(entries)
+		return nil
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, "mo: no saved sessions")
+		return nil
+	}
+	printSessions(os.Stdout, entries)
+	return nil
+}
+
+func printSessions(w io.Writer, entries []jsonSessionEntry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tGROUPS\tITEMS\tSAVED")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", e.Name, e.Groups, e.Items, e.SavedAt.Local().Format(time.DateTime))
+	}
+	tw.Flush() //nolint:errcheck
+}
+
+// doDeleteSession deletes the saved session name.
+func doDeleteSession(name string) error {
+	if err := session.Remove(name); err != nil {
+		if errors.Is(err, session.ErrNotFound) {
+			return fmt.Errorf("no saved session %q (use --list-sessions to see saved sessions)", name)
+		}
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "mo: deleted session %q\n", name)
+	return nil
+}
+
+// sessionSize returns the number of groups in rd and the number of items
+// (files, watch patterns, uploaded files, reviews and archives) it opens.
+func sessionSize(rd server.RestoreData) (int, int) {
+	groups := make(map[string]struct{})
+	items := 0
+	for g, files := range rd.Groups {
+		if len(files) > 0 {
+			groups[g] = struct{}{}
+		}
+		items += len(files)
+	}
+	for g, pats := range rd.Patterns {
+		groups[g] = struct{}{}
+		items += len(pats)
+	}
+	for _, uf := range rd.UploadedFiles {
+		groups[uf.Group] = struct{}{}
+	}
+	for _, rv := range rd.Reviews {
+		groups[rv.Group] = struct{}{}
+	}
+	for _, ad := range rd.Archives {
+		groups[ad.Group] = struct{}{}
+	}
+	items += len(rd.UploadedFiles) + len(rd.Reviews) + len(rd.Archives)
+	return len(groups), items
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
@@ -41,6 +42,17 @@ func readStdin(r io.Reader) (name string, content string, err error) {
 	return stdinName(c), c, nil
 }
 
+// validateUploadName reports whether name can name stdin content with --name.
+func validateUploadName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("--name must not be empty")
+	}
+	if strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("--name must be a file name, not a path: %s", name)
+	}
+	return nil
+}
+
 // stdinName generates a deterministic name for stdin content
 // in the format "stdin-<first 7 hex chars of SHA-256>.md".
 func stdinName(content string) string {
@@ -49,16 +61,43 @@ func stdinName(content string) string {
 }
 
 type uploadRequest struct {
-	Name    string `json:"name"`
-	Content string `json:"content"`
+	Name    string            `json:"name"`
+	Content string            `json:"content"`
+	Git     *server.GitSource `json:"git,omitempty"`
+	Named   bool              `json:"named,omitempty"`
 }
 
-// postUploadedFile uploads in-memory content to a running mo server.
-func postUploadedFile(client *http.Client, addr, group, name, content string) (deeplinkEntry, error) {
-	body, err := json.Marshal(uploadRequest{
-		Name:    name,
-		Content: content,
+// postUploadedFile uploads in-memory content to a running mo server. A named
+// upload replaces the content of the entry uploaded under the same name.
+func postUploadedFile(client *http.Client, addr, group string, uf server.UploadedFileData) (deeplinkEntry, error) {
+	return postUpload(client, addr, group, uploadRequest{
+		Name:    uf.Name,
+		Content: uf.Content,
+		Named:   uf.Named,
 	})
+}
+
+// postRevisionFiles uploads files read from a git revision to a running mo
+// server and returns the deeplinks of the accepted ones.
+func postRevisionFiles(client *http.Client, addr, group string, files []server.UploadedFileData) []deeplinkEntry {
+	var entries []deeplinkEntry
+	for _, f := range files {
+		entry, err := postUpload(client, addr, group, uploadRequest{
+			Name:    f.Name,
+			Content: f.Content,
+			Git:     f.Git,
+		})
+		if err != nil {
+			slog.Warn("failed to add revision file", "name", f.Name, "error", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func postUpload(client *http.Client, addr, group string, req uploadRequest) (deeplinkEntry, error) {
+	body, err := json.Marshal(req)
 	if err != nil {
 		return deeplinkEntry{}, err
 	}
@@ -0,0 +1,253 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// MaxFileSize is the size above which files in an archive are not read.
+const MaxFileSize = 10 << 20
+
+var (
+	// ErrNotFound is returned by ReadFile when the archive has no such file.
+	ErrNotFound = errors.New("file not found in archive")
+	// ErrTooLarge is returned by ReadFile for files over MaxFileSize.
+	ErrTooLarge = errors.New("file in archive is too large")
+)
+
+// File is a regular file read from an archive.
+type File struct {
+	Name string // slash-separated path inside the archive
+	Data []byte
+}
+
+// IsArchive reports whether name has the extension of a supported archive:
+// .zip, .tar, .tar.gz or .tgz.
+func IsArchive(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadFiles reads the regular files of the archive at p whose names match,
+// ordered by name with numbers compared numerically. Files over MaxFileSize
+// are skipped.
+func ReadFiles(p string, match func(name string) bool) ([]File, error) {
+	// A name added to a tar twice takes the later content, as on extraction.
+	byName := make(map[string][]byte)
+	err := walk(p, func(name string, size int64, open func() (io.ReadCloser, error)) error {
+		if !match(name) || size > MaxFileSize {
+			return nil
+		}
+		data, err := readAll(open)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		byName[name] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	collate.New(language.Und, collate.Numeric).SortStrings(names)
+	files := make([]File, len(names))
+	for i, name := range names {
+		files[i] = File{Name: name, Data: byName[name]}
+	}
+	return files, nil
+}
+
+// ReadFile reads the file with the slash-separated name from the archive at
+// p.
+func ReadFile(p, name string) ([]byte, error) {
+	var data []byte
+	found := false
+	err := walk(p, func(n string, size int64, open func() (io.ReadCloser, error)) error {
+		if n != name {
+			return nil
+		}
+		if size > MaxFileSize {
+			return ErrTooLarge
+		}
+		var err error
+		data, err = readAll(open)
+		found = true
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+// Index holds the regular files of an archive read in one pass, so that many
+// of them can be served without scanning the archive again. Files past the
+// memory budget given to NewIndex are only listed and read on demand.
+type Index struct {
+	path   string
+	files  map[string][]byte // nil for files that were not kept
+	tooBig map[string]struct{}
+}
+
+// NewIndex reads the archive at p, keeping the content of its files until
+// they add up to budget bytes.
+func NewIndex(p string, budget int64) (*Index, error) {
+	ix := &Index{
+		path:   p,
+		files:  make(map[string][]byte),
+		tooBig: make(map[string]struct{}),
+	}
+	err := walk(p, func(name string, size int64, open func() (io.ReadCloser, error)) error {
+		// A name added to a tar twice takes the later content.
+		if size > MaxFileSize {
+			delete(ix.files, name)
+			ix.tooBig[name] = struct{}{}
+			return nil
+		}
+		delete(ix.tooBig, name)
+		if size > budget {
+			ix.files[name] = nil
+			return nil
+		}
+		data, err := readAll(open)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		budget -= int64(len(data))
+		ix.files[name] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// ReadFile reads the file with the slash-separated name, from memory when
+// the index kept it.
+func (ix *Index) ReadFile(name string) ([]byte, error) {
+	if _, ok := ix.tooBig[name]; ok {
+		return nil, ErrTooLarge
+	}
+	data, ok := ix.files[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if data == nil {
+		return ReadFile(ix.path, name)
+	}
+	return data, nil
+}
+
+func readAll(open func() (io.ReadCloser, error)) ([]byte, error) {
+	rc, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, MaxFileSize))
+}
+
+// cleanName normalizes an entry name, rejecting names that would point
+// outside the archive.
+func cleanName(name string) (string, bool) {
+	name = path.Clean(strings.TrimPrefix(strings.ReplaceAll(name, "\\", "/"), "./"))
+	if name == "." || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	return name, true
+}
+
+type walkFunc func(name string, size int64, open func() (io.ReadCloser, error)) error
+
+// walk calls fn for every regular file of the archive at p.
+func walk(p string, fn walkFunc) error {
+	if strings.HasSuffix(strings.ToLower(p), ".zip") {
+		return walkZip(p, fn)
+	}
+	return walkTar(p, fn)
+}
+
+func walkZip(p string, fn walkFunc) error {
+	r, err := zip.OpenReader(p)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		name, ok := cleanName(f.Name)
+		if !ok {
+			continue
+		}
+		open := func() (io.ReadCloser, error) { return f.Open() }
+		if err := fn(name, int64(f.UncompressedSize64), open); err != nil { //nolint:gosec // G115: sizes beyond int64 are rejected by MaxFileSize anyway
+			return err
+		}
+	}
+	return nil
+}
+
+func walkTar(p string, fn walkFunc) error {
+	f, err := os.Open(p) //nolint:gosec // Path is server-managed, not user-supplied
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	lower := strings.ToLower(p)
+	if strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name, ok := cleanName(hdr.Name)
+		if !ok {
+			continue
+		}
+		open := func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }
+		if err := fn(name, hdr.Size, open); err != nil {
+			return err
+		}
+	}
+}
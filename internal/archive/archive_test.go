@@ -0,0 +1,149 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testFiles = []File{
+	{Name: "docs/guide10.md", Data: []byte("# Guide 10")},
+	{Name: "README.md", Data: []byte("# Readme")},
+	{Name: "docs/guide2.md", Data: []byte("# Guide 2")},
+	{Name: "docs/img/logo.png", Data: []byte("\x89PNG")},
+	{Name: "../escape.md", Data: []byte("# Escape")},
+}
+
+func writeZip(t *testing.T, p string) {
+	t.Helper()
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for _, tf := range testFiles {
+		fw, err := w.Create(tf.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(tf.Data) //nolint:errcheck
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTar(t *testing.T, p string, gz bool) {
+	t.Helper()
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var w io.Writer = f
+	if gz {
+		zw := gzip.NewWriter(f)
+		defer zw.Close()
+		w = zw
+	}
+	tw := tar.NewWriter(w)
+	tw.WriteHeader(&tar.Header{Name: "./docs/", Typeflag: tar.TypeDir, Mode: 0o755}) //nolint:errcheck
+	for _, tf := range testFiles {
+		if err := tw.WriteHeader(&tar.Header{Name: "./" + tf.Name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(tf.Data))}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(tf.Data) //nolint:errcheck
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadFiles(t *testing.T) {
+	dir := t.TempDir()
+	archives := map[string]func(string){
+		"bundle.zip":    func(p string) { writeZip(t, p) },
+		"bundle.tar":    func(p string) { writeTar(t, p, false) },
+		"bundle.tar.gz": func(p string) { writeTar(t, p, true) },
+		"bundle.tgz":    func(p string) { writeTar(t, p, true) },
+	}
+	for name, write := range archives {
+		t.Run(name, func(t *testing.T) {
+			p := filepath.Join(dir, name)
+			write(p)
+			if !IsArchive(p) {
+				t.Fatalf("%s is not recognized as an archive", name)
+			}
+
+			files, err := ReadFiles(p, func(n string) bool { return strings.HasSuffix(n, ".md") })
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, f := range files {
+				names = append(names, f.Name)
+			}
+			want := []string{"docs/guide2.md", "docs/guide10.md", "README.md"}
+			if !slices.Equal(names, want) {
+				t.Errorf("got %v, want %v", names, want)
+			}
+			if string(files[2].Data) != "# Readme" {
+				t.Errorf("got %q", files[2].Data)
+			}
+
+			data, err := ReadFile(p, "docs/img/logo.png")
+			if err != nil || string(data) != "\x89PNG" {
+				t.Errorf("ReadFile = %q, %v", data, err)
+			}
+			if _, err := ReadFile(p, "missing.png"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("got %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestIndex(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	writeTar(t, p, true)
+
+	// Room for the first file only; the others are read on demand.
+	ix, err := NewIndex(p, int64(len(testFiles[0].Data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tf := range testFiles[:4] {
+		data, err := ix.ReadFile(tf.Name)
+		if err != nil || string(data) != string(tf.Data) {
+			t.Errorf("%s: got %q, %v", tf.Name, data, err)
+		}
+	}
+	if ix.files[testFiles[0].Name] == nil || ix.files[testFiles[1].Name] != nil {
+		t.Error("the budget was not applied")
+	}
+	if _, err := ix.ReadFile("missing.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	for name, want := range map[string]bool{
+		"docs.zip":       true,
+		"release.TAR.GZ": true,
+		"release.tgz":    true,
+		"docs.tar":       true,
+		"README.md":      false,
+		"notes.gz":       false,
+	} {
+		if got := IsArchive(name); got != want {
+			t.Errorf("IsArchive(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
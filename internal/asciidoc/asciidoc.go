@@ -0,0 +1,565 @@
+package asciidoc
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Convert converts the commonly used subset of AsciiDoc to Markdown:
+// section titles, paragraphs with inline formatting, ordered, unordered,
+// check and description lists, listing/literal/quote/example/sidebar
+// blocks, admonitions, tables, images, links and cross references.
+// Attribute entries are applied to {name} references and otherwise dropped.
+// Unsupported syntax is kept as text.
+func Convert(data []byte) string {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	c := &converter{attrs: map[string]string{}}
+	return strings.TrimRight(c.convert(strings.Split(text, "\n")), "\n") + "\n"
+}
+
+type converter struct {
+	attrs     map[string]string
+	seenTitle bool
+}
+
+// blockAttrs are the attributes given in brackets above a block.
+type blockAttrs struct {
+	style   string   // first positional attribute, e.g. "source", "NOTE", "quote"
+	args    []string // remaining positional attributes
+	options string   // options and named attributes, e.g. `options="header"`
+	title   string
+}
+
+var (
+	sectionTitle   = regexp.MustCompile(`^(={1,6})\s+(.+?)\s*$`)
+	attributeEntry = regexp.MustCompile(`^:(!?[\w-]+!?):\s*(.*)$`)
+	blockAttr      = regexp.MustCompile(`^\[([^\[\]]*)\]\s*$`)
+	anchor         = regexp.MustCompile(`^\[\[[^\]]*\]\]\s*$`)
+	blockTitle     = regexp.MustCompile(`^\.([^.\s].*)$`)
+	blockImage     = regexp.MustCompile(`^image::([^\[]+)\[([^\]]*)\]\s*$`)
+	admonitionPara = regexp.MustCompile(`^(NOTE|TIP|IMPORTANT|WARNING|CAUTION):\s+(.*)$`)
+	listItem       = regexp.MustCompile(`^\s*(\*{1,5}|-|\.{1,5}|\d+\.)\s+(.*)$`)
+	descItem       = regexp.MustCompile(`^(.+?)(:{2,4}|;;)(?:\s+(.*))?$`)
+	checkbox       = regexp.MustCompile(`^\[([ xX*])\]\s+`)
+	colsAttr       = regexp.MustCompile(`cols="?([^"]+)"?`)
+)
+
+// delimiters maps block delimiter lines to their block kind.
+var delimiters = map[string]string{
+	"----": "listing",
+	"....": "literal",
+	"____": "quote",
+	"====": "example",
+	"****": "sidebar",
+	"++++": "pass",
+	"--":   "open",
+	"|===": "table",
+	"////": "comment",
+}
+
+func (c *converter) convert(lines []string) string {
+	var out []string
+	var attrs blockAttrs
+	emit := func(block string) {
+		if attrs.title != "" {
+			out = append(out, "**"+c.inline(attrs.title)+"**")
+		}
+		out = append(out, block)
+		attrs = blockAttrs{}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			continue
+		case strings.HasPrefix(line, "//") && !strings.HasPrefix(line, "////"):
+			continue
+		case attributeEntry.MatchString(line):
+			m := attributeEntry.FindStringSubmatch(line)
+			c.attrs[m[1]] = m[2]
+			continue
+		case anchor.MatchString(line):
+			continue
+		case blockAttr.MatchString(line):
+			attrs = parseBlockAttrs(blockAttr.FindStringSubmatch(line)[1], attrs.title)
+			continue
+		case blockTitle.MatchString(line) && !listItem.MatchString(line):
+			attrs.title = blockTitle.FindStringSubmatch(line)[1]
+			continue
+		case line == "'''" || line == "---" || line == "***":
+			emit("---")
+			continue
+		case line == "<<<":
+			continue
+		}
+
+		if kind, ok := delimiters[trimmed]; ok {
+			end := i + 1
+			for end < len(lines) && strings.TrimSpace(lines[end]) != trimmed {
+				end++
+			}
+			inner := lines[i+1 : min(end, len(lines))]
+			i = end
+			if block := c.delimited(kind, inner, attrs); block != "" {
+				emit(block)
+			} else {
+				attrs = blockAttrs{}
+			}
+			continue
+		}
+
+		if m := sectionTitle.FindStringSubmatch(line); m != nil {
+			emit(strings.Repeat("#", len(m[1])) + " " + c.inline(m[2]))
+			if len(m[1]) == 1 && !c.seenTitle {
+				// The document title is followed by the header: author and
+				// revision lines, and attribute entries.
+				c.seenTitle = true
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+					if m := attributeEntry.FindStringSubmatch(lines[i]); m != nil {
+						c.attrs[m[1]] = m[2]
+					}
+				}
+			}
+			continue
+		}
+		if m := blockImage.FindStringSubmatch(line); m != nil {
+			emit(fmt.Sprintf("![%s](%s)", firstAttr(m[2]), c.subst(m[1])))
+			continue
+		}
+		if isListLine(line) {
+			end := i
+			for end < len(lines) {
+				l := lines[end]
+				if strings.TrimSpace(l) == "" {
+					// Blank lines between items don't end the list.
+					next := end + 1
+					for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+						next++
+					}
+					if next == len(lines) || !isListLine(lines[next]) {
+						break
+					}
+					end = next
+					continue
+				}
+				if !isListLine(l) && l != "+" && (end == i || startsBlock(l)) {
+					break
+				}
+				end++
+			}
+			emit(c.list(lines[i:end]))
+			i = end - 1
+			continue
+		}
+
+		// Paragraph: runs until a blank line or the start of another block.
+		end := i + 1
+		for end < len(lines) && strings.TrimSpace(lines[end]) != "" && !startsBlock(lines[end]) {
+			end++
+		}
+		para := lines[i:end]
+		i = end - 1
+		if m := admonitionPara.FindStringSubmatch(para[0]); m != nil {
+			para[0] = m[2]
+			emit(alert(m[1], c.paragraph(para)))
+			continue
+		}
+		if isAdmonition(attrs.style) {
+			emit(alert(attrs.style, c.paragraph(para)))
+			continue
+		}
+		switch attrs.style {
+		case "source", "listing":
+			emit(fenced(sourceLang(attrs), strings.Join(para, "\n")))
+		case "literal":
+			emit(fenced("text", strings.Join(para, "\n")))
+		case "quote", "verse":
+			emit(quote(c.paragraph(para)))
+		default:
+			if strings.HasPrefix(para[0], " ") {
+				// An indented paragraph is a literal block.
+				emit(fenced("text", strings.Join(dedent(para), "\n")))
+			} else {
+				emit(c.paragraph(para))
+			}
+		}
+	}
+	return strings.Join(out, "\n\n")
+}
+
+func (c *converter) delimited(kind string, inner []string, attrs blockAttrs) string {
+	switch kind {
+	case "listing":
+		return fenced(sourceLang(attrs), strings.Join(inner, "\n"))
+	case "literal":
+		return fenced("text", strings.Join(inner, "\n"))
+	case "pass":
+		return strings.Join(inner, "\n")
+	case "comment":
+		return ""
+	case "table":
+		return c.table(inner, attrs)
+	case "quote":
+		return quote(c.convert(inner))
+	}
+	// Example, sidebar and open blocks; an admonition style turns them into
+	// an alert.
+	content := c.convert(inner)
+	if isAdmonition(attrs.style) {
+		return alert(attrs.style, content)
+	}
+	return content
+}
+
+func (c *converter) paragraph(lines []string) string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		l = strings.TrimSpace(l)
+		if strings.HasSuffix(l, " +") {
+			// Hard line break.
+			l = strings.TrimSuffix(l, " +") + "\\"
+		}
+		out[i] = c.inline(l)
+	}
+	return strings.Join(out, "\n")
+}
+
+// list converts a run of list lines, including description lists.
+// Continuation lines join the preceding item.
+func (c *converter) list(lines []string) string {
+	var out []string
+	var depths []string // marker of each open nesting level
+	for _, line := range lines {
+		if line == "+" || strings.TrimSpace(line) == "" {
+			continue
+		}
+		if m := listItem.FindStringSubmatch(line); m != nil {
+			marker := m[1]
+			if isNumbered(marker) {
+				marker = "."
+			}
+			depth := slices.Index(depths, marker)
+			if depth < 0 {
+				depths = append(depths, marker)
+				depth = len(depths) - 1
+			} else {
+				depths = depths[:depth+1]
+			}
+			bullet := "-"
+			if strings.HasPrefix(marker, ".") {
+				bullet = "1."
+			}
+			text := m[2]
+			if cb := checkbox.FindStringSubmatch(text); cb != nil {
+				box := "[ ]"
+				if cb[1] != " " {
+					box = "[x]"
+				}
+				text = box + " " + text[len(cb[0]):]
+			}
+			out = append(out, strings.Repeat("   ", depth)+bullet+" "+c.inline(text))
+			continue
+		}
+		if m := descItem.FindStringSubmatch(line); m != nil && !strings.HasPrefix(line, " ") {
+			item := "- **" + c.inline(strings.TrimSpace(m[1])) + "**"
+			if m[3] != "" {
+				item += ": " + c.inline(m[3])
+			}
+			out = append(out, item)
+			depths = nil
+			continue
+		}
+		if len(out) > 0 {
+			// Continuation of the previous item's text.
+			out[len(out)-1] += " " + c.inline(strings.TrimSpace(line))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func (c *converter) table(lines []string, attrs blockAttrs) string {
+	cols := 0
+	if m := colsAttr.FindStringSubmatch(attrs.options); m != nil {
+		spec := m[1]
+		if n, err := fmt.Sscanf(spec, "%d*", &cols); n != 1 || err != nil || !strings.Contains(spec, "*") {
+			cols = len(strings.Split(spec, ","))
+		}
+	}
+	header := strings.Contains(attrs.options, "header")
+
+	var cells []string
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			// A blank line after the first row marks it as the header.
+			if i > 0 && len(cells) > 0 && cols == 0 {
+				cols = len(cells)
+				header = true
+			}
+			continue
+		}
+		if !strings.HasPrefix(line, "|") {
+			if len(cells) > 0 {
+				cells[len(cells)-1] += " " + line
+			}
+			continue
+		}
+		rowCells := strings.Split(line[1:], "|")
+		for _, cell := range rowCells {
+			cells = append(cells, strings.TrimSpace(cell))
+		}
+		if cols == 0 && i == 0 {
+			cols = len(rowCells)
+		}
+	}
+	if cols == 0 || len(cells) == 0 {
+		return ""
+	}
+
+	var rows [][]string
+	for start := 0; start < len(cells); start += cols {
+		row := make([]string, cols)
+		for j := range cols {
+			if start+j < len(cells) {
+				row[j] = strings.ReplaceAll(c.inline(cells[start+j]), "|", `\|`)
+			}
+		}
+		rows = append(rows, row)
+	}
+	if !header {
+		// GFM tables need a header row; use an empty one.
+		rows = append([][]string{make([]string, cols)}, rows...)
+	}
+	var sb strings.Builder
+	sb.WriteString("| " + strings.Join(rows[0], " | ") + " |\n")
+	sb.WriteString("|" + strings.Repeat(" --- |", cols) + "\n")
+	for _, r := range rows[1:] {
+		sb.WriteString("| " + strings.Join(r, " | ") + " |\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+var (
+	attrRef       = regexp.MustCompile(`\{([\w-]+)\}`)
+	inlineImage   = regexp.MustCompile(`image:([^\s\[]+)\[([^\]]*)\]`)
+	macroLink     = regexp.MustCompile(`(?:link|xref):([^\s\[]+)\[([^\]]*)\]`)
+	urlLink       = regexp.MustCompile(`((?:https?|ftp|mailto):[^\s\[]+)\[([^\]]*)\]`)
+	crossRef      = regexp.MustCompile(`<<([^,>]+)(?:,\s*([^>]+))?>>`)
+	strong        = regexp.MustCompile(`(^|[^\w*])\*([^\s*](?:[^*]*[^\s*])?)\*($|[^\w*])`)
+	emphasis      = regexp.MustCompile(`(^|[^\w_])_([^\s_](?:[^_]*[^\s_])?)_($|[^\w_])`)
+	unconstrained = regexp.MustCompile(`__([^_]+)__`)
+	highlight     = regexp.MustCompile(`(^|[^\w#])#([^\s#](?:[^#]*[^\s#])?)#($|[^\w#])`)
+	monoPlus      = regexp.MustCompile("`\\+([^`]+)\\+`")
+)
+
+// inline converts inline formatting, links and images of a line, leaving
+// code spans untouched.
+func (c *converter) inline(s string) string {
+	s = monoPlus.ReplaceAllString(s, "`$1`")
+	parts := strings.Split(s, "`")
+	for i := 0; i < len(parts); i += 2 {
+		if i == len(parts)-1 && len(parts)%2 == 0 {
+			break // unbalanced backtick
+		}
+		parts[i] = c.inlineText(parts[i])
+	}
+	return strings.Join(parts, "`")
+}
+
+func (c *converter) inlineText(s string) string {
+	s = c.subst(s)
+	s = inlineImage.ReplaceAllStringFunc(s, func(m string) string {
+		sm := inlineImage.FindStringSubmatch(m)
+		return fmt.Sprintf("![%s](%s)", firstAttr(sm[2]), sm[1])
+	})
+	s = macroLink.ReplaceAllStringFunc(s, func(m string) string {
+		sm := macroLink.FindStringSubmatch(m)
+		text := firstAttr(sm[2])
+		if text == "" {
+			text = sm[1]
+		}
+		return fmt.Sprintf("[%s](%s)", text, sm[1])
+	})
+	s = urlLink.ReplaceAllStringFunc(s, func(m string) string {
+		sm := urlLink.FindStringSubmatch(m)
+		text := firstAttr(sm[2])
+		if text == "" {
+			return sm[1]
+		}
+		return fmt.Sprintf("[%s](%s)", text, sm[1])
+	})
+	s = crossRef.ReplaceAllStringFunc(s, func(m string) string {
+		sm := crossRef.FindStringSubmatch(m)
+		target, text := strings.TrimSpace(sm[1]), strings.TrimSpace(sm[2])
+		if text == "" {
+			text = target
+		}
+		if !strings.Contains(target, "#") && !strings.HasSuffix(target, ".adoc") {
+			target = "#" + target
+		}
+		return fmt.Sprintf("[%s](%s)", text, target)
+	})
+	// Each pattern consumes the character around a match, so adjacent spans
+	// ("*a* *b*") need a second pass. Emphasis is marked with a placeholder
+	// until then so that the strong pattern doesn't match its output.
+	s = unconstrained.ReplaceAllString(s, "\x00$1\x00")
+	for range 2 {
+		s = strong.ReplaceAllString(s, "$1**$2**$3")
+		s = emphasis.ReplaceAllString(s, "$1\x00$2\x00$3")
+		s = highlight.ReplaceAllString(s, "$1<mark>$2</mark>$3")
+	}
+	return strings.ReplaceAll(s, "\x00", "*")
+}
+
+// subst replaces attribute references with their values; unknown
+// references are kept.
+func (c *converter) subst(s string) string {
+	return attrRef.ReplaceAllStringFunc(s, func(m string) string {
+		if v, ok := c.attrs[m[1:len(m)-1]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+func parseBlockAttrs(s, title string) blockAttrs {
+	a := blockAttrs{title: title}
+	for i, part := range splitAttrs(s) {
+		part = strings.TrimSpace(part)
+		switch {
+		case strings.Contains(part, "="):
+			a.options += part + " "
+		case i == 0:
+			// Shorthands like "source%linenums" or "#id.role".
+			style, opts, _ := strings.Cut(part, "%")
+			if opts != "" {
+				a.options += opts + " "
+			}
+			style, _, _ = strings.Cut(style, "#")
+			style, _, _ = strings.Cut(style, ".")
+			a.style = style
+		case strings.HasPrefix(part, "%"):
+			a.options += part[1:] + " "
+		default:
+			a.args = append(a.args, part)
+		}
+	}
+	return a
+}
+
+// splitAttrs splits an attribute list on commas outside quotes.
+func splitAttrs(s string) []string {
+	var parts []string
+	inQuote := false
+	start := 0
+	for i, r := range s {
+		switch r {
+		case '"':
+			inQuote = !inQuote
+		case ',':
+			if !inQuote {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func firstAttr(s string) string {
+	first, _, _ := strings.Cut(s, ",")
+	return strings.Trim(strings.TrimSpace(first), `"`)
+}
+
+func sourceLang(attrs blockAttrs) string {
+	if (attrs.style == "source" || attrs.style == "listing") && len(attrs.args) > 0 {
+		return attrs.args[0]
+	}
+	return ""
+}
+
+func isAdmonition(style string) bool {
+	switch style {
+	case "NOTE", "TIP", "IMPORTANT", "WARNING", "CAUTION":
+		return true
+	}
+	return false
+}
+
+func isNumbered(marker string) bool {
+	return marker != "" && marker[0] >= '0' && marker[0] <= '9'
+}
+
+func isListLine(line string) bool {
+	if listItem.MatchString(line) {
+		return true
+	}
+	m := descItem.FindStringSubmatch(line)
+	return m != nil && !strings.HasPrefix(line, " ") && !strings.Contains(m[1], "://")
+}
+
+// startsBlock reports whether line starts a new block, ending a paragraph.
+func startsBlock(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if _, ok := delimiters[trimmed]; ok {
+		return true
+	}
+	return sectionTitle.MatchString(line) || blockAttr.MatchString(line) ||
+		anchor.MatchString(line) || blockImage.MatchString(line) || listItem.MatchString(line)
+}
+
+func alert(kind, content string) string {
+	return quote("[!" + kind + "]\n" + content)
+}
+
+func quote(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, l := range lines {
+		if l == "" {
+			lines[i] = ">"
+		} else {
+			lines[i] = "> " + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fenced wraps content in a code fence longer than any backtick run inside it.
+func fenced(lang, content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + lang + "\n" + content + "\n" + fence
+}
+
+func dedent(lines []string) []string {
+	indent := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		n := len(l) - len(strings.TrimLeft(l, " "))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		if len(l) >= indent {
+			out[i] = l[indent:]
+		}
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package asciidoc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			"document title and header",
+			"= Runbook\nJane Doe <jane@example.com>\n:app: billing\n\nDeploys {app}.\n",
+			"# Runbook\n\nDeploys billing.\n",
+		},
+		{
+			"sections",
+			"== Steps\n\n=== Details\n",
+			"## Steps\n\n### Details\n",
+		},
+		{
+			"inline formatting",
+			"*bold* and _italic_, *a* *b*, `*code*` and #marked#\n",
+			"**bold** and *italic*, **a** **b**, `*code*` and <mark>marked</mark>\n",
+		},
+		{
+			"links and cross references",
+			"See https://example.com[the docs], link:other.adoc[other] and <<rollback,Rolling back>>.\n",
+			"See [the docs](https://example.com), [other](other.adoc) and [Rolling back](#rollback).\n",
+		},
+		{
+			"nested and check lists",
+			". Build\n.. tag\n. Deploy\n\n* [x] done\n",
+			"1. Build\n   1. tag\n1. Deploy\n   - [x] done\n",
+		},
+		{
+			"description list",
+			"CPU:: 2 cores\nMemory:: 4 GiB\n",
+			"- **CPU**: 2 cores\n- **Memory**: 4 GiB\n",
+		},
+		{
+			"source block",
+			"[source,go]\n----\nfmt.Println(\"hi\")\n----\n",
+			"```go\nfmt.Println(\"hi\")\n```\n",
+		},
+		{
+			"admonitions",
+			"NOTE: Requires VPN.\n\n[WARNING]\n====\nCheck first.\n====\n",
+			"> [!NOTE]\n> Requires VPN.\n\n> [!WARNING]\n> Check first.\n",
+		},
+		{
+			"table with header and title",
+			".Ports\n[cols=\"1,1\",options=\"header\"]\n|===\n|Service |Port\n|api |8080\n|===\n",
+			"**Ports**\n\n| Service | Port |\n| --- | --- |\n| api | 8080 |\n",
+		},
+		{
+			"table without header",
+			"|===\n|a |b\n|===\n",
+			"|  |  |\n| --- | --- |\n| a | b |\n",
+		},
+		{
+			"image and comments",
+			"// hidden\nimage::diagram.png[Architecture]\n////\nhidden\n////\n",
+			"![Architecture](diagram.png)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Convert([]byte(tt.in)); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvert_CRLF(t *testing.T) {
+	got := Convert([]byte("== Title\r\n\r\ntext\r\n"))
+	if strings.Contains(got, "\r") {
+		t.Errorf("got %q, want no carriage returns", got)
+	}
+}
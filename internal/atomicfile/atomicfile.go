@@ -0,0 +1,38 @@
+// Package atomicfile writes files so that readers see either the old or the
+// new content, never a partial write.
+package atomicfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Write writes data to a temp file named after pattern (as with
+// os.CreateTemp) in the directory of path, then renames it to path. The
+// directory must exist.
+func Write(path, pattern string, data []byte) (retErr error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), pattern)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer func() {
+		if retErr != nil {
+			os.Remove(tmpName) //nolint:gosec // Path is from our own CreateTemp, not user-supplied
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmpName, path); err != nil { //nolint:gosec // Both paths are the caller's own
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
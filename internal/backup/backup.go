@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/k1LoW/mo/internal/atomicfile"
 	"github.com/k1LoW/mo/internal/xdg"
 )
 
@@ -28,7 +32,7 @@ func Path(port int) (string, error) {
 }
 
 // Save atomically writes data to the backup file for the given port.
-func Save(port int, data any) (retErr error) {
+func Save(port int, data any) error {
 	p, err := Path(port)
 	if err != nil {
 		return err
@@ -43,30 +47,7 @@ func Save(port int, data any) (retErr error) {
 		return fmt.Errorf("failed to marshal backup data: %w", err)
 	}
 
-	tmp, err := os.CreateTemp(dir, "mo-backup-*.tmp")
-	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
-	}
-	tmpName := tmp.Name()
-	defer func() {
-		if retErr != nil {
-			os.Remove(tmpName) //nolint:gosec // Path is from our own CreateTemp, not user-supplied
-		}
-	}()
-
-	if _, err := tmp.Write(b); err != nil {
-		tmp.Close()
-		return fmt.Errorf("failed to write backup data: %w", err)
-	}
-	if err := tmp.Close(); err != nil {
-		return fmt.Errorf("failed to close temp file: %w", err)
-	}
-
-	if err := os.Rename(tmpName, p); err != nil { //nolint:gosec // Both paths are from our own Path() and CreateTemp
-		return fmt.Errorf("failed to rename temp file: %w", err)
-	}
-
-	return nil
+	return atomicfile.Write(p, "mo-backup-*.tmp", b)
 }
 
 // Load reads the backup file for the given port.
@@ -114,3 +95,33 @@ func Remove(port int) error {
 	}
 	return nil
 }
+
+// Prune deletes the backup files that have not been written for longer than
+// maxAge, except those of the ports keep reports, and returns how many were
+// deleted.
+func Prune(maxAge time.Duration, keep func(port int) bool) (int, error) {
+	dir, err := Dir()
+	if err != nil {
+		return 0, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "mo-*.json"))
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, p := range paths {
+		port, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(p), "mo-"), ".json"))
+		if err != nil || keep(port) {
+			continue
+		}
+		info, err := os.Stat(p)
+		if err != nil || time.Since(info.ModTime()) <= maxAge {
+			continue
+		}
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove backup file: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -3,6 +3,7 @@ package backup
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 type testData struct {
@@ -155,3 +156,35 @@ func TestSaveCreatesDirectory(t *testing.T) {
 		t.Fatalf("backup directory should exist after Save: %v", err)
 	}
 }
+
+func TestPrune(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	past := time.Now().Add(-48 * time.Hour)
+	for _, port := range []int{6275, 6276, 6277} {
+		if err := Save(port, testData{}); err != nil {
+			t.Fatal(err)
+		}
+		if port == 6275 {
+			continue
+		}
+		p, err := Path(port)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := Prune(24*time.Hour, func(port int) bool { return port == 6277 })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("removed %d backups, want 1", removed)
+	}
+	if !Exists(6275) || Exists(6276) || !Exists(6277) {
+		t.Errorf("want only the old backup of a port not kept removed")
+	}
+}
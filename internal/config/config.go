@@ -0,0 +1,287 @@
+// Package config reads the user's defaults for mo from
+// $XDG_CONFIG_HOME/mo/config.yaml and MO_* environment variables.
+//
+// A setting given as a command line flag wins over the environment, which
+// wins over the file:
+//
+//	port: 6275          # or auto to pick a free one for a new server
+//	bind: localhost
+//	open: auto          # always, auto or never
+//	target: default     # group files open in
+//	extensions: [md, qmd]
+//	ignore: [node_modules, "*.draft.md", "vendor/**"]
+//	theme: dark         # light or dark; follows the OS when unset
+//	font_size: large    # small, medium, large or xlarge
+//	backup_retention: 30d
+//	renderers: ["dot=dot -Tsvg"]
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/k1LoW/mo/internal/fencerender"
+	"github.com/k1LoW/mo/internal/xdg"
+	"gopkg.in/yaml.v3"
+)
+
+// Open behaviours.
+const (
+	OpenAlways = "always"
+	OpenAuto   = "auto"
+	OpenNever  = "never"
+)
+
+var (
+	themes    = []string{"light", "dark"}
+	fontSizes = []string{"small", "medium", "large", "xlarge"}
+)
+
+// Config holds the defaults read from the config file and the environment.
+// Zero values mean the setting is not configured.
+type Config struct {
+	Port            string   `yaml:"port"` // a port number or auto, as with --port
+	Bind            string   `yaml:"bind"`
+	Open            string   `yaml:"open"`
+	Target          string   `yaml:"target"`
+	Extensions      []string `yaml:"extensions"`
+	Ignore          []string `yaml:"ignore"`
+	Theme           string   `yaml:"theme"`
+	FontSize        string   `yaml:"font_size"`
+	BackupRetention Duration `yaml:"backup_retention"`
+	Renderers       []string `yaml:"renderers"` // in the form of --renderer
+}
+
+// Duration is a time.Duration that also accepts a number of days, as in 30d.
+type Duration time.Duration
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (d *Duration) UnmarshalYAML(n *yaml.Node) error {
+	v, err := parseRetention(n.Value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Path returns the path to the config file: $MO_CONFIG when set, otherwise
+// $XDG_CONFIG_HOME/mo/config.yaml.
+func Path() (string, error) {
+	if v := os.Getenv("MO_CONFIG"); v != "" {
+		return v, nil
+	}
+	configHome, err := xdg.ConfigHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configHome, "mo", "config.yaml"), nil
+}
+
+// Load reads the config file, if any, and applies the MO_* environment
+// variables over it.
+func Load() (Config, error) {
+	p, err := Path()
+	if err != nil {
+		return Config{}, err
+	}
+	return load(p, os.LookupEnv)
+}
+
+func load(p string, lookupEnv func(string) (string, bool)) (Config, error) {
+	var c Config
+	data, err := os.ReadFile(p) //nolint:gosec // The user's own config file
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Config{}, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+			return Config{}, fmt.Errorf("invalid config file %s: %w", p, err)
+		}
+	}
+	if err := c.applyEnv(lookupEnv); err != nil {
+		return Config{}, err
+	}
+	if err := c.validate(); err != nil {
+		if len(data) > 0 {
+			return Config{}, fmt.Errorf("invalid config (%s): %w", p, err)
+		}
+		return Config{}, fmt.Errorf("invalid config: %w", err)
+	}
+	return c, nil
+}
+
+func (c *Config) applyEnv(lookupEnv func(string) (string, bool)) error {
+	if v, ok := lookupEnv("MO_PORT"); ok && v != "" {
+		c.Port = v
+	}
+	if v, ok := lookupEnv("MO_BIND"); ok && v != "" {
+		c.Bind = v
+	}
+	if v, ok := lookupEnv("MO_OPEN"); ok && v != "" {
+		c.Open = v
+	}
+	if v, ok := lookupEnv("MO_TARGET"); ok && v != "" {
+		c.Target = v
+	}
+	if v, ok := lookupEnv("MO_EXT"); ok && v != "" {
+		c.Extensions = splitList(v)
+	}
+	if v, ok := lookupEnv("MO_IGNORE"); ok {
+		c.Ignore = splitList(v)
+	}
+	if v, ok := lookupEnv("MO_THEME"); ok && v != "" {
+		c.Theme = v
+	}
+	if v, ok := lookupEnv("MO_FONT_SIZE"); ok && v != "" {
+		c.FontSize = v
+	}
+	if v, ok := lookupEnv("MO_BACKUP_RETENTION"); ok && v != "" {
+		d, err := parseRetention(v)
+		if err != nil {
+			return fmt.Errorf("invalid MO_BACKUP_RETENTION: %w", err)
+		}
+		c.BackupRetention = Duration(d)
+	}
+	return nil
+}
+
+func (c *Config) validate() error {
+	if c.Port != "" && !strings.EqualFold(c.Port, "auto") {
+		if n, err := strconv.Atoi(c.Port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("port must be a port number or auto, got %q", c.Port)
+		}
+	}
+	if c.Open != "" && !slices.Contains([]string{OpenAlways, OpenAuto, OpenNever}, c.Open) {
+		return fmt.Errorf("open must be %s, %s or %s, got %q", OpenAlways, OpenAuto, OpenNever, c.Open)
+	}
+	if c.Theme != "" && !slices.Contains(themes, c.Theme) {
+		return fmt.Errorf("theme must be one of %s, got %q", strings.Join(themes, ", "), c.Theme)
+	}
+	if c.FontSize != "" && !slices.Contains(fontSizes, c.FontSize) {
+		return fmt.Errorf("font_size must be one of %s, got %q", strings.Join(fontSizes, ", "), c.FontSize)
+	}
+	for _, p := range c.Ignore {
+		if !doublestar.ValidatePattern(p) {
+			return fmt.Errorf("invalid ignore pattern %q", p)
+		}
+	}
+	for _, spec := range c.Renderers {
+		if _, err := fencerender.Parse(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseRetention parses a duration such as 720h or 30d.
+func parseRetention(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err == nil && n >= 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid retention %q (e.g. 30d, 72h)", s)
+	}
+	return d, nil
+}
+
+func splitList(v string) []string {
+	var items []string
+	for item := range strings.SplitSeq(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Ignored reports whether rel, a path relative to the directory being
+// expanded, matches one of the ignore patterns. A pattern without a slash
+// matches any path element, so "node_modules" skips everything below a
+// node_modules directory and "*.draft.md" skips drafts at any depth. A
+// pattern with a slash matches the path from the start, and also whatever is
+// below a matching directory.
+func Ignored(patterns []string, rel string) bool {
+	rel = filepath.ToSlash(rel)
+	for _, p := range patterns {
+		p = strings.Trim(p, "/")
+		if p == "" {
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			for elem := range strings.SplitSeq(rel, "/") {
+				if ok, _ := path.Match(p, elem); ok {
+					return true
+				}
+			}
+			continue
+		}
+		if ok, _ := doublestar.Match(p, rel); ok {
+			return true
+		}
+		if ok, _ := doublestar.Match(p+"/**", rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AbsIgnore turns ignore patterns relative to dir, matched as by Ignored,
+// into absolute patterns for IgnoredAbs.
+func AbsIgnore(dir string, patterns []string) []string {
+	base := escapeGlob(filepath.ToSlash(filepath.Clean(dir)))
+	result := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		p = strings.Trim(p, "/")
+		switch {
+		case p == "":
+		case strings.Contains(p, "/"):
+			result = append(result, base+"/"+p)
+		default:
+			result = append(result, base+"/**/"+p)
+		}
+	}
+	return result
+}
+
+// IgnoredAbs reports whether the absolute path p, or a directory containing
+// it, matches one of the patterns returned by AbsIgnore.
+func IgnoredAbs(patterns []string, p string) bool {
+	p = filepath.ToSlash(p)
+	for _, pat := range patterns {
+		if ok, _ := doublestar.Match(pat, p); ok {
+			return true
+		}
+		if ok, _ := doublestar.Match(pat+"/**", p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]{}\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
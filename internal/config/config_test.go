@@ -0,0 +1,157 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(p, []byte(`port: 7000
+bind: 0.0.0.0
+open: never
+target: docs
+extensions: [md, qmd]
+ignore: [node_modules]
+theme: dark
+font_size: large
+backup_retention: 30d
+renderers: ["dot=dot -Tsvg"]
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("file", func(t *testing.T) {
+		c, err := load(p, noEnv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Port != "7000" || c.Bind != "0.0.0.0" || c.Open != OpenNever || c.Target != "docs" ||
+			!slices.Equal(c.Extensions, []string{"md", "qmd"}) || !slices.Equal(c.Ignore, []string{"node_modules"}) ||
+			c.Theme != "dark" || c.FontSize != "large" || time.Duration(c.BackupRetention) != 30*24*time.Hour ||
+			!slices.Equal(c.Renderers, []string{"dot=dot -Tsvg"}) {
+			t.Errorf("unexpected config %+v", c)
+		}
+	})
+
+	t.Run("env overrides the file", func(t *testing.T) {
+		env := map[string]string{
+			"MO_PORT":             "7001",
+			"MO_EXT":              "md, txt",
+			"MO_IGNORE":           "",
+			"MO_THEME":            "light",
+			"MO_BACKUP_RETENTION": "72h",
+		}
+		c, err := load(p, func(k string) (string, bool) {
+			v, ok := env[k]
+			return v, ok
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Port != "7001" || c.Bind != "0.0.0.0" || !slices.Equal(c.Extensions, []string{"md", "txt"}) ||
+			len(c.Ignore) != 0 || c.Theme != "light" || time.Duration(c.BackupRetention) != 72*time.Hour {
+			t.Errorf("unexpected config %+v", c)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		c, err := load(filepath.Join(t.TempDir(), "config.yaml"), noEnv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Port != "" || c.Open != "" {
+			t.Errorf("unexpected config %+v", c)
+		}
+	})
+
+	t.Run("auto port", func(t *testing.T) {
+		c, err := load(p, func(k string) (string, bool) {
+			if k == "MO_PORT" {
+				return "auto", true
+			}
+			return "", false
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Port != "auto" {
+			t.Errorf("got port %q, want auto", c.Port)
+		}
+	})
+
+	invalid := []string{
+		"prot: 7000\n",
+		"port: 70000\n",
+		"port: any\n",
+		"open: sometimes\n",
+		"theme: blue\n",
+		"font_size: huge\n",
+		"backup_retention: soon\n",
+		"ignore: ['[']\n",
+		"renderers: [dot]\n",
+	}
+	for _, content := range invalid {
+		t.Run(content, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := load(p, noEnv); err == nil {
+				t.Error("want an error")
+			}
+		})
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	patterns := []string{"node_modules", "*.draft.md", "docs/internal", "vendor/**"}
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{"README.md", false},
+		{"node_modules/pkg/README.md", true},
+		{"a/node_modules/README.md", true},
+		{"notes.draft.md", true},
+		{"docs/plan.draft.md", true},
+		{"docs/internal/secret.md", true},
+		{"docs/public/internal.md", false},
+		{"sub/docs/internal/secret.md", false},
+		{"vendor/x/README.md", true},
+	}
+	for _, tt := range tests {
+		if got := Ignored(patterns, tt.rel); got != tt.want {
+			t.Errorf("Ignored(%q) = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+}
+
+func noEnv(string) (string, bool) { return "", false }
+
+func TestIgnoredAbs(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "repo[1]")
+	patterns := AbsIgnore(root, []string{"node_modules", "*.draft.md", "docs/archive"})
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{"README.md", false},
+		{"node_modules/pkg/README.md", true},
+		{"a/node_modules/README.md", true},
+		{"docs/plan.draft.md", true},
+		{"docs/archive/old.md", true},
+		{"sub/docs/archive/old.md", false},
+	}
+	for _, tt := range tests {
+		if got := IgnoredAbs(patterns, filepath.Join(root, tt.rel)); got != tt.want {
+			t.Errorf("IgnoredAbs(%q) = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+	if IgnoredAbs(patterns, filepath.Join(filepath.Dir(root), "other", "node_modules", "a.md")) {
+		t.Error("matched outside the directory")
+	}
+}
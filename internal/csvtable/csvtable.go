@@ -0,0 +1,243 @@
+package csvtable
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Table is a parsed CSV or TSV file.
+type Table struct {
+	Delimiter rune
+	Header    []string // nil when the first row is data
+	Rows      [][]string
+	Columns   int
+}
+
+// candidates are the delimiters considered when sniffing CSV files, in order
+// of preference: a file with semicolons on every line is more likely to use
+// decimal commas than to have a semicolon in every row by chance.
+var candidates = []rune{'\t', ';', '|', ','}
+
+const sniffLines = 20
+
+// Parse parses data as delimiter-separated values. tsv forces a tab
+// delimiter; otherwise the delimiter is sniffed from the first lines. Whether
+// the first row is a header is sniffed as well. Rows are padded to the same
+// number of columns.
+func Parse(data []byte, tsv bool) (*Table, error) {
+	text := DecodeText(data)
+	delim := '\t'
+	if !tsv {
+		delim = sniffDelimiter(text)
+	}
+
+	r := csv.NewReader(strings.NewReader(text))
+	r.Comma = delim
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	var rows [][]string
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, rec)
+	}
+
+	t := &Table{Delimiter: delim}
+	for _, row := range rows {
+		t.Columns = max(t.Columns, len(row))
+	}
+	for i, row := range rows {
+		if len(row) < t.Columns {
+			rows[i] = append(row, make([]string, t.Columns-len(row))...)
+		}
+	}
+	if len(rows) > 1 && hasHeader(rows) {
+		t.Header, rows = rows[0], rows[1:]
+	}
+	t.Rows = rows
+	return t, nil
+}
+
+// DecodeText returns data as UTF-8 text, dropping a byte order mark and
+// decoding UTF-16 (as spreadsheet applications often export).
+func DecodeText(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:])
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], false)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], true)
+	}
+	return string(data)
+}
+
+// HasUTF16BOM reports whether data starts with a UTF-16 byte order mark.
+// Such text contains NUL bytes, which would otherwise look binary.
+func HasUTF16BOM(data []byte) bool {
+	return bytes.HasPrefix(data, []byte{0xFF, 0xFE}) || bytes.HasPrefix(data, []byte{0xFE, 0xFF})
+}
+
+func decodeUTF16(b []byte, bigEndian bool) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		if bigEndian {
+			u[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		} else {
+			u[i] = uint16(b[2*i+1])<<8 | uint16(b[2*i])
+		}
+	}
+	return string(utf16.Decode(u))
+}
+
+// sniffDelimiter picks the first candidate that appears the same, non-zero
+// number of times (outside quotes) on each of the first lines. Without a
+// consistent candidate, the most frequent one wins.
+func sniffDelimiter(text string) rune {
+	var lines []string
+	for line := range strings.SplitSeq(text, "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			lines = append(lines, line)
+		}
+		if len(lines) == sniffLines {
+			break
+		}
+	}
+
+	best, bestTotal := ',', 0
+	for _, c := range candidates {
+		consistent := len(lines) > 0
+		first, total := -1, 0
+		for _, line := range lines {
+			n := countOutsideQuotes(line, c)
+			total += n
+			if first < 0 {
+				first = n
+			}
+			if n == 0 || n != first {
+				consistent = false
+			}
+		}
+		if consistent {
+			return c
+		}
+		if total > bestTotal {
+			best, bestTotal = c, total
+		}
+	}
+	return best
+}
+
+func countOutsideQuotes(line string, c rune) int {
+	n := 0
+	quoted := false
+	for _, r := range line {
+		switch r {
+		case '"':
+			quoted = !quoted
+		case c:
+			if !quoted {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// hasHeader guesses whether the first row is a header. A column whose values
+// are numeric but whose first value is not votes for a header, and the
+// reverse votes against. Without numeric columns, a first row with empty or
+// duplicate cells is taken as data and anything else as a header.
+func hasHeader(rows [][]string) bool {
+	first, rest := rows[0], rows[1:min(len(rows), sniffLines+1)]
+	votes := 0
+	for col := range first {
+		numeric := true
+		for _, row := range rest {
+			if v := strings.TrimSpace(row[col]); v != "" && !isNumber(v) {
+				numeric = false
+				break
+			}
+		}
+		if !numeric {
+			continue
+		}
+		if isNumber(strings.TrimSpace(first[col])) {
+			votes--
+		} else {
+			votes++
+		}
+	}
+	if votes != 0 {
+		return votes > 0
+	}
+	seen := make(map[string]struct{}, len(first))
+	for _, v := range first {
+		v = strings.TrimSpace(v)
+		if _, dup := seen[v]; dup || v == "" {
+			return false
+		}
+		seen[v] = struct{}{}
+	}
+	return true
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+	return err == nil
+}
+
+// Sort orders the rows by column col, comparing numbers numerically and
+// other values case-insensitively. Empty cells sort last in either order.
+func (t *Table) Sort(col int, desc bool) {
+	if col < 0 || col >= t.Columns {
+		return
+	}
+	slices.SortStableFunc(t.Rows, func(a, b []string) int {
+		x, y := strings.TrimSpace(a[col]), strings.TrimSpace(b[col])
+		switch {
+		case x == "" && y == "":
+			return 0
+		case x == "":
+			return 1
+		case y == "":
+			return -1
+		}
+		c := compareValues(x, y)
+		if desc {
+			return -c
+		}
+		return c
+	})
+}
+
+func compareValues(x, y string) int {
+	fx, errX := strconv.ParseFloat(strings.ReplaceAll(x, ",", ""), 64)
+	fy, errY := strconv.ParseFloat(strings.ReplaceAll(y, ",", ""), 64)
+	switch {
+	case errX == nil && errY == nil:
+		if fx < fy {
+			return -1
+		}
+		if fx > fy {
+			return 1
+		}
+		return 0
+	case errX == nil:
+		return -1 // numbers before text
+	case errY == nil:
+		return 1
+	}
+	return strings.Compare(strings.ToLower(x), strings.ToLower(y))
+}
@@ -0,0 +1,117 @@
+package csvtable
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		tsv        bool
+		wantDelim  rune
+		wantHeader []string
+		wantRows   [][]string
+	}{
+		{
+			name:       "comma with header",
+			data:       "name,age\nalice,30\nbob,25\n",
+			wantDelim:  ',',
+			wantHeader: []string{"name", "age"},
+			wantRows:   [][]string{{"alice", "30"}, {"bob", "25"}},
+		},
+		{
+			name:      "numeric first row is data",
+			data:      "1,2\n3,4\n",
+			wantDelim: ',',
+			wantRows:  [][]string{{"1", "2"}, {"3", "4"}},
+		},
+		{
+			name:       "semicolons with decimal commas",
+			data:       "item;price\ntea;1,50\ncoffee;2,30\n",
+			wantDelim:  ';',
+			wantHeader: []string{"item", "price"},
+			wantRows:   [][]string{{"tea", "1,50"}, {"coffee", "2,30"}},
+		},
+		{
+			name:       "quoted delimiters are not counted",
+			data:       "city,note\n\"Paris, FR\",ok\n\"Tokyo\",\"a;b\"\n",
+			wantDelim:  ',',
+			wantHeader: []string{"city", "note"},
+			wantRows:   [][]string{{"Paris, FR", "ok"}, {"Tokyo", "a;b"}},
+		},
+		{
+			name:       "tsv",
+			data:       "a,b\tc\n1\t2\n",
+			tsv:        true,
+			wantDelim:  '\t',
+			wantHeader: []string{"a,b", "c"},
+			wantRows:   [][]string{{"1", "2"}},
+		},
+		{
+			name:      "duplicate text cells are data",
+			data:      "x,x\ny,z\n",
+			wantDelim: ',',
+			wantRows:  [][]string{{"x", "x"}, {"y", "z"}},
+		},
+		{
+			name:       "ragged rows are padded",
+			data:       "a,b,c\n1\n2,3\n",
+			wantDelim:  ',',
+			wantHeader: []string{"a", "b", "c"},
+			wantRows:   [][]string{{"1", "", ""}, {"2", "3", ""}},
+		},
+		{
+			name:       "UTF-16LE with BOM",
+			data:       "\xff\xfea\x00,\x00b\x00\n\x001\x00,\x00x\x00\n\x00",
+			wantDelim:  ',',
+			wantHeader: []string{"a", "b"},
+			wantRows:   [][]string{{"1", "x"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tbl, err := Parse([]byte(tt.data), tt.tsv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tbl.Delimiter != tt.wantDelim {
+				t.Errorf("got delimiter %q, want %q", tbl.Delimiter, tt.wantDelim)
+			}
+			if !slices.Equal(tbl.Header, tt.wantHeader) {
+				t.Errorf("got header %q, want %q", tbl.Header, tt.wantHeader)
+			}
+			if !slices.EqualFunc(tbl.Rows, tt.wantRows, slices.Equal) {
+				t.Errorf("got rows %q, want %q", tbl.Rows, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestSort(t *testing.T) {
+	tbl, err := Parse([]byte("name,size\nb,10\na,9\nC,\nd,\"1,000\"\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl.Sort(1, false)
+	if got := names(tbl); !slices.Equal(got, []string{"a", "b", "d", "C"}) {
+		t.Errorf("ascending by size: got %v", got)
+	}
+	tbl.Sort(1, true)
+	if got := names(tbl); !slices.Equal(got, []string{"d", "b", "a", "C"}) {
+		t.Errorf("descending by size: got %v (empty cells stay last)", got)
+	}
+	tbl.Sort(0, false)
+	if got := names(tbl); !slices.Equal(got, []string{"a", "b", "C", "d"}) {
+		t.Errorf("ascending by name: got %v", got)
+	}
+}
+
+func names(tbl *Table) []string {
+	var out []string
+	for _, r := range tbl.Rows {
+		out = append(out, r[0])
+	}
+	return out
+}
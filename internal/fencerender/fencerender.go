@@ -0,0 +1,154 @@
+// Package fencerender replaces fenced code blocks of configured languages,
+// such as Graphviz or PlantUML, with the output of local commands.
+package fencerender
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/k1LoW/mo/internal/mdfence"
+)
+
+// DefaultTimeout is how long a renderer may run by default.
+const DefaultTimeout = 10 * time.Second
+
+// ErrTimedOut is returned by Run when the command runs out of time.
+var ErrTimedOut = errors.New("timed out")
+
+// maxOutput is the largest output a renderer may produce.
+const maxOutput = 10 << 20
+
+// Renderer renders the code blocks of a fence language with a command that
+// reads the block on stdin and writes SVG to stdout.
+type Renderer struct {
+	Lang    string
+	Command []string
+}
+
+// Parse parses a renderer given as "lang=command args", e.g.
+// "dot=dot -Tsvg". The command is split on spaces and run without a shell.
+func Parse(spec string) (Renderer, error) {
+	lang, command, ok := strings.Cut(spec, "=")
+	lang = strings.TrimSpace(lang)
+	args := strings.Fields(command)
+	if !ok || lang == "" || len(args) == 0 {
+		return Renderer{}, fmt.Errorf("invalid renderer %q: want lang=command, e.g. dot=dot -Tsvg", spec)
+	}
+	if strings.ContainsAny(lang, " \t`~") {
+		return Renderer{}, fmt.Errorf("invalid renderer %q: invalid language %q", spec, lang)
+	}
+	return Renderer{Lang: lang, Command: args}, nil
+}
+
+// String returns the renderer in the form Parse accepts.
+func (r Renderer) String() string {
+	return r.Lang + "=" + strings.Join(r.Command, " ")
+}
+
+// Run runs the renderer with code on stdin and returns its output. Failures
+// carry the first line the command wrote to stderr.
+func (r Renderer) Run(ctx context.Context, code string, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, r.Command[0], r.Command[1:]...) //nolint:gosec // Renderer commands are configured by the user
+	cmd.Stdin = strings.NewReader(code)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &limitedWriter{w: &stdout, n: maxOutput}
+	cmd.Stderr = &limitedWriter{w: &stderr, n: 4096}
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%s %w after %s", r.Command[0], ErrTimedOut, timeout)
+	}
+	if err != nil {
+		if msg := firstLine(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", r.Command[0], msg)
+		}
+		return nil, fmt.Errorf("%s: %w", r.Command[0], err)
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("%s produced no output", r.Command[0])
+	}
+	return stdout.Bytes(), nil
+}
+
+// Replace calls render for every fenced code block in content whose language
+// it handles, with the block's code and the block itself, and puts the
+// returned Markdown in place of the block.
+func Replace(content string, handles func(lang string) bool, render func(lang, code, block string) string) string {
+	if !strings.Contains(content, "```") && !strings.Contains(content, "~~~") {
+		return content
+	}
+	var b, block, code strings.Builder
+	var fence mdfence.Fence
+	rendering := false
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if line == "" {
+			continue
+		}
+		if fence.Len == 0 {
+			if f, ok := mdfence.Open(line); ok {
+				fence = f
+				rendering = handles(f.Lang())
+				if rendering {
+					block.Reset()
+					code.Reset()
+					block.WriteString(line)
+					continue
+				}
+			}
+			b.WriteString(line)
+			continue
+		}
+		if !rendering {
+			b.WriteString(line)
+			if fence.Closes(line) {
+				fence = mdfence.Fence{}
+			}
+			continue
+		}
+		block.WriteString(line)
+		if !fence.Closes(line) {
+			code.WriteString(line)
+			continue
+		}
+		b.WriteString(render(fence.Lang(), code.String(), block.String()))
+		fence, rendering = mdfence.Fence{}, false
+	}
+	// A block left open runs to the end of the document and is kept as is.
+	if rendering {
+		b.WriteString(block.String())
+	}
+	return b.String()
+}
+
+func firstLine(s string) string {
+	for line := range strings.SplitSeq(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// limitedWriter discards what is written beyond n bytes.
+type limitedWriter struct {
+	w *bytes.Buffer
+	n int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if rest := l.n - l.w.Len(); rest > 0 {
+		if len(p) > rest {
+			l.w.Write(p[:rest])
+		} else {
+			l.w.Write(p)
+		}
+	}
+	return len(p), nil
+}
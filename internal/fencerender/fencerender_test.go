@@ -0,0 +1,56 @@
+package fencerender
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	r, err := Parse("dot=dot -Tsvg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Lang != "dot" || strings.Join(r.Command, "|") != "dot|-Tsvg" || r.String() != "dot=dot -Tsvg" {
+		t.Errorf("unexpected renderer %+v", r)
+	}
+	for _, spec := range []string{"dot", "dot=", "=dot -Tsvg", "a b=cmd"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q): want an error", spec)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	out, err := Renderer{Lang: "txt", Command: []string{"cat"}}.Run(ctx, "<svg/>", time.Second)
+	if err != nil || string(out) != "<svg/>" {
+		t.Errorf("got %q, %v", out, err)
+	}
+
+	_, err = Renderer{Lang: "txt", Command: []string{"sh", "-c", "echo 'syntax error in line 1' >&2; exit 1"}}.Run(ctx, "", time.Second)
+	if err == nil || err.Error() != "sh: syntax error in line 1" {
+		t.Errorf("got %v", err)
+	}
+
+	_, err = Renderer{Lang: "txt", Command: []string{"sleep", "5"}}.Run(ctx, "", 50*time.Millisecond)
+	if !errors.Is(err, ErrTimedOut) || !strings.Contains(err.Error(), "timed out after") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	content := "# Doc\n\n```dot\ndigraph { a -> b }\n```\n\n~~~go\n```dot\n~~~\n\n```dot\nbroken\n```\n"
+	got := Replace(content, func(lang string) bool { return lang == "dot" }, func(_, code, block string) string {
+		if code == "broken\n" {
+			return block
+		}
+		return "![dot](" + strings.TrimSpace(code) + ")\n"
+	})
+	want := "# Doc\n\n![dot](digraph { a -> b })\n\n~~~go\n```dot\n~~~\n\n```dot\nbroken\n```\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
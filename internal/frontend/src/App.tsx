@@ -36,7 +36,9 @@ import {
   buildFileUrl,
 } from "./utils/groups";
 import { isMarkdownFile } from "./utils/filetype";
-import { formatFileLabel } from "./utils/fileLabel";
+import { formatFileLabel, formatLastCommit, stripRevision } from "./utils/fileLabel";
+import { serverDefault } from "./utils/serverDefaults";
+import { parseLocationHash, type DocLocation } from "./utils/location";
 
 const VIEWMODE_STORAGE_KEY = "mo-sidebar-viewmode";
 const WIDTH_STORAGE_KEY = "mo-layout-width";
@@ -53,6 +55,15 @@ export function getInitialFontSize(): FontSize {
   } catch {
     /* ignore */
   }
+  const configured = serverDefault("font-size");
+  if (
+    configured === "small" ||
+    configured === "medium" ||
+    configured === "large" ||
+    configured === "xlarge"
+  ) {
+    return configured;
+  }
   return "medium";
 }
 
@@ -87,6 +98,10 @@ export function isTocOpenForFile(
   return map[fileId] === true;
 }
 
+interface PendingLocation extends DocLocation {
+  fileId: string;
+}
+
 export function App() {
   const [groups, setGroups] = useState<Group[]>([]);
   const [activeGroup, setActiveGroup] = useState<string>(
@@ -101,6 +116,13 @@ export function App() {
   const [searchResults, setSearchResults] = useState<SearchResult[]>([]);
   const [searchLoading, setSearchLoading] = useState(false);
   const [pendingSearchHeading, setPendingSearchHeading] = useState<string | null>(null);
+  const [pendingChangedLine, setPendingChangedLine] = useState<number | null>(null);
+  // Where a deeplink or a navigation from the CLI points in a file.
+  const [pendingLocation, setPendingLocation] = useState<PendingLocation | null>(() => {
+    const fileId = parseFileIdFromSearch(window.location.search);
+    const location = parseLocationHash(window.location.hash);
+    return fileId && location ? { fileId, ...location } : null;
+  });
   const [viewModes, setViewModes] = useState<Record<string, ViewMode>>(() => {
     try {
       const stored = localStorage.getItem(VIEWMODE_STORAGE_KEY);
@@ -316,7 +338,11 @@ export function App() {
     [activeGroupData, activeFileId],
   );
   const activeFileName = activeFile?.name ?? "";
-  const tocOpen = isTocOpenForFile(tocOpenMap, activeFileId, activeFileName);
+  const tocOpen = isTocOpenForFile(
+    tocOpenMap,
+    activeFileId,
+    stripRevision(activeFileName, activeFile?.revision),
+  );
   const currentShowTitle: boolean = showTitles[activeGroup] ?? false;
 
   const setTocOpen = useCallback(
@@ -335,15 +361,25 @@ export function App() {
     onUpdate: () => {
       loadGroups();
     },
-    onFileChanged: (fileId) => {
+    onFileChanged: (fileId, change) => {
       captureScrollPosition();
       setActiveFileId((current) => {
         if (current === fileId) {
           setContentRevision((r) => r + 1);
+          setPendingChangedLine(change.firstChangedLine ?? null);
         }
         return current;
       });
     },
+    onNavigate: (group, fileId, location) => {
+      window.history.pushState(null, "", buildFileUrl(group, fileId));
+      setActiveGroup(group);
+      // Resolved against the refreshed groups, which may not list a file
+      // added just before the navigation yet.
+      setInitialFileId(fileId);
+      setPendingLocation({ fileId, ...location });
+      loadGroups();
+    },
   });
 
   const { isDragging } = useFileDrop(activeGroup);
@@ -558,11 +594,19 @@ export function App() {
                 onTocToggle={() => setTocOpen(!tocOpen)}
                 onRemoveFile={handleRemoveFile}
                 uploaded={activeFile?.uploaded}
+                url={activeFile?.url}
+                gitRevision={activeFile?.revision}
+                gitStatus={activeFile?.gitStatus}
+                lastCommit={formatLastCommit(activeFile?.git)}
                 isWide={isWide}
                 fontSize={fontSize}
                 onZoom={handleZoom}
                 scrollToHeading={pendingSearchHeading}
                 onScrolledToHeading={() => setPendingSearchHeading(null)}
+                scrollToLine={pendingChangedLine}
+                onScrolledToLine={() => setPendingChangedLine(null)}
+                scrollToLocation={pendingLocation?.fileId === activeFileId ? pendingLocation : null}
+                onScrolledToLocation={() => setPendingLocation(null)}
                 searchQuery={searchQuery}
               />
             ) : (
@@ -0,0 +1,25 @@
+interface DiffToggleProps {
+  isDiff: boolean;
+  onToggle: () => void;
+}
+
+export function DiffToggle({ isDiff, onToggle }: DiffToggleProps) {
+  return (
+    <button
+      type="button"
+      className="flex items-center justify-center bg-transparent border border-gh-border rounded-md p-1.5 text-gh-text-secondary cursor-pointer transition-colors duration-150 hover:bg-gh-bg-hover"
+      onClick={onToggle}
+      aria-label="Diff view"
+      aria-pressed={isDiff}
+      title={isDiff ? "Show rendered" : "Show diff against the work tree"}
+    >
+      <svg className="size-5" fill="none" stroke="currentColor" strokeWidth={1.5} viewBox="0 0 24 24">
+        <path
+          strokeLinecap="round"
+          strokeLinejoin="round"
+          d="M7.5 21 3 16.5m0 0L7.5 12M3 16.5h13.5m0-13.5L21 7.5m0 0L16.5 12M21 7.5H7.5"
+        />
+      </svg>
+    </button>
+  );
+}
@@ -0,0 +1,78 @@
+import { useEffect, useState } from "react";
+import { fetchWorktreeDiff, type WorktreeDiff } from "../hooks/useApi";
+
+interface DiffViewProps {
+  group: string;
+  fileId: string;
+  revision: number;
+}
+
+const lineClass: Record<string, string> = {
+  insert: "bg-green-500/15",
+  delete: "bg-red-500/15",
+  equal: "",
+};
+
+const linePrefix: Record<string, string> = {
+  insert: "+",
+  delete: "-",
+  equal: " ",
+};
+
+export function DiffView({ group, fileId, revision }: DiffViewProps) {
+  const [diff, setDiff] = useState<WorktreeDiff | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    let cancelled = false;
+    fetchWorktreeDiff(group, fileId)
+      .then((data) => {
+        if (!cancelled) {
+          setDiff(data);
+          setError(null);
+        }
+      })
+      .catch((e: unknown) => {
+        if (!cancelled) {
+          setError(e instanceof Error ? e.message : String(e));
+        }
+      });
+    return () => {
+      cancelled = true;
+    };
+  }, [group, fileId, revision]);
+
+  if (error) {
+    return <p className="text-gh-text-secondary">{error}</p>;
+  }
+  if (!diff) {
+    return <p className="text-gh-text-secondary">Loading diff...</p>;
+  }
+  if (diff.hunks.length === 0) {
+    return <p className="text-gh-text-secondary">No differences from the work tree.</p>;
+  }
+
+  return (
+    <div className="not-prose font-mono text-sm">
+      {!diff.exists && (
+        <p className="text-gh-text-secondary">The file no longer exists in the work tree.</p>
+      )}
+      {diff.hunks.map((hunk) => (
+        <div
+          key={`${hunk.oldStart}:${hunk.newStart}`}
+          className="mb-4 border border-gh-border rounded-md overflow-x-auto"
+        >
+          <div className="px-2 py-1 bg-gh-bg-secondary text-gh-text-secondary">
+            @@ -{hunk.oldStart},{hunk.oldLines} +{hunk.newStart},{hunk.newLines} @@
+          </div>
+          {hunk.lines?.map((line, i) => (
+            <div key={i} className={`px-2 whitespace-pre ${lineClass[line.op] ?? ""}`}>
+              {linePrefix[line.op] ?? " "}
+              {line.text}
+            </div>
+          ))}
+        </div>
+      ))}
+    </div>
+  );
+}
@@ -0,0 +1,31 @@
+import { describe, it, expect } from "vitest";
+import { render, screen } from "@testing-library/react";
+import { GitStatusBadge, WorktreeStatusDot } from "./GitStatusBadge";
+
+describe("GitStatusBadge", () => {
+  it.each([
+    ["added", "A"],
+    ["modified", "M"],
+    ["deleted", "D"],
+  ])("renders %s as %s", (status, letter) => {
+    render(<GitStatusBadge status={status} />);
+    expect(screen.getByTitle(status).textContent).toBe(letter);
+  });
+
+  it("renders nothing without a status", () => {
+    const { container } = render(<GitStatusBadge />);
+    expect(container.firstChild).toBeNull();
+  });
+});
+
+describe("WorktreeStatusDot", () => {
+  it("labels uncommitted edits", () => {
+    render(<WorktreeStatusDot status="modified" />);
+    expect(screen.getByLabelText("Uncommitted changes")).toBeInTheDocument();
+  });
+
+  it("renders nothing for a clean file", () => {
+    const { container } = render(<WorktreeStatusDot />);
+    expect(container.firstChild).toBeNull();
+  });
+});
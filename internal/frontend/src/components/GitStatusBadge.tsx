@@ -0,0 +1,35 @@
+const badges: Record<string, { letter: string; className: string }> = {
+  added: { letter: "A", className: "text-green-500" },
+  modified: { letter: "M", className: "text-amber-500" },
+  deleted: { letter: "D", className: "text-red-500" },
+};
+
+// GitStatusBadge marks a file of a review group with its git status.
+export function GitStatusBadge({ status }: { status?: string }) {
+  const badge = status ? badges[status] : undefined;
+  if (!badge) return null;
+  return (
+    <span className={`shrink-0 font-mono text-xs font-semibold ${badge.className}`} title={status}>
+      {badge.letter}
+    </span>
+  );
+}
+
+const worktreeLabels: Record<string, string> = {
+  modified: "Uncommitted changes",
+  staged: "Staged changes",
+  untracked: "Untracked file",
+};
+
+// WorktreeStatusDot marks a file that has uncommitted edits in its work tree.
+export function WorktreeStatusDot({ status }: { status?: string }) {
+  const label = status ? worktreeLabels[status] : undefined;
+  if (!label) return null;
+  return (
+    <span
+      className={`shrink-0 size-2 rounded-full ${status === "staged" ? "bg-green-500" : "bg-amber-500"}`}
+      title={label}
+      aria-label={label}
+    />
+  );
+}
@@ -11,9 +11,13 @@ import "katex/dist/katex.min.css";
 import { codeToHtml } from "shiki";
 import mermaid from "mermaid";
 import { fetchFileContent, openRelativeFile } from "../hooks/useApi";
+import type { TableData } from "../hooks/useApi";
 import { isPlainLeftClick } from "../utils/linkClick";
 import { escapeRegExp } from "../utils/regex";
 import { RawToggle } from "./RawToggle";
+import { DiffToggle } from "./DiffToggle";
+import { DiffView } from "./DiffView";
+import { TableView } from "./TableView";
 import { TocToggle } from "./TocToggle";
 import { CopyButton } from "./CopyButton";
 import { CloseFileButton } from "./CloseFileButton";
@@ -22,7 +26,10 @@ import { buildRelativeOpenUrl } from "../utils/groups";
 import { parseFrontmatter } from "../utils/frontmatter";
 import { stripMdxSyntax } from "../utils/mdx";
 import { isMarkdownFile, detectLanguage } from "../utils/filetype";
-import { formatFileLabel } from "../utils/fileLabel";
+import { formatFileLabel, stripRevision } from "../utils/fileLabel";
+import { rehypeSourceLines, countLeadingLines, findBlockForLine } from "../utils/sourceLines";
+import { findAnchorTarget } from "../utils/location";
+import type { DocLocation } from "../utils/location";
 import type { ZoomContent } from "./ZoomModal";
 import type { TocHeading } from "./TocPanel";
 import type { Components } from "react-markdown";
@@ -59,6 +66,7 @@ const sanitizeSchema = {
   ...defaultSchema,
   attributes: {
     ...defaultSchema.attributes,
+    "*": [...(defaultSchema.attributes?.["*"] || []), "dataSourceLine", "dataSourceEndLine"],
     span: [...(defaultSchema.attributes?.["span"] || []), "style"],
     div: [...(defaultSchema.attributes?.["div"] || []), "style", "align"],
   },
@@ -93,11 +101,19 @@ interface MarkdownViewerProps {
   onTocToggle: () => void;
   onRemoveFile: () => void;
   uploaded?: boolean;
+  url?: string;
+  gitRevision?: string;
+  gitStatus?: string;
+  lastCommit?: string;
   isWide: boolean;
   fontSize: FontSize;
   onZoom?: (content: ZoomContent) => void;
   scrollToHeading?: string | null;
   onScrolledToHeading?: () => void;
+  scrollToLine?: number | null;
+  onScrolledToLine?: () => void;
+  scrollToLocation?: DocLocation | null;
+  onScrolledToLocation?: () => void;
   searchQuery?: string | null;
 }
 
@@ -108,6 +124,14 @@ interface SearchHitMarker {
 
 const SEARCH_HIT_COLUMN_OFFSET = -24;
 
+// Scroll target into view and flash it.
+function flashBlock(target: HTMLElement, block: ScrollLogicalPosition) {
+  const reduced = window.matchMedia("(prefers-reduced-motion: reduce)").matches;
+  target.scrollIntoView({ behavior: reduced ? "auto" : "smooth", block });
+  target.classList.add("changed-highlight");
+  setTimeout(() => target.classList.remove("changed-highlight"), 2000);
+}
+
 function collectSearchHitMarkers(root: HTMLElement, query: string): SearchHitMarker[] {
   const trimmed = query.trim();
   if (!trimmed) {
@@ -572,16 +596,26 @@ export function MarkdownViewer({
   onTocToggle,
   onRemoveFile,
   uploaded,
+  url,
+  gitRevision,
+  gitStatus,
+  lastCommit,
   isWide,
   fontSize,
   onZoom,
   scrollToHeading,
   onScrolledToHeading,
+  scrollToLine,
+  onScrolledToLine,
+  scrollToLocation,
+  onScrolledToLocation,
   searchQuery,
 }: MarkdownViewerProps) {
   const [content, setContent] = useState("");
+  const [table, setTable] = useState<TableData | null>(null);
   const [loading, setLoading] = useState(true);
   const [isRawView, setIsRawView] = useState(false);
+  const [isDiffView, setIsDiffView] = useState(false);
   const [searchHitMarkers, setSearchHitMarkers] = useState<SearchHitMarker[]>([]);
   // The sticky bar shows the file name only while the document's own title is on
   // screen (so it never duplicates it), then folds the title into the label once
@@ -602,12 +636,14 @@ export function MarkdownViewer({
       .then((data) => {
         if (!cancelled) {
           setContent(data.content);
+          setTable(data.table ?? null);
           setLoading(false);
         }
       })
       .catch(() => {
         if (!cancelled) {
           setContent("Failed to load file.");
+          setTable(null);
           setLoading(false);
         }
       });
@@ -652,7 +688,7 @@ export function MarkdownViewer({
         );
       },
       img: ({ src, alt, ...props }) => {
-        const resolvedSrc = resolveImageSrc(src, activeGroup, fileId);
+        const resolvedSrc = resolveImageSrc(src, activeGroup, fileId, revision);
         if (onZoom && resolvedSrc) {
           return (
             <span className="relative inline-block group/img">
@@ -665,7 +701,7 @@ export function MarkdownViewer({
             </span>
           );
         }
-        return <img src={resolveImageSrc(src, activeGroup, fileId)} alt={alt} {...props} />;
+        return <img src={resolvedSrc} alt={alt} {...props} />;
       },
       a: ({ href, children, ...props }) => {
         const resolved = resolveLink(href, activeGroup, fileId);
@@ -731,11 +767,15 @@ export function MarkdownViewer({
         }
       },
     }),
-    [activeGroup, fileId, handleLinkClick, onZoom],
+    [activeGroup, fileId, revision, handleLinkClick, onZoom],
   );
 
-  const isMarkdown = isMarkdownFile(fileName);
-  const codeLanguage = isMarkdown ? null : detectLanguage(fileName);
+  const typeName = stripRevision(fileName, gitRevision);
+  // Entries read from a revision and files of a review group can be diffed
+  // against the work tree.
+  const hasDiff = !!gitRevision || !!gitStatus;
+  const isMarkdown = isMarkdownFile(typeName);
+  const codeLanguage = isMarkdown ? null : detectLanguage(typeName);
 
   const parsed = useMemo(
     () => (isMarkdown && !isRawView ? parseFrontmatter(content) : null),
@@ -743,6 +783,9 @@ export function MarkdownViewer({
   );
 
   const renderedContent = useMemo(() => {
+    if (table && !isRawView) {
+      return <TableView key={fileId} group={activeGroup} fileId={fileId} revision={revision} initial={table} />;
+    }
     if (!isMarkdown) {
       return <HighlightedView content={content} language={codeLanguage!} />;
     }
@@ -750,7 +793,7 @@ export function MarkdownViewer({
       return <RawView content={content} />;
     }
     const base = parsed ? parsed.content : content;
-    const md = fileName.toLowerCase().endsWith(".mdx") ? stripMdxSyntax(base) : base;
+    const md = typeName.toLowerCase().endsWith(".mdx") ? stripMdxSyntax(base) : base;
     return (
       <>
         {parsed && <FrontmatterBlock yaml={parsed.yaml} />}
@@ -758,6 +801,7 @@ export function MarkdownViewer({
           remarkPlugins={[remarkGfm, remarkMath]}
           rehypePlugins={[
             rehypeRaw,
+            [rehypeSourceLines, countLeadingLines(content, base)],
             rehypeStripClobberPrefix,
             [rehypeSanitize, sanitizeSchema],
             rehypeGithubAlerts,
@@ -771,7 +815,19 @@ export function MarkdownViewer({
         </Markdown>
       </>
     );
-  }, [content, isRawView, isMarkdown, codeLanguage, parsed, components, fileName]);
+  }, [
+    content,
+    table,
+    isRawView,
+    isMarkdown,
+    codeLanguage,
+    parsed,
+    components,
+    typeName,
+    activeGroup,
+    fileId,
+    revision,
+  ]);
 
   const prevHeadingsKey = useRef("");
   useEffect(() => {
@@ -821,6 +877,43 @@ export function MarkdownViewer({
     }
   }, [loading, renderedContent, scrollToHeading, onScrolledToHeading]);
 
+  // After a live reload, bring the first changed block into view and flash it.
+  // This runs after scroll restoration so it only moves when the change is
+  // off screen.
+  useLayoutEffect(() => {
+    if (loading || scrollToLine == null || !articleRef.current) {
+      return;
+    }
+    onScrolledToLine?.();
+    if (!isMarkdown || isRawView) {
+      return;
+    }
+    const target = findBlockForLine(articleRef.current, scrollToLine);
+    if (target) {
+      flashBlock(target, "nearest");
+    }
+  }, [loading, renderedContent, scrollToLine, onScrolledToLine, isMarkdown, isRawView]);
+
+  // A deeplink to a heading anchor or source line scrolls it to the top and
+  // flashes it.
+  useLayoutEffect(() => {
+    if (loading || scrollToLocation == null || !articleRef.current) {
+      return;
+    }
+    onScrolledToLocation?.();
+    if (!isMarkdown || isRawView) {
+      return;
+    }
+    const target = scrollToLocation.anchor
+      ? findAnchorTarget(articleRef.current, scrollToLocation.anchor)
+      : scrollToLocation.line
+        ? findBlockForLine(articleRef.current, scrollToLocation.line)
+        : null;
+    if (target) {
+      flashBlock(target, "start");
+    }
+  }, [loading, renderedContent, scrollToLocation, onScrolledToLocation, isMarkdown, isRawView]);
+
   useLayoutEffect(() => {
     if (loading || !articleRef.current || !isMarkdown || isRawView || !searchQuery?.trim()) {
       setSearchHitMarkers([]);
@@ -904,8 +997,13 @@ export function MarkdownViewer({
         <div
           ref={stickyLabelRef}
           className={`sticky -top-8 z-20 mx-auto mb-4 border-b border-gh-border bg-gh-bg py-2 text-sm font-medium text-right text-gh-text-secondary overflow-hidden text-ellipsis whitespace-nowrap${isWide ? "" : " max-w-[980px]"}`}
-          title={!uploaded && filePath ? filePath : fileName}
+          title={url ?? (!uploaded && filePath ? filePath : fileName)}
         >
+          {lastCommit && (
+            <span className="float-left mr-4 font-normal" title="Last commit">
+              {lastCommit}
+            </span>
+          )}
           {showFullLabel ? formatFileLabel(fileName, title) : fileName}
         </div>
         <article
@@ -925,12 +1023,19 @@ export function MarkdownViewer({
               />
             ))}
           </div>
-          {renderedContent}
+          {hasDiff && isDiffView ? (
+            <DiffView group={activeGroup} fileId={fileId} revision={revision} />
+          ) : (
+            renderedContent
+          )}
         </article>
       </div>
       <div className="shrink-0 flex flex-col gap-2 -mr-4 -mt-4 sticky -top-4">
         {isMarkdown && <TocToggle isTocOpen={isTocOpen} onToggle={onTocToggle} />}
-        {isMarkdown && <RawToggle isRaw={isRawView} onToggle={() => setIsRawView((v) => !v)} />}
+        {(isMarkdown || (!!table && content !== "")) && (
+          <RawToggle isRaw={isRawView} onToggle={() => setIsRawView((v) => !v)} />
+        )}
+        {hasDiff && <DiffToggle isDiff={isDiffView} onToggle={() => setIsDiffView((v) => !v)} />}
         <CopyButton content={content} />
         <CloseFileButton onClose={onRemoveFile} uploaded={uploaded} />
       </div>
@@ -23,6 +23,7 @@ import type { ViewMode } from "./ViewModeToggle";
 import { TreeView } from "./TreeView";
 import { FileContextMenu } from "./FileContextMenu";
 import { FileIcon } from "./FileIcon";
+import { GitStatusBadge, WorktreeStatusDot } from "./GitStatusBadge";
 
 const MIN_WIDTH = 180;
 const MAX_WIDTH = 480;
@@ -102,10 +103,12 @@ function FileItem({
           e.preventDefault();
           onFileSelect(file.id);
         }}
-        title={file.uploaded ? file.name : file.path}
+        title={file.url ?? (file.uploaded ? file.name : file.path)}
         aria-current={isActive ? "page" : undefined}
       >
         <FileIcon uploaded={file.uploaded} />
+        <GitStatusBadge status={file.gitStatus} />
+        {!file.gitStatus && <WorktreeStatusDot status={file.git?.status} />}
         <span className="overflow-hidden text-ellipsis whitespace-nowrap pr-6">
           {(showTitle && file.title) || file.name}
         </span>
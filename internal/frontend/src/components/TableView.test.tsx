@@ -0,0 +1,74 @@
+import { describe, it, expect, vi, beforeEach } from "vitest";
+import { render, screen, waitFor } from "@testing-library/react";
+import userEvent from "@testing-library/user-event";
+import { TableView } from "./TableView";
+import { fetchTablePage } from "../hooks/useApi";
+import type { TableData } from "../hooks/useApi";
+
+vi.mock("../hooks/useApi", () => ({
+  fetchTablePage: vi.fn(),
+}));
+
+const initial: TableData = {
+  delimiter: ",",
+  header: ["name", "score"],
+  columns: 2,
+  rows: [
+    ["bob", "7"],
+    ["alice", "12"],
+  ],
+  totalRows: 3,
+  offset: 0,
+  limit: 2,
+};
+
+beforeEach(() => {
+  vi.mocked(fetchTablePage).mockReset();
+});
+
+describe("TableView", () => {
+  it("renders the header and the first page", () => {
+    render(<TableView group="default" fileId="abc" revision={0} initial={initial} />);
+    expect(screen.getByRole("columnheader", { name: /name/ })).toBeInTheDocument();
+    expect(screen.getByText("alice")).toBeInTheDocument();
+    expect(screen.getByText("Rows 1–2 of 3")).toBeInTheDocument();
+    expect(fetchTablePage).not.toHaveBeenCalled();
+  });
+
+  it("numbers the columns of a table without a header", () => {
+    render(
+      <TableView group="default" fileId="abc" revision={0} initial={{ ...initial, header: undefined }} />,
+    );
+    expect(screen.getByRole("columnheader", { name: "1" })).toBeInTheDocument();
+    expect(screen.getByRole("columnheader", { name: "2" })).toBeInTheDocument();
+  });
+
+  it("sorts on the server when a header is clicked", async () => {
+    const user = userEvent.setup();
+    vi.mocked(fetchTablePage).mockResolvedValue({
+      ...initial,
+      rows: [
+        ["bob", "7"],
+        ["carol", "9"],
+      ],
+      sort: 1,
+    });
+    render(<TableView group="default" fileId="abc" revision={0} initial={initial} />);
+
+    await user.click(screen.getByRole("button", { name: "score" }));
+    expect(fetchTablePage).toHaveBeenCalledWith("default", "abc", { offset: 0, sort: 1, desc: false });
+    await waitFor(() => expect(screen.getByText("carol")).toBeInTheDocument());
+    expect(screen.getByRole("columnheader", { name: /score/ })).toHaveAttribute("aria-sort", "ascending");
+  });
+
+  it("fetches the next page", async () => {
+    const user = userEvent.setup();
+    vi.mocked(fetchTablePage).mockResolvedValue({ ...initial, rows: [["carol", "9"]], offset: 2 });
+    render(<TableView group="default" fileId="abc" revision={0} initial={initial} />);
+
+    await user.click(screen.getByRole("button", { name: "Next" }));
+    expect(fetchTablePage).toHaveBeenCalledWith("default", "abc", { offset: 2, sort: null, desc: false });
+    await waitFor(() => expect(screen.getByText("Rows 3–3 of 3")).toBeInTheDocument());
+    expect(screen.getByRole("button", { name: "Next" })).toBeDisabled();
+  });
+});
@@ -0,0 +1,109 @@
+import { useEffect, useState } from "react";
+import { fetchTablePage } from "../hooks/useApi";
+import type { TableData, TablePageQuery } from "../hooks/useApi";
+
+interface TableViewProps {
+  group: string;
+  fileId: string;
+  revision: number;
+  // The first, unsorted page served along with the file content.
+  initial: TableData;
+}
+
+const firstPage: TablePageQuery = { offset: 0, sort: null, desc: false };
+
+// Clicking a column header cycles ascending, descending and file order.
+function nextSort(query: TablePageQuery, col: number): TablePageQuery {
+  if (query.sort !== col) return { offset: 0, sort: col, desc: false };
+  if (!query.desc) return { offset: 0, sort: col, desc: true };
+  return firstPage;
+}
+
+export function TableView({ group, fileId, revision, initial }: TableViewProps) {
+  const [query, setQuery] = useState(firstPage);
+  const [page, setPage] = useState<TableData | null>(null);
+  const isFirstPage = query.offset === 0 && query.sort === null;
+
+  // Sorting and paging happen on the server. Other pages are refetched when
+  // the file changes so the view keeps its place.
+  useEffect(() => {
+    if (isFirstPage) return;
+    let cancelled = false;
+    fetchTablePage(group, fileId, query)
+      .then((data) => {
+        if (!cancelled) setPage(data);
+      })
+      .catch(() => {});
+    return () => {
+      cancelled = true;
+    };
+  }, [group, fileId, revision, query, isFirstPage]);
+
+  const table = isFirstPage ? initial : (page ?? initial);
+  const header = table.header ?? Array.from({ length: table.columns }, (_, i) => String(i + 1));
+  const first = table.rows.length > 0 ? table.offset + 1 : 0;
+  const last = table.offset + table.rows.length;
+
+  return (
+    <div>
+      <table>
+        <thead>
+          <tr>
+            {header.map((label, col) => {
+              const sorted = query.sort === col;
+              return (
+                <th
+                  key={col}
+                  aria-sort={sorted ? (query.desc ? "descending" : "ascending") : "none"}
+                  className="whitespace-nowrap"
+                >
+                  <button
+                    type="button"
+                    className="bg-transparent border-0 p-0 font-semibold text-inherit cursor-pointer"
+                    onClick={() => setQuery((q) => nextSort(q, col))}
+                    title="Sort by this column"
+                  >
+                    {label}
+                    {sorted && <span className="ml-1 text-gh-text-secondary">{query.desc ? "▼" : "▲"}</span>}
+                  </button>
+                </th>
+              );
+            })}
+          </tr>
+        </thead>
+        <tbody>
+          {table.rows.map((row, i) => (
+            <tr key={table.offset + i}>
+              {row.map((cell, col) => (
+                <td key={col}>{cell}</td>
+              ))}
+            </tr>
+          ))}
+        </tbody>
+      </table>
+      {table.totalRows > table.limit && (
+        <div className="flex items-center justify-end gap-2 text-sm text-gh-text-secondary">
+          <span>
+            Rows {first}–{last} of {table.totalRows}
+          </span>
+          <button
+            type="button"
+            className="bg-transparent border border-gh-border rounded-md px-2 py-1 text-gh-text-secondary cursor-pointer transition-colors duration-150 hover:bg-gh-bg-hover disabled:cursor-default disabled:opacity-50"
+            disabled={table.offset === 0}
+            onClick={() => setQuery((q) => ({ ...q, offset: Math.max(0, q.offset - table.limit) }))}
+          >
+            Previous
+          </button>
+          <button
+            type="button"
+            className="bg-transparent border border-gh-border rounded-md px-2 py-1 text-gh-text-secondary cursor-pointer transition-colors duration-150 hover:bg-gh-bg-hover disabled:cursor-default disabled:opacity-50"
+            disabled={last >= table.totalRows}
+            onClick={() => setQuery((q) => ({ ...q, offset: q.offset + table.limit }))}
+          >
+            Next
+          </button>
+        </div>
+      )}
+    </div>
+  );
+}
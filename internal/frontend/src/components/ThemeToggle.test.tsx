@@ -6,6 +6,7 @@ import { ThemeToggle } from "./ThemeToggle";
 beforeEach(() => {
   localStorage.clear();
   document.documentElement.removeAttribute("data-theme");
+  document.head.querySelectorAll('meta[name="mo-default-theme"]').forEach((m) => m.remove());
 });
 
 describe("ThemeToggle", () => {
@@ -20,6 +21,15 @@ describe("ThemeToggle", () => {
     expect(document.documentElement.getAttribute("data-theme")).toBe("dark");
   });
 
+  it("uses the configured default when no theme is saved", () => {
+    const meta = document.createElement("meta");
+    meta.name = "mo-default-theme";
+    meta.content = "dark";
+    document.head.appendChild(meta);
+    render(<ThemeToggle />);
+    expect(document.documentElement.getAttribute("data-theme")).toBe("dark");
+  });
+
   it("toggles from light to dark on click", async () => {
     const user = userEvent.setup();
     localStorage.setItem("mo-theme", "light");
@@ -1,10 +1,13 @@
 import { useEffect, useState } from "react";
+import { serverDefault } from "../utils/serverDefaults";
 
 type Theme = "light" | "dark";
 
 function getInitialTheme(): Theme {
   const stored = localStorage.getItem("mo-theme");
   if (stored === "light" || stored === "dark") return stored;
+  const configured = serverDefault("theme");
+  if (configured === "light" || configured === "dark") return configured;
   return window.matchMedia("(prefers-color-scheme: dark)").matches ? "dark" : "light";
 }
 
@@ -5,6 +5,7 @@ import { buildFileUrl } from "../utils/groups";
 import { isPlainLeftClick } from "../utils/linkClick";
 import { FileContextMenu } from "./FileContextMenu";
 import { FileIcon } from "./FileIcon";
+import { GitStatusBadge, WorktreeStatusDot } from "./GitStatusBadge";
 
 const COLLAPSED_STORAGE_KEY = "mo-sidebar-tree-collapsed";
 
@@ -286,10 +287,12 @@ function FileNodeItem({
           e.preventDefault();
           onFileSelect(file.id);
         }}
-        title={file.uploaded ? file.name : file.path}
+        title={file.url ?? (file.uploaded ? file.name : file.path)}
         aria-current={isActive ? "page" : undefined}
       >
         <FileIcon uploaded={file.uploaded} />
+        <GitStatusBadge status={file.gitStatus} />
+        {!file.gitStatus && <WorktreeStatusDot status={file.git?.status} />}
         <span className="overflow-hidden text-ellipsis whitespace-nowrap pr-6">
           {(showTitle && file.title) || name}
         </span>
@@ -2,6 +2,7 @@ import { describe, it, expect, vi, beforeEach } from "vitest";
 import {
   fetchGroups,
   fetchFileContent,
+  fetchTablePage,
   openRelativeFile,
   reorderFiles,
   moveFile,
@@ -72,6 +73,38 @@ describe("fetchFileContent", () => {
   });
 });
 
+describe("fetchTablePage", () => {
+  it("passes offset and sort as query parameters", async () => {
+    const table = { delimiter: ",", columns: 2, rows: [["a", "1"]], totalRows: 1, offset: 500, limit: 500 };
+    vi.stubGlobal(
+      "fetch",
+      vi.fn().mockResolvedValue({
+        ok: true,
+        json: () => Promise.resolve({ content: "", baseDir: "/tmp", table }),
+      }),
+    );
+
+    const result = await fetchTablePage("default", "abc12345", { offset: 500, sort: 1, desc: true });
+    expect(result).toEqual(table);
+    expect(fetch).toHaveBeenCalledWith(
+      "/_/api/groups/default/files/abc12345/content?offset=500&sort=1&desc=true",
+    );
+  });
+
+  it("omits sort when unsorted", async () => {
+    vi.stubGlobal(
+      "fetch",
+      vi.fn().mockResolvedValue({
+        ok: true,
+        json: () => Promise.resolve({ content: "", baseDir: "", table: { rows: [] } }),
+      }),
+    );
+
+    await fetchTablePage("default", "abc12345", { offset: 0, sort: null, desc: false });
+    expect(fetch).toHaveBeenCalledWith("/_/api/groups/default/files/abc12345/content?offset=0");
+  });
+});
+
 describe("openRelativeFile", () => {
   it("sends POST with correct body", async () => {
     const entry = { id: "eee55555", name: "other.md", path: "/other.md" };
@@ -1,9 +1,22 @@
+export interface GitInfo {
+  root: string;
+  status?: string;
+  commit?: string;
+  author?: string;
+  date?: string;
+}
+
 export interface FileEntry {
   name: string;
   id: string;
   path: string;
   title?: string;
   uploaded?: boolean;
+  // Set on entries fetched over HTTP(S).
+  url?: string;
+  revision?: string;
+  gitStatus?: string;
+  git?: GitInfo;
 }
 
 export interface Group {
@@ -12,9 +25,30 @@ export interface Group {
   patterns?: string[];
 }
 
+export interface TableData {
+  delimiter: string;
+  header?: string[];
+  columns: number;
+  rows: string[][];
+  totalRows: number;
+  offset: number;
+  limit: number;
+  sort?: number;
+  desc?: boolean;
+}
+
 export interface FileContent {
   content: string;
   baseDir: string;
+  // Set for CSV and TSV files; content is empty when the file is too big to
+  // send whole.
+  table?: TableData;
+}
+
+export interface TablePageQuery {
+  offset: number;
+  sort: number | null;
+  desc: boolean;
 }
 
 export interface VersionInfo {
@@ -55,6 +89,28 @@ export interface SearchResponse {
   results: SearchResult[];
 }
 
+export interface DiffLine {
+  op: "equal" | "insert" | "delete";
+  text: string;
+}
+
+export interface DiffHunk {
+  oldStart: number;
+  oldLines: number;
+  newStart: number;
+  newLines: number;
+  lines?: DiffLine[];
+}
+
+export interface WorktreeDiff {
+  id: string;
+  rev: string;
+  path: string;
+  exists: boolean;
+  hunks: DiffHunk[];
+  unified: string;
+}
+
 function groupPath(group: string): string {
   return `/_/api/groups/${encodeURIComponent(group)}`;
 }
@@ -71,6 +127,29 @@ export async function fetchFileContent(group: string, id: string): Promise<FileC
   return res.json();
 }
 
+export async function fetchTablePage(
+  group: string,
+  id: string,
+  query: TablePageQuery,
+): Promise<TableData> {
+  const params = new URLSearchParams({ offset: String(query.offset) });
+  if (query.sort !== null) {
+    params.set("sort", String(query.sort));
+    if (query.desc) params.set("desc", "true");
+  }
+  const res = await fetch(`${groupPath(group)}/files/${id}/content?${params.toString()}`);
+  if (!res.ok) throw new Error("Failed to fetch table page");
+  const data: FileContent = await res.json();
+  if (!data.table) throw new Error("Not a table");
+  return data.table;
+}
+
+export async function fetchWorktreeDiff(group: string, id: string): Promise<WorktreeDiff> {
+  const res = await fetch(`${groupPath(group)}/files/${id}/worktree-diff`);
+  if (!res.ok) throw new Error("Failed to fetch diff");
+  return res.json();
+}
+
 export async function openRelativeFile(
   group: string,
   fileId: string,
@@ -108,3 +108,23 @@ describe("useSSE started event", () => {
     expect(window.location.reload).toHaveBeenCalledOnce();
   });
 });
+
+describe("useSSE navigate event", () => {
+  it("passes the group, file and location", () => {
+    const onNavigate = vi.fn();
+    renderHook(() => useSSE({ onUpdate: vi.fn(), onNavigate }));
+
+    instances[0].emit("navigate", JSON.stringify({ group: "docs", id: "abc", line: 120 }));
+
+    expect(onNavigate).toHaveBeenCalledWith("docs", "abc", { anchor: undefined, line: 120 });
+  });
+
+  it("ignores malformed data", () => {
+    const onNavigate = vi.fn();
+    renderHook(() => useSSE({ onUpdate: vi.fn(), onNavigate }));
+
+    instances[0].emit("navigate", "not json");
+
+    expect(onNavigate).not.toHaveBeenCalled();
+  });
+});
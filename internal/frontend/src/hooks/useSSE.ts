@@ -1,8 +1,15 @@
 import { useEffect, useLayoutEffect, useRef } from "react";
+import type { DocLocation } from "../utils/location";
+
+export interface FileChange {
+  revision?: number;
+  firstChangedLine?: number;
+}
 
 interface SSECallbacks {
   onUpdate: () => void;
-  onFileChanged?: (fileId: string) => void;
+  onFileChanged?: (fileId: string, change: FileChange) => void;
+  onNavigate?: (group: string, fileId: string, location: DocLocation) => void;
 }
 
 export function useSSE(callbacks: SSECallbacks) {
@@ -44,7 +51,22 @@ export function useSSE(callbacks: SSECallbacks) {
       es.addEventListener("file-changed", (e) => {
         try {
           const data = JSON.parse(e.data);
-          callbacksRef.current.onFileChanged?.(data.id);
+          callbacksRef.current.onFileChanged?.(data.id, {
+            revision: data.revision,
+            firstChangedLine: data.firstChangedLine,
+          });
+        } catch {
+          // ignore malformed data
+        }
+      });
+
+      es.addEventListener("navigate", (e) => {
+        try {
+          const data = JSON.parse(e.data);
+          callbacksRef.current.onNavigate?.(data.group, data.id, {
+            anchor: data.anchor,
+            line: data.line,
+          });
         } catch {
           // ignore malformed data
         }
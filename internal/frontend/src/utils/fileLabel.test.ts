@@ -1,5 +1,5 @@
 import { describe, it, expect } from "vitest";
-import { formatFileLabel } from "./fileLabel";
+import { formatFileLabel, formatLastCommit, stripRevision } from "./fileLabel";
 
 describe("formatFileLabel", () => {
   it("returns the file name alone when title is undefined", () => {
@@ -15,3 +15,32 @@ describe("formatFileLabel", () => {
     expect(formatFileLabel("file.md", "   ")).toBe("file.md");
   });
 });
+
+describe("stripRevision", () => {
+  it("removes the revision suffix", () => {
+    expect(stripRevision("README.md@HEAD~3", "HEAD~3")).toBe("README.md");
+  });
+
+  it("keeps names without the suffix", () => {
+    expect(stripRevision("README.md", "HEAD~3")).toBe("README.md");
+    expect(stripRevision("notes@2024.md", undefined)).toBe("notes@2024.md");
+  });
+});
+
+describe("formatLastCommit", () => {
+  it("formats author, date and short hash", () => {
+    expect(
+      formatLastCommit({
+        root: "/repo",
+        commit: "1a2b3c4d5e6f",
+        author: "alice",
+        date: "2024-05-01T23:30:00+09:00",
+      }),
+    ).toBe("alice, 2024-05-01 (1a2b3c4)");
+  });
+
+  it("returns undefined for files never committed", () => {
+    expect(formatLastCommit({ root: "/repo", status: "untracked" })).toBeUndefined();
+    expect(formatLastCommit(undefined)).toBeUndefined();
+  });
+});
@@ -1,3 +1,5 @@
+import type { GitInfo } from "../hooks/useApi";
+
 // formatFileLabel builds the "Title - filename" label shared by the browser
 // tab title and the content-area title bar. Falls back to the file name alone
 // when the file has no usable title (undefined, empty, or whitespace-only, e.g.
@@ -5,3 +7,20 @@
 export function formatFileLabel(name: string, title?: string): string {
   return title && title.trim() !== "" ? `${title} - ${name}` : name;
 }
+
+// stripRevision removes the "@<revision>" suffix of entries read from a git
+// revision (e.g. "README.md@HEAD~3"), so the file type can be detected from
+// the underlying file name.
+export function stripRevision(name: string, revision?: string): string {
+  const suffix = `@${revision}`;
+  return revision && name.endsWith(suffix) ? name.slice(0, -suffix.length) : name;
+}
+
+// formatLastCommit describes who last committed a file, e.g.
+// "alice, 2024-05-01 (1a2b3c4)". The date is kept in the author's time zone
+// as git reports it. Returns undefined for files that were never committed.
+export function formatLastCommit(git?: GitInfo): string | undefined {
+  if (!git?.commit) return undefined;
+  const parts = [git.author, git.date?.slice(0, 10)].filter(Boolean).join(", ");
+  return `${parts} (${git.commit.slice(0, 7)})`;
+}
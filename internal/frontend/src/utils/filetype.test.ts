@@ -14,6 +14,15 @@ describe("isMarkdownFile", () => {
     expect(isMarkdownFile("notes.markdown")).toBe(true);
   });
 
+  it("returns true for Quarto, R Markdown and .mdc files", () => {
+    expect(isMarkdownFile("analysis.qmd")).toBe(true);
+    expect(isMarkdownFile("analysis.ipynb")).toBe(true);
+    expect(isMarkdownFile("runbook.org")).toBe(true);
+    expect(isMarkdownFile("runbook.adoc")).toBe(true);
+    expect(isMarkdownFile("report.Rmd")).toBe(true);
+    expect(isMarkdownFile("rules.mdc")).toBe(true);
+  });
+
   it("is case-insensitive", () => {
     expect(isMarkdownFile("README.MD")).toBe(true);
     expect(isMarkdownFile("file.Mdx")).toBe(true);
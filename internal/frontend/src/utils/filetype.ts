@@ -1,4 +1,19 @@
-const markdownExtensions = new Set(["md", "mdx", "markdown", "mdown", "mkdn", "mkd"]);
+const markdownExtensions = new Set([
+  "md",
+  "mdx",
+  "markdown",
+  "mdown",
+  "mkdn",
+  "mkd",
+  "mdc",
+  "qmd",
+  "rmd",
+  // Converted to Markdown by the server.
+  "ipynb",
+  "org",
+  "adoc",
+  "asciidoc",
+]);
 
 export function isMarkdownFile(fileName: string): boolean {
   const ext = fileName.split(".").pop()?.toLowerCase() ?? "";
@@ -67,6 +82,7 @@ const extToLang: Record<string, string> = {
   txt: "text",
   log: "text",
   csv: "csv",
+  tsv: "tsv",
   svg: "xml",
 };
 
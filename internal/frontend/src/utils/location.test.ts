@@ -0,0 +1,35 @@
+import { describe, it, expect } from "vitest";
+import { findAnchorTarget, parseLocationHash } from "./location";
+
+describe("parseLocationHash", () => {
+  it("parses line targets", () => {
+    expect(parseLocationHash("#L120")).toEqual({ line: 120 });
+    expect(parseLocationHash("#L0")).toBeNull();
+  });
+
+  it("parses heading anchors", () => {
+    expect(parseLocationHash("#installation")).toEqual({ anchor: "installation" });
+    expect(parseLocationHash("#%E6%A6%82%E8%A6%81")).toEqual({ anchor: "概要" });
+  });
+
+  it("returns null without a fragment", () => {
+    expect(parseLocationHash("")).toBeNull();
+    expect(parseLocationHash("#")).toBeNull();
+  });
+});
+
+describe("findAnchorTarget", () => {
+  it("prefers an exact id and falls back to a case-insensitive match", () => {
+    const root = document.createElement("div");
+    root.innerHTML = '<h2 id="setup">Setup</h2><h2 id="installation">Installation</h2>';
+    expect(findAnchorTarget(root, "installation")?.textContent).toBe("Installation");
+    expect(findAnchorTarget(root, "Setup")?.textContent).toBe("Setup");
+    expect(findAnchorTarget(root, "usage")).toBeNull();
+  });
+
+  it("finds ids prefixed by rehype-sanitize", () => {
+    const root = document.createElement("div");
+    root.innerHTML = '<a id="user-content-usage"></a>';
+    expect(findAnchorTarget(root, "usage")?.id).toBe("user-content-usage");
+  });
+});
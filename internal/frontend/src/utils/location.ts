@@ -0,0 +1,35 @@
+// A place in a document that a deeplink points at: a heading anchor
+// (#installation) or a source line (#L120).
+export interface DocLocation {
+  anchor?: string;
+  line?: number;
+}
+
+export function parseLocationHash(hash: string): DocLocation | null {
+  const fragment = hash.replace(/^#/, "");
+  if (fragment === "") return null;
+  const m = /^L(\d+)$/.exec(fragment);
+  if (m) {
+    const line = Number(m[1]);
+    return line > 0 ? { line } : null;
+  }
+  try {
+    return { anchor: decodeURIComponent(fragment) };
+  } catch {
+    return { anchor: fragment };
+  }
+}
+
+// Find the element a heading anchor names. Anchors are matched
+// case-insensitively, so #Installation finds the slug installation, and also
+// find ids in raw HTML, which rehype-sanitize prefixes with user-content-.
+export function findAnchorTarget(root: HTMLElement, anchor: string): HTMLElement | null {
+  const wanted = [anchor, `user-content-${anchor}`];
+  const lower = wanted.map((id) => id.toLowerCase());
+  let fallback: HTMLElement | null = null;
+  for (const el of root.querySelectorAll<HTMLElement>("[id]")) {
+    if (wanted.includes(el.id)) return el;
+    if (fallback == null && lower.includes(el.id.toLowerCase())) fallback = el;
+  }
+  return fallback;
+}
@@ -60,6 +60,24 @@ describe("resolveLink", () => {
     });
   });
 
+  it("returns markdown for .ipynb links", () => {
+    expect(resolveLink("notebooks/analysis.ipynb", "default", "e")).toEqual({
+      type: "markdown",
+      hrefPath: "notebooks/analysis.ipynb",
+    });
+  });
+
+  it("returns markdown for Org-mode and AsciiDoc links", () => {
+    expect(resolveLink("runbooks/restart.org", "default", "e")).toEqual({
+      type: "markdown",
+      hrefPath: "runbooks/restart.org",
+    });
+    expect(resolveLink("deploy.adoc#rollback", "default", "e")).toEqual({
+      type: "markdown",
+      hrefPath: "deploy.adoc",
+    });
+  });
+
   it("returns file for links with non-md extensions", () => {
     expect(resolveLink("image.png", "default", "g")).toEqual({
       type: "file",
@@ -68,9 +86,20 @@ describe("resolveLink", () => {
   });
 
   it("returns file and preserves anchor in rawUrl", () => {
-    expect(resolveLink("data.csv#sheet1", "default", "b")).toEqual({
+    expect(resolveLink("report.pdf#page=2", "default", "b")).toEqual({
       type: "file",
-      rawUrl: "/_/api/groups/default/files/b/raw/data.csv#sheet1",
+      rawUrl: "/_/api/groups/default/files/b/raw/report.pdf#page=2",
+    });
+  });
+
+  it("returns markdown for CSV and TSV links", () => {
+    expect(resolveLink("data/scores.csv", "default", "e")).toEqual({
+      type: "markdown",
+      hrefPath: "data/scores.csv",
+    });
+    expect(resolveLink("export.tsv", "default", "e")).toEqual({
+      type: "markdown",
+      hrefPath: "export.tsv",
     });
   });
 
@@ -110,6 +139,15 @@ describe("resolveImageSrc", () => {
     );
   });
 
+  it("appends the revision as a cache-busting query", () => {
+    expect(resolveImageSrc("image.png", "default", "c", 3)).toBe(
+      "/_/api/groups/default/files/c/raw/image.png?v=3",
+    );
+    expect(resolveImageSrc("image.svg?x=1", "default", "c", 3)).toBe(
+      "/_/api/groups/default/files/c/raw/image.svg?x=1&v=3",
+    );
+  });
+
   it("passes through http:// URLs", () => {
     expect(resolveImageSrc("http://example.com/img.png", "default", "a")).toBe(
       "http://example.com/img.png",
@@ -132,6 +170,12 @@ describe("resolveImageSrc", () => {
     expect(resolveImageSrc(src, "default", "a")).toBe(src);
   });
 
+  it("passes through converted document assets unchanged", () => {
+    expect(resolveImageSrc("/_/api/assets/0123456789abcdef.png", "default", "a", 3)).toBe(
+      "/_/api/assets/0123456789abcdef.png",
+    );
+  });
+
   it("does not pass through non-image data: URIs", () => {
     expect(resolveImageSrc("data:text/html,<script>alert(1)</script>", "default", "c")).toBe(
       "/_/api/groups/default/files/c/raw/data:text/html,<script>alert(1)</script>",
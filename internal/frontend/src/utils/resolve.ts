@@ -9,6 +9,10 @@ function rawBasePath(group: string, fileId: string): string {
   return `/_/api/groups/${encodeURIComponent(group)}/files/${fileId}/raw`;
 }
 
+// Links to these files open them in mo; the server converts the formats
+// other than Markdown, and serves CSV and TSV files as tables.
+const documentLinkExtensions = [".md", ".mdx", ".ipynb", ".org", ".adoc", ".asciidoc", ".csv", ".tsv"];
+
 export function resolveLink(
   href: string | undefined,
   group: string,
@@ -21,7 +25,7 @@ export function resolveLink(
     return { type: "hash" };
   }
   const hrefPath = href.split("#")[0];
-  if (hrefPath.endsWith(".md") || hrefPath.endsWith(".mdx")) {
+  if (documentLinkExtensions.some((ext) => hrefPath.endsWith(ext))) {
     return { type: "markdown", hrefPath };
   }
   const basename = hrefPath.split("/").pop() || "";
@@ -31,18 +35,30 @@ export function resolveLink(
   return { type: "passthrough" };
 }
 
+// revision is appended as a cache-busting query so that an image regenerated
+// on disk is fetched again when the document reloads.
 export function resolveImageSrc(
   src: string | undefined,
   group: string,
   fileId: string,
+  revision?: number,
 ): string | undefined {
   // Only data:image/ passes through: rehype-sanitize can whitelist the "data" scheme
   // but not the MIME type, so restrict it here as defense in depth.
   if (src && src.startsWith("data:image/")) {
     return src;
   }
+  // Images extracted from converted documents (e.g. notebook outputs) are
+  // served by the server from memory under content-addressed URLs.
+  if (src && src.startsWith("/_/api/assets/")) {
+    return src;
+  }
   if (src && !src.startsWith("http://") && !src.startsWith("https://")) {
-    return `${rawBasePath(group, fileId)}/${src}`;
+    const url = `${rawBasePath(group, fileId)}/${src}`;
+    if (!revision) {
+      return url;
+    }
+    return `${url}${url.includes("?") ? "&" : "?"}v=${revision}`;
   }
   return src;
 }
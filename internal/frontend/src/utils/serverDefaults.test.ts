@@ -0,0 +1,20 @@
+import { afterEach, describe, it, expect } from "vitest";
+import { serverDefault } from "./serverDefaults";
+
+describe("serverDefault", () => {
+  afterEach(() => {
+    document.head.querySelectorAll('meta[name^="mo-default-"]').forEach((m) => m.remove());
+  });
+
+  it("returns the content of the matching meta tag", () => {
+    const meta = document.createElement("meta");
+    meta.name = "mo-default-theme";
+    meta.content = "dark";
+    document.head.appendChild(meta);
+    expect(serverDefault("theme")).toBe("dark");
+  });
+
+  it("returns null without a meta tag", () => {
+    expect(serverDefault("font-size")).toBeNull();
+  });
+});
@@ -0,0 +1,7 @@
+// Defaults from the user's mo config file, set by the server as meta tags
+// on index.html. They apply when the browser has no saved setting.
+
+export function serverDefault(name: string): string | null {
+  const meta = document.querySelector<HTMLMetaElement>(`meta[name="mo-default-${name}"]`);
+  return meta?.content || null;
+}
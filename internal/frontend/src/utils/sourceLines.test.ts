@@ -0,0 +1,62 @@
+import { describe, it, expect } from "vitest";
+import { countLeadingLines, findBlockForLine, rehypeSourceLines } from "./sourceLines";
+
+describe("rehypeSourceLines", () => {
+  it("annotates top-level elements with offset source lines", () => {
+    const tree = {
+      type: "root",
+      children: [
+        {
+          type: "element",
+          tagName: "h1",
+          properties: {},
+          position: { start: { line: 1 }, end: { line: 1 } },
+        },
+        { type: "text", value: "\n" },
+        {
+          type: "element",
+          tagName: "p",
+          properties: { className: ["x"] },
+          position: { start: { line: 3 }, end: { line: 4 } },
+        },
+      ],
+    };
+    rehypeSourceLines(2)(tree);
+    expect(tree.children[0].properties).toEqual({ dataSourceLine: 3, dataSourceEndLine: 3 });
+    expect(tree.children[2].properties).toEqual({
+      className: ["x"],
+      dataSourceLine: 5,
+      dataSourceEndLine: 6,
+    });
+  });
+});
+
+describe("countLeadingLines", () => {
+  it("counts lines removed before the body", () => {
+    expect(countLeadingLines("---\na: 1\n---\n# Hi", "# Hi")).toBe(3);
+    expect(countLeadingLines("# Hi", "# Hi")).toBe(0);
+  });
+});
+
+describe("findBlockForLine", () => {
+  function setup() {
+    const root = document.createElement("div");
+    root.innerHTML =
+      '<h1 data-source-line="1" data-source-end-line="1">A</h1>' +
+      '<p data-source-line="3" data-source-end-line="5">B</p>' +
+      '<p data-source-line="7" data-source-end-line="7">C</p>';
+    return root;
+  }
+
+  it("finds the block containing the line", () => {
+    expect(findBlockForLine(setup(), 4)?.textContent).toBe("B");
+  });
+
+  it("falls forward to the next block from a blank line", () => {
+    expect(findBlockForLine(setup(), 6)?.textContent).toBe("C");
+  });
+
+  it("falls back to the last block past the end", () => {
+    expect(findBlockForLine(setup(), 20)?.textContent).toBe("C");
+  });
+});
@@ -0,0 +1,37 @@
+// Annotate top-level rendered blocks with the source lines they came from, so
+// the viewer can find the block containing a changed line after a reload.
+// lineOffset accounts for lines stripped before rendering (e.g. frontmatter).
+export function rehypeSourceLines(lineOffset = 0) {
+  // eslint-disable-next-line @typescript-eslint/no-explicit-any
+  return (tree: any) => {
+    for (const node of tree.children ?? []) {
+      if (node.type !== "element" || !node.position) continue;
+      node.properties = {
+        ...node.properties,
+        dataSourceLine: node.position.start.line + lineOffset,
+        dataSourceEndLine: node.position.end.line + lineOffset,
+      };
+    }
+  };
+}
+
+// Number of lines that precede body in raw, where body is a suffix of raw.
+export function countLeadingLines(raw: string, body: string): number {
+  const prefix = raw.slice(0, raw.length - body.length);
+  return prefix.split("\n").length - 1;
+}
+
+// Find the rendered block covering line, or the first block after it when the
+// line falls between blocks (e.g. on a blank line).
+export function findBlockForLine(root: HTMLElement, line: number): HTMLElement | null {
+  const blocks = root.querySelectorAll<HTMLElement>("[data-source-line]");
+  let last: HTMLElement | null = null;
+  for (const el of blocks) {
+    const end = Number(el.dataset.sourceEndLine ?? el.dataset.sourceLine);
+    if (line <= end) {
+      return el;
+    }
+    last = el;
+  }
+  return last;
+}
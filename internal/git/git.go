@@ -0,0 +1,333 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a path does not exist at a revision.
+var ErrNotFound = errors.New("path not found at revision")
+
+// run executes git with args in dir and returns its standard output. The
+// error includes git's standard error output.
+func run(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...) //nolint:gosec // Arguments are built by this package, not passed to a shell.
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return nil, fmt.Errorf("git %s: %w", args[0], err)
+		}
+		return nil, fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return stdout.Bytes(), nil
+}
+
+// IsCommitHash reports whether s is a full SHA-1 or SHA-256 commit hash.
+func IsCommitHash(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Root returns the top-level directory of the work tree containing dir.
+func Root(dir string) (string, error) {
+	out, err := run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return filepath.FromSlash(strings.TrimSpace(string(out))), nil
+}
+
+// ResolveCommit resolves rev (a branch, tag, hash or expression such as
+// HEAD~3) to a full commit hash.
+func ResolveCommit(root, rev string) (string, error) {
+	if rev == "" || strings.HasPrefix(rev, "-") {
+		return "", fmt.Errorf("invalid revision %q", rev)
+	}
+	out, err := run(root, "rev-parse", "--verify", "--quiet", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %q", rev)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Show returns the content of relPath (relative to root, slash-separated) at
+// commit. It returns ErrNotFound when the path does not exist there.
+func Show(root, commit, relPath string) ([]byte, error) {
+	obj := commit + ":" + relPath
+	if _, err := run(root, "cat-file", "-e", obj); err != nil {
+		return nil, fmt.Errorf("%s: %w", obj, ErrNotFound)
+	}
+	return run(root, "cat-file", "blob", obj)
+}
+
+// RelPath returns absPath relative to root in the slash-separated form git
+// uses for tree paths.
+func RelPath(root, absPath string) (string, error) {
+	// Resolve symlinks on both sides so that e.g. /var vs /private/var on
+	// macOS does not produce a "../" path.
+	if r, err := filepath.EvalSymlinks(root); err == nil {
+		root = r
+	}
+	dir, base := filepath.Split(absPath)
+	if d, err := filepath.EvalSymlinks(dir); err == nil {
+		absPath = filepath.Join(d, base)
+	}
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil {
+		return "", err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the repository %s", absPath, root)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Change states reported by ChangedFiles.
+const (
+	StatusAdded    = "added"
+	StatusModified = "modified"
+	StatusDeleted  = "deleted"
+)
+
+// Change is a file that differs between a commit and the work tree.
+type Change struct {
+	Path   string // slash-separated path relative to the repository root
+	Status string // StatusAdded, StatusModified or StatusDeleted
+}
+
+// DefaultBranch returns the branch reviews are compared against by default:
+// the remote's default branch when known, otherwise the first of
+// origin/main, origin/master, main and master that exists.
+func DefaultBranch(root string) (string, error) {
+	if out, err := run(root, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		if b := strings.TrimSpace(string(out)); b != "" {
+			return b, nil
+		}
+	}
+	for _, b := range []string{"origin/main", "origin/master", "main", "master"} {
+		if _, err := ResolveCommit(root, b); err == nil {
+			return b, nil
+		}
+	}
+	return "", errors.New("cannot determine the default branch; pass a base revision")
+}
+
+// MergeBase returns the best common ancestor of HEAD and base.
+func MergeBase(root, base string) (string, error) {
+	commit, err := ResolveCommit(root, base)
+	if err != nil {
+		return "", err
+	}
+	out, err := run(root, "merge-base", "HEAD", commit)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ChangedFiles lists the files in the work tree that differ from commit,
+// including untracked files that are not ignored.
+func ChangedFiles(root, commit string) ([]Change, error) {
+	out, err := run(root, "diff", "--name-status", "-z", "--no-renames", commit, "--")
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status := StatusModified
+		switch fields[i] {
+		case "A":
+			status = StatusAdded
+		case "D":
+			status = StatusDeleted
+		}
+		changes = append(changes, Change{Path: fields[i+1], Status: status})
+	}
+
+	out, err = run(root, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	for p := range strings.SplitSeq(string(out), "\x00") {
+		if p != "" {
+			changes = append(changes, Change{Path: p, Status: StatusAdded})
+		}
+	}
+	return changes, nil
+}
+
+// TrackedFiles lists the files in the index at root that match one of
+// pathspecs (e.g. "*.md", which matches in every directory), relative to root
+// and slash-separated.
+func TrackedFiles(root string, pathspecs ...string) ([]string, error) {
+	args := append([]string{"ls-files", "--cached", "-z", "--"}, pathspecs...)
+	out, err := run(root, args...)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for p := range strings.SplitSeq(string(out), "\x00") {
+		if p != "" {
+			files = append(files, p)
+		}
+	}
+	return files, nil
+}
+
+// Work-tree states reported by Status, in addition to StatusModified.
+const (
+	StatusStaged    = "staged"
+	StatusUntracked = "untracked"
+)
+
+// GitDir returns the absolute path of the git directory of the work tree at
+// root. For linked work trees this is the per-worktree directory.
+func GitDir(root string) (string, error) {
+	out, err := run(root, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", err
+	}
+	return filepath.FromSlash(strings.TrimSpace(string(out))), nil
+}
+
+// Status reports the work-tree state of relPaths (relative to root,
+// slash-separated): StatusModified for unstaged edits, StatusStaged when all
+// edits are staged and StatusUntracked for new files. Clean and ignored files
+// are absent from the result.
+func Status(root string, relPaths []string) (map[string]string, error) {
+	// --no-optional-locks keeps status from refreshing the index, which would
+	// otherwise show up as a change of the git directory to callers watching it.
+	args := []string{"--no-optional-locks", "--literal-pathspecs", "status", "--porcelain=v1", "-z", "--no-renames", "--untracked-files=all", "--"}
+	out, err := run(root, append(args, relPaths...)...)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string)
+	for e := range strings.SplitSeq(string(out), "\x00") {
+		if len(e) < 4 {
+			continue
+		}
+		x, y, p := e[0], e[1], e[3:]
+		switch {
+		case x == '?' && y == '?':
+			result[p] = StatusUntracked
+		case x == '!':
+		case y != ' ':
+			result[p] = StatusModified
+		default:
+			result[p] = StatusStaged
+		}
+	}
+	return result, nil
+}
+
+// Commit describes the last commit that touched a file.
+type Commit struct {
+	Hash   string
+	Author string
+	Date   time.Time
+}
+
+// LastCommits returns the most recent commit on HEAD that touched each of
+// relPaths, walking the history once and only as far as needed. Files that
+// have never been committed are left out.
+func LastCommits(root string, relPaths []string) (map[string]*Commit, error) {
+	commits := make(map[string]*Commit, len(relPaths))
+	if len(relPaths) == 0 {
+		return commits, nil
+	}
+	// A repository without commits has no HEAD to walk.
+	if _, err := run(root, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return commits, nil
+	}
+
+	want := make(map[string]struct{}, len(relPaths))
+	for _, p := range relPaths {
+		want[p] = struct{}{}
+	}
+	args := append([]string{"--literal-pathspecs", "log", "-z", "--name-only", "--format=%x1e%H%x00%an%x00%aI", "--"}, relPaths...)
+	cmd := exec.Command("git", args...) //nolint:gosec // Arguments are built by this package, not passed to a shell.
+	cmd.Dir = root
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("git log: %w", err)
+	}
+
+	// Each record is "hash\x00author\x00date\x00" followed by the names of
+	// the files it touched, each terminated by "\x00", and is complete once
+	// the separator of the next one (or EOF) is read.
+	r := bufio.NewReader(stdout)
+	var parseErr error
+	for len(want) > 0 && parseErr == nil {
+		rec, readErr := r.ReadString('\x1e')
+		rec = strings.TrimSuffix(rec, "\x1e")
+		if rec != "" {
+			parseErr = parseLogRecord(rec, want, commits)
+		}
+		if readErr != nil {
+			break
+		}
+	}
+	if len(want) == 0 || parseErr != nil {
+		// The rest of the history is not needed.
+		cmd.Process.Kill() //nolint:errcheck
+		cmd.Wait()         //nolint:errcheck
+		return commits, parseErr
+	}
+	if err := cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git log: %s", msg)
+		}
+		return nil, fmt.Errorf("git log: %w", err)
+	}
+	return commits, nil
+}
+
+// parseLogRecord records the commit of a LastCommits log record for each
+// file in want it touched, removing those files from want.
+func parseLogRecord(rec string, want map[string]struct{}, commits map[string]*Commit) error {
+	fields := strings.Split(rec, "\x00")
+	if len(fields) < 3 {
+		return fmt.Errorf("unexpected log record %q", rec)
+	}
+	var c *Commit
+	for _, name := range fields[3:] {
+		name = strings.TrimPrefix(name, "\n")
+		if _, ok := want[name]; !ok {
+			continue
+		}
+		if c == nil {
+			date, err := time.Parse(time.RFC3339, fields[2])
+			if err != nil {
+				return fmt.Errorf("unexpected commit date %q: %w", fields[2], err)
+			}
+			c = &Commit{Hash: fields[0], Author: fields[1], Date: date}
+		}
+		commits[name] = c
+		delete(want, name)
+	}
+	return nil
+}
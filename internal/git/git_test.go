@@ -0,0 +1,243 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// newTestRepo creates a repository with one commit per entry of revisions,
+// each writing the given files.
+func newTestRepo(t *testing.T, revisions ...map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	gitCmd := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=mo", "GIT_AUTHOR_EMAIL=mo@example.com",
+			"GIT_COMMITTER_NAME=mo", "GIT_COMMITTER_EMAIL=mo@example.com",
+			"GIT_CONFIG_GLOBAL=/dev/null",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	gitCmd("init", "-q", "-b", "main")
+	for i, files := range revisions {
+		for name, content := range files {
+			p := filepath.Join(dir, filepath.FromSlash(name))
+			os.MkdirAll(filepath.Dir(p), 0o700)     //nolint:errcheck
+			os.WriteFile(p, []byte(content), 0o600) //nolint:errcheck
+		}
+		gitCmd("add", "-A")
+		gitCmd("commit", "-q", "-m", fmt.Sprintf("revision %d", i+1))
+	}
+	return dir
+}
+
+func TestShow(t *testing.T) {
+	dir := newTestRepo(t,
+		map[string]string{"docs/README.md": "# v1"},
+		map[string]string{"docs/README.md": "# v2"},
+	)
+
+	root, err := Root(filepath.Join(dir, "docs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := ResolveCommit(root, "HEAD~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel, err := RelPath(root, filepath.Join(dir, "docs", "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel != "docs/README.md" {
+		t.Fatalf("got rel %q, want docs/README.md", rel)
+	}
+
+	got, err := Show(root, commit, rel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "# v1" {
+		t.Errorf("got %q, want %q", got, "# v1")
+	}
+
+	if _, err := Show(root, commit, "missing.md"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestResolveCommit_Invalid(t *testing.T) {
+	dir := newTestRepo(t, map[string]string{"a.md": "a"})
+	for _, rev := range []string{"", "no-such-branch", "--all"} {
+		if _, err := ResolveCommit(dir, rev); err == nil {
+			t.Errorf("ResolveCommit(%q) should fail", rev)
+		}
+	}
+}
+
+func TestRelPath_OutsideRepository(t *testing.T) {
+	dir := newTestRepo(t, map[string]string{"a.md": "a"})
+	if _, err := RelPath(dir, filepath.Join(filepath.Dir(dir), "other.md")); err == nil {
+		t.Fatal("expected error for a path outside the repository")
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	dir := newTestRepo(t, map[string]string{
+		"keep.md":   "keep",
+		"edit.md":   "before",
+		"remove.md": "remove",
+	})
+	base, err := ResolveCommit(dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "edit.md"), []byte("after"), 0o600)   //nolint:errcheck
+	os.Remove(filepath.Join(dir, "remove.md"))                            //nolint:errcheck
+	os.WriteFile(filepath.Join(dir, "new file.md"), []byte("new"), 0o600) //nolint:errcheck
+
+	changes, err := ChangedFiles(dir, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, c := range changes {
+		got[c.Path] = c.Status
+	}
+	want := map[string]string{
+		"edit.md":     StatusModified,
+		"remove.md":   StatusDeleted,
+		"new file.md": StatusAdded,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for p, s := range want {
+		if got[p] != s {
+			t.Errorf("%s: got %q, want %q", p, got[p], s)
+		}
+	}
+}
+
+func TestMergeBaseAndDefaultBranch(t *testing.T) {
+	dir := newTestRepo(t, map[string]string{"a.md": "a"})
+	mainCommit, err := ResolveCommit(dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(dir, "checkout", "-q", "-b", "feature"); err != nil {
+		t.Fatal(err)
+	}
+
+	branch, err := DefaultBranch(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch != "main" {
+		t.Errorf("got default branch %q, want main", branch)
+	}
+	mb, err := MergeBase(dir, branch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mb != mainCommit {
+		t.Errorf("got merge-base %q, want %q", mb, mainCommit)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	dir := newTestRepo(t, map[string]string{
+		"clean.md":    "# Clean",
+		"modified.md": "# Modified",
+		"staged.md":   "# Staged",
+		"with *.md":   "# Literal",
+	})
+	os.WriteFile(filepath.Join(dir, "modified.md"), []byte("# Edited"), 0o600) //nolint:errcheck
+	os.WriteFile(filepath.Join(dir, "staged.md"), []byte("# Edited"), 0o600)   //nolint:errcheck
+	os.WriteFile(filepath.Join(dir, "new.md"), []byte("# New"), 0o600)         //nolint:errcheck
+	cmd := exec.Command("git", "add", "staged.md")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git add: %v\n%s", err, out)
+	}
+
+	got, err := Status(dir, []string{"clean.md", "modified.md", "staged.md", "new.md", "with *.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"modified.md": StatusModified,
+		"staged.md":   StatusStaged,
+		"new.md":      StatusUntracked,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for p, s := range want {
+		if got[p] != s {
+			t.Errorf("%s: got %q, want %q", p, got[p], s)
+		}
+	}
+}
+
+func TestLastCommits(t *testing.T) {
+	dir := newTestRepo(t,
+		map[string]string{"a.md": "# A", "b.md": "# B", "with *.md": "# Literal"},
+		map[string]string{"b.md": "# B2", "docs/c.md": "# C"},
+	)
+
+	got, err := LastCommits(dir, []string{"a.md", "b.md", "docs/c.md", "with *.md", "untracked.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := ResolveCommit(dir, "HEAD~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := ResolveCommit(dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a.md": first, "with *.md": first, "b.md": head, "docs/c.md": head}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want commits for %v", got, want)
+	}
+	for p, hash := range want {
+		c := got[p]
+		if c == nil || c.Hash != hash || c.Author != "mo" || c.Date.IsZero() {
+			t.Errorf("%s: got %+v, want commit %s by mo", p, c, hash)
+		}
+	}
+
+	empty := newTestRepo(t)
+	if got, err := LastCommits(empty, []string{"a.md"}); err != nil || len(got) != 0 {
+		t.Errorf("got %v, %v; want none in a repository without commits", got, err)
+	}
+}
+
+func TestTrackedFiles(t *testing.T) {
+	dir := newTestRepo(t, map[string]string{
+		"a.md":          "# A",
+		"a.txt":         "A",
+		"docs/api/b.md": "# B",
+	})
+	os.WriteFile(filepath.Join(dir, "c.md"), []byte("# C"), 0o600) //nolint:errcheck
+
+	got, err := TrackedFiles(dir, "*.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a.md", "docs/api/b.md"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
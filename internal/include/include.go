@@ -0,0 +1,300 @@
+// Package include expands include directives in Markdown, so that documents
+// can share sections kept in separate files.
+//
+// A directive is either an HTML comment on a line of its own:
+//
+//	<!-- include: ../shared/warning.md -->
+//
+// or a fenced block named include listing one file per line:
+//
+//	```include
+//	../shared/warning.md
+//	../shared/support.md
+//	```
+//
+// A fenced code block with a file attribute is filled with an excerpt of a
+// source file, either a line range or a region between #region and
+// #endregion marker comments; the body given in the document is replaced:
+//
+//	```go file=../internal/server/server.go#L150-L154
+//	```
+//
+//	```go file=../internal/server/server.go#handlers
+//	```
+//
+// Paths are relative to the file containing the directive. Directives inside
+// other fenced code blocks are left as they are.
+package include
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/k1LoW/mo/internal/mdfence"
+)
+
+// MaxDepth is how deeply includes may nest.
+const MaxDepth = 8
+
+var commentRe = regexp.MustCompile(`^ {0,3}<!--\s*include:\s*(.+?)\s*-->\s*$`)
+
+// Expand replaces the include directives in content, the Markdown of the file
+// at path, with the content of the files they name, expanded in turn, and
+// fills excerpt blocks from their source files. read reads an included or
+// excerpted file. It returns the expanded Markdown and the paths of the files
+// read directly or indirectly. A directive that cannot be expanded (a missing
+// file or range, a cycle or nesting deeper than MaxDepth) is replaced by an
+// alert naming the problem.
+func Expand(path, content string, read func(path string) ([]byte, error)) (string, []string) {
+	if !strings.Contains(content, "include") && !strings.Contains(content, "file=") {
+		return content, nil
+	}
+	e := &expander{read: read, seen: make(map[string]struct{})}
+	return e.expand(content, []string{filepath.Clean(path)}), e.files
+}
+
+type expander struct {
+	read  func(path string) ([]byte, error)
+	files []string
+	seen  map[string]struct{}
+}
+
+// expand expands the directives in content, the Markdown of the last file of
+// stack.
+func (e *expander) expand(content string, stack []string) string {
+	var b strings.Builder
+	var fence mdfence.Fence // the open fenced block, if Len > 0
+	inInclude := false
+	var excerptOpen string // opening line of an excerpt block, without its file attribute
+	var excerptRef string
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if line == "" {
+			continue
+		}
+		switch {
+		case excerptRef != "":
+			if fence.Closes(line) {
+				b.WriteString(e.excerpt(excerptOpen, excerptRef, fence, stack))
+				excerptRef, fence = "", mdfence.Fence{}
+			}
+		case inInclude:
+			if fence.Closes(line) {
+				inInclude, fence = false, mdfence.Fence{}
+				continue
+			}
+			if target := strings.TrimSpace(line); target != "" {
+				b.WriteString(e.include(target, stack))
+			}
+		case fence.Len > 0:
+			b.WriteString(line)
+			if fence.Closes(line) {
+				fence = mdfence.Fence{}
+			}
+		default:
+			if f, ok := mdfence.Open(line); ok {
+				fence = f
+				if f.Lang() == "include" {
+					inInclude = true
+					continue
+				}
+				if open, ref, ok := cutFileAttr(line); ok {
+					excerptOpen, excerptRef = open, ref
+					continue
+				}
+				b.WriteString(line)
+				continue
+			}
+			if m := commentRe.FindStringSubmatch(strings.TrimRight(line, "\r\n")); m != nil {
+				b.WriteString(e.include(m[1], stack))
+				continue
+			}
+			b.WriteString(line)
+		}
+	}
+	if excerptRef != "" {
+		// An unclosed block runs to the end of the document.
+		b.WriteString(e.excerpt(excerptOpen, excerptRef, fence, stack))
+	}
+	return b.String()
+}
+
+// include returns the expanded content of target, named by a directive in
+// the last file of stack.
+func (e *expander) include(target string, stack []string) string {
+	p := target
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(filepath.Dir(stack[len(stack)-1]), p)
+	}
+	p = filepath.Clean(p)
+
+	for i, f := range stack {
+		if f == p {
+			names := make([]string, 0, len(stack)-i+1)
+			for _, f := range stack[i:] {
+				names = append(names, filepath.Base(f))
+			}
+			names = append(names, filepath.Base(p))
+			return alert(target, fmt.Errorf("include cycle %s", strings.Join(names, " → ")))
+		}
+	}
+	if len(stack) > MaxDepth {
+		return alert(target, fmt.Errorf("includes are nested more than %d deep", MaxDepth))
+	}
+
+	data, err := e.read(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return alert(target, errors.New("no such file"))
+		}
+		return alert(target, err)
+	}
+	if _, ok := e.seen[p]; !ok {
+		e.seen[p] = struct{}{}
+		e.files = append(e.files, p)
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return alert(target, errors.New("not a text file"))
+	}
+
+	expanded := e.expand(string(data), append(stack[:len(stack):len(stack)], p))
+	if expanded != "" && !strings.HasSuffix(expanded, "\n") {
+		expanded += "\n"
+	}
+	return expanded
+}
+
+// excerpt returns the code block opened by open, filled with the excerpt ref
+// names, read relative to the last file of stack.
+func (e *expander) excerpt(open, ref string, f mdfence.Fence, stack []string) string {
+	target, fragment, _ := strings.Cut(ref, "#")
+	p := target
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(filepath.Dir(stack[len(stack)-1]), p)
+	}
+	p = filepath.Clean(p)
+
+	data, err := e.read(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return excerptAlert(ref, errors.New("no such file"))
+		}
+		return excerptAlert(ref, err)
+	}
+	if _, ok := e.seen[p]; !ok {
+		e.seen[p] = struct{}{}
+		e.files = append(e.files, p)
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return excerptAlert(ref, errors.New("not a text file"))
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var body []string
+	switch {
+	case fragment == "":
+		body = lines
+	case lineRangeRe.MatchString(fragment):
+		body, err = lineRange(lines, fragment)
+	default:
+		body, err = region(lines, fragment)
+	}
+	if err != nil {
+		return excerptAlert(ref, err)
+	}
+
+	code := strings.Join(body, "")
+	if code != "" && !strings.HasSuffix(code, "\n") {
+		code += "\n"
+	}
+	// The fence must be longer than any fence in the excerpt.
+	fence := mdfence.For(code, f.Char, f.Len)
+	open = strings.TrimLeft(open, " ")
+	open = fence + strings.TrimLeft(open, string(f.Char))
+	return open + code + fence + "\n"
+}
+
+var (
+	lineRangeRe = regexp.MustCompile(`^L(\d+)(?:-L?(\d+))?$`)
+	regionRe    = regexp.MustCompile(`#region\s+(\S+)`)
+	endRegionRe = regexp.MustCompile(`#endregion\b`)
+	fileAttrRe  = regexp.MustCompile(`\s+file=("[^"]*"|\S+)`)
+)
+
+// lineRange returns the lines of a fragment such as L150-L154 or L42.
+func lineRange(lines []string, fragment string) ([]string, error) {
+	m := lineRangeRe.FindStringSubmatch(fragment)
+	start, _ := strconv.Atoi(m[1])
+	end := start
+	if m[2] != "" {
+		end, _ = strconv.Atoi(m[2])
+	}
+	if start < 1 || end < start {
+		return nil, fmt.Errorf("invalid line range %s", fragment)
+	}
+	if end > len(lines) {
+		return nil, fmt.Errorf("lines %d-%d are out of range: the file has %d lines", start, end, len(lines))
+	}
+	return lines[start-1 : end], nil
+}
+
+// region returns the lines between the #region name and matching #endregion
+// markers, excluding the markers and any nested markers.
+func region(lines []string, name string) ([]string, error) {
+	var body []string
+	depth := 0
+	for _, line := range lines {
+		if depth == 0 {
+			if m := regionRe.FindStringSubmatch(line); m != nil && m[1] == name {
+				depth = 1
+			}
+			continue
+		}
+		if regionRe.MatchString(line) {
+			depth++
+			continue
+		}
+		if endRegionRe.MatchString(line) {
+			depth--
+			if depth == 0 {
+				return body, nil
+			}
+			continue
+		}
+		body = append(body, line)
+	}
+	if depth > 0 {
+		return nil, fmt.Errorf("region %q has no #endregion", name)
+	}
+	return nil, fmt.Errorf("no region %q", name)
+}
+
+// cutFileAttr returns the opening fence line without its file attribute and
+// the attribute's value.
+func cutFileAttr(line string) (string, string, bool) {
+	loc := fileAttrRe.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return "", "", false
+	}
+	ref := strings.Trim(line[loc[2]:loc[3]], `"`)
+	if ref == "" {
+		return "", "", false
+	}
+	return line[:loc[0]] + line[loc[1]:], ref, true
+}
+
+func alert(target string, err error) string {
+	return fmt.Sprintf("> [!CAUTION]\n> Failed to include %s: %s\n", target, err)
+}
+
+func excerptAlert(ref string, err error) string {
+	return fmt.Sprintf("> [!CAUTION]\n> Failed to read excerpt %s: %s\n", ref, err)
+}
@@ -0,0 +1,139 @@
+package include
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func reader(files map[string]string) func(string) ([]byte, error) {
+	return func(p string) ([]byte, error) {
+		content, ok := files[filepath.ToSlash(p)]
+		if !ok {
+			return nil, fs.ErrNotExist
+		}
+		return []byte(content), nil
+	}
+}
+
+func TestExpand(t *testing.T) {
+	files := map[string]string{
+		"/handbook/shared/warning.md": "> [!WARNING]\n> Read this first.",
+		"/handbook/shared/support.md": "Ask in #support.\n<!-- include: footer.md -->\n",
+		"/handbook/shared/footer.md":  "-- The handbook team\n",
+		"/handbook/a.md":              "A\n<!-- include: b.md -->\n",
+		"/handbook/b.md":              "B\n<!-- include: a.md -->\n",
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		files   []string
+	}{
+		{
+			name:    "comment",
+			content: "# Page\n\n<!-- include: shared/warning.md -->\n\nBody\n",
+			want:    "# Page\n\n> [!WARNING]\n> Read this first.\n\nBody\n",
+			files:   []string{"/handbook/shared/warning.md"},
+		},
+		{
+			name:    "fenced block with nested include",
+			content: "# Page\n\n```include\nshared/warning.md\n\nshared/support.md\n```\nBody\n",
+			want:    "# Page\n\n> [!WARNING]\n> Read this first.\nAsk in #support.\n-- The handbook team\nBody\n",
+			files:   []string{"/handbook/shared/warning.md", "/handbook/shared/support.md", "/handbook/shared/footer.md"},
+		},
+		{
+			name:    "inside a code block",
+			content: "~~~markdown\n<!-- include: shared/warning.md -->\n~~~\n",
+			want:    "~~~markdown\n<!-- include: shared/warning.md -->\n~~~\n",
+		},
+		{
+			name:    "missing file",
+			content: "<!-- include: shared/missing.md -->\n",
+			want:    "> [!CAUTION]\n> Failed to include shared/missing.md: no such file\n",
+		},
+		{
+			name:    "cycle",
+			content: "<!-- include: a.md -->\n",
+			want:    "A\nB\n> [!CAUTION]\n> Failed to include a.md: include cycle a.md → b.md → a.md\n",
+			files:   []string{"/handbook/a.md", "/handbook/b.md"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, deps := Expand("/handbook/page.md", tt.content, reader(files))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			for i := range deps {
+				deps[i] = filepath.ToSlash(deps[i])
+			}
+			if !slices.Equal(deps, tt.files) {
+				t.Errorf("got files %v, want %v", deps, tt.files)
+			}
+		})
+	}
+}
+
+func TestExpand_MaxDepth(t *testing.T) {
+	files := make(map[string]string)
+	for i := range MaxDepth + 2 {
+		files["/docs/"+string(rune('a'+i))+".md"] = "<!-- include: " + string(rune('a'+i+1)) + ".md -->\n"
+	}
+	got, _ := Expand("/docs/page.md", "<!-- include: a.md -->\n", reader(files))
+	if !strings.Contains(got, "nested more than 8 deep") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestExpand_Excerpt(t *testing.T) {
+	files := map[string]string{
+		"/repo/main.go": "package main\n\n// #region hello\nfunc hello() {\n\t// #region inner\n\tprintln(\"```\")\n\t// #endregion\n}\n// #endregion\n",
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "line range",
+			content: "```go file=../main.go#L1-L2\nstale\n```\n",
+			want:    "```go\npackage main\n\n```\n",
+		},
+		{
+			name:    "single line",
+			content: "```go file=../main.go#L4 title=x\n```\n",
+			want:    "```go title=x\nfunc hello() {\n```\n",
+		},
+		{
+			name:    "region lengthens the fence",
+			content: "```go file=\"../main.go#hello\"\n```\n",
+			want:    "````go\nfunc hello() {\n\tprintln(\"```\")\n}\n````\n",
+		},
+		{
+			name:    "range out of bounds",
+			content: "```go file=../main.go#L8-L20\n```\n",
+			want:    "> [!CAUTION]\n> Failed to read excerpt ../main.go#L8-L20: lines 8-20 are out of range: the file has 9 lines\n",
+		},
+		{
+			name:    "missing region",
+			content: "```go file=../main.go#gone\n```\n",
+			want:    "> [!CAUTION]\n> Failed to read excerpt ../main.go#gone: no region \"gone\"\n",
+		},
+		{
+			name:    "missing file",
+			content: "```go file=../gone.go#L1\n```\n",
+			want:    "> [!CAUTION]\n> Failed to read excerpt ../gone.go#L1: no such file\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := Expand("/repo/docs/design.md", tt.content, reader(files))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
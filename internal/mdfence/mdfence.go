@@ -0,0 +1,72 @@
+// Package mdfence recognizes the lines that open and close fenced code
+// blocks in Markdown.
+package mdfence
+
+import "strings"
+
+// Fence is the opening line of a fenced code block.
+type Fence struct {
+	Char byte   // '`' or '~'
+	Len  int    // number of fence characters
+	Info string // info string, trimmed
+}
+
+// Lang returns the first word of the info string.
+func (f Fence) Lang() string {
+	lang, _, _ := strings.Cut(f.Info, " ")
+	return lang
+}
+
+// Open reports whether line opens a fenced code block.
+func Open(line string) (Fence, bool) {
+	s := strings.TrimRight(line, "\r\n")
+	trimmed := strings.TrimLeft(s, " ")
+	if len(s)-len(trimmed) > 3 || len(trimmed) < 3 {
+		return Fence{}, false
+	}
+	c := trimmed[0]
+	if c != '`' && c != '~' {
+		return Fence{}, false
+	}
+	n := 0
+	for n < len(trimmed) && trimmed[n] == c {
+		n++
+	}
+	if n < 3 {
+		return Fence{}, false
+	}
+	info := strings.TrimSpace(trimmed[n:])
+	if c == '`' && strings.Contains(info, "`") {
+		return Fence{}, false
+	}
+	return Fence{Char: c, Len: n, Info: info}, true
+}
+
+// Closes reports whether line closes the block f opened.
+func (f Fence) Closes(line string) bool {
+	s := strings.TrimRight(line, "\r\n")
+	trimmed := strings.TrimLeft(s, " ")
+	if len(s)-len(trimmed) > 3 {
+		return false
+	}
+	rest := strings.TrimRight(trimmed, " \t")
+	if len(rest) < f.Len {
+		return false
+	}
+	for i := 0; i < len(rest); i++ {
+		if rest[i] != f.Char {
+			return false
+		}
+	}
+	return true
+}
+
+// For returns a fence of at least n characters c that is longer than any run
+// of c in code, so that code can be put inside it.
+func For(code string, c byte, n int) string {
+	fence := strings.Repeat(string(c), n)
+	for strings.Contains(code, fence) {
+		fence += string(c)
+	}
+	return fence
+}
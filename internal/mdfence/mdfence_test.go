@@ -0,0 +1,50 @@
+package mdfence
+
+import "testing"
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		line string
+		want Fence
+		ok   bool
+	}{
+		{"```go title=x\n", Fence{Char: '`', Len: 3, Info: "go title=x"}, true},
+		{"   ~~~~\n", Fence{Char: '~', Len: 4}, true},
+		{"    ```\n", Fence{}, false},
+		{"``\n", Fence{}, false},
+		{"``` a`b\n", Fence{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := Open(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("Open(%q) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+	if f, _ := Open("```go title=x\n"); f.Lang() != "go" {
+		t.Errorf("got lang %q", f.Lang())
+	}
+}
+
+func TestCloses(t *testing.T) {
+	f := Fence{Char: '`', Len: 4}
+	for line, want := range map[string]bool{
+		"````\n":    true,
+		"`````  \n": true,
+		"```\n":     false,
+		"~~~~\n":    false,
+		"```` x\n":  false,
+	} {
+		if got := f.Closes(line); got != want {
+			t.Errorf("Closes(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
+
+func TestFor(t *testing.T) {
+	if got := For("fmt.Println()\n", '`', 3); got != "```" {
+		t.Errorf("got %q", got)
+	}
+	if got := For("```go\n````\n", '`', 3); got != "`````" {
+		t.Errorf("got %q", got)
+	}
+}
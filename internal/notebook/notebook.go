@@ -0,0 +1,242 @@
+package notebook
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Asset is an image embedded in a notebook, such as a plot output or a
+// Markdown cell attachment.
+type Asset struct {
+	Name        string // content-addressed file name, e.g. "3f2a….png"
+	ContentType string
+	Data        []byte
+}
+
+// Document is a notebook converted to Markdown.
+type Document struct {
+	Markdown string
+	Assets   []Asset
+}
+
+// text is a notebook string field, stored either as a string or as a list of
+// lines.
+type text string
+
+func (t *text) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = text(s)
+		return nil
+	}
+	var lines []string
+	if err := json.Unmarshal(b, &lines); err != nil {
+		return err
+	}
+	*t = text(strings.Join(lines, ""))
+	return nil
+}
+
+type notebookJSON struct {
+	NBFormat int    `json:"nbformat"`
+	Cells    []cell `json:"cells"`
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+type cell struct {
+	CellType    string                     `json:"cell_type"`
+	Source      text                       `json:"source"`
+	Outputs     []output                   `json:"outputs"`
+	Attachments map[string]map[string]text `json:"attachments"`
+}
+
+type output struct {
+	OutputType string          `json:"output_type"`
+	Text       text            `json:"text"`
+	Data       map[string]text `json:"data"`
+	EName      string          `json:"ename"`
+	EValue     string          `json:"evalue"`
+	Traceback  []string        `json:"traceback"`
+}
+
+// imageTypes lists the image MIME types rendered from outputs, in order of
+// preference, with the extension used for their asset names.
+var imageTypes = []struct{ mime, ext string }{
+	{"image/png", ".png"},
+	{"image/jpeg", ".jpg"},
+	{"image/gif", ".gif"},
+	{"image/svg+xml", ".svg"},
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// Convert converts a Jupyter notebook (nbformat 4) to Markdown. Markdown cells
+// are kept as-is, code cells become fenced blocks in the kernel's language,
+// and outputs are rendered as text blocks, HTML (e.g. DataFrame tables) or
+// images. Images are returned as assets and referenced through assetURL.
+func Convert(data []byte, assetURL func(name string) string) (*Document, error) {
+	var nb notebookJSON
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return nil, fmt.Errorf("invalid notebook: %w", err)
+	}
+	if nb.NBFormat != 4 {
+		return nil, fmt.Errorf("unsupported notebook format %d (only nbformat 4 is supported)", nb.NBFormat)
+	}
+	lang := nb.Metadata.Kernelspec.Language
+	if lang == "" {
+		lang = nb.Metadata.LanguageInfo.Name
+	}
+
+	c := &converter{assetURL: assetURL, seen: make(map[string]struct{})}
+	var blocks []string
+	for _, cl := range nb.Cells {
+		src := strings.TrimRight(string(cl.Source), "\n")
+		switch cl.CellType {
+		case "markdown":
+			if src != "" {
+				blocks = append(blocks, c.replaceAttachments(src, cl.Attachments))
+			}
+		case "code":
+			if src != "" {
+				blocks = append(blocks, fenced(lang, src))
+			}
+			for _, o := range cl.Outputs {
+				if b := c.renderOutput(o); b != "" {
+					blocks = append(blocks, b)
+				}
+			}
+		default: // "raw"
+			if src != "" {
+				blocks = append(blocks, fenced("text", src))
+			}
+		}
+	}
+
+	md := strings.Join(blocks, "\n\n")
+	if md != "" {
+		md += "\n"
+	}
+	return &Document{Markdown: md, Assets: c.assets}, nil
+}
+
+type converter struct {
+	assetURL func(string) string
+	assets   []Asset
+	seen     map[string]struct{}
+}
+
+// addAsset stores data as an asset and returns its URL.
+func (c *converter) addAsset(contentType, ext string, data []byte) string {
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:])[:16] + ext
+	if _, ok := c.seen[name]; !ok {
+		c.seen[name] = struct{}{}
+		c.assets = append(c.assets, Asset{Name: name, ContentType: contentType, Data: data})
+	}
+	return c.assetURL(name)
+}
+
+// image decodes an image MIME bundle entry: SVG is stored as text, the other
+// formats as base64.
+func (c *converter) image(mime, ext string, value text) (string, bool) {
+	if mime == "image/svg+xml" {
+		return c.addAsset(mime, ext, []byte(value)), true
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(value)), ""))
+	if err != nil {
+		return "", false
+	}
+	return c.addAsset(mime, ext, data), true
+}
+
+func (c *converter) renderOutput(o output) string {
+	switch o.OutputType {
+	case "stream":
+		return fenced("text", strings.TrimRight(ansiEscape.ReplaceAllString(string(o.Text), ""), "\n"))
+	case "error":
+		tb := ansiEscape.ReplaceAllString(strings.Join(o.Traceback, "\n"), "")
+		if tb == "" {
+			tb = o.EName + ": " + o.EValue
+		}
+		return fenced("text", strings.TrimRight(tb, "\n"))
+	case "execute_result", "display_data":
+		for _, it := range imageTypes {
+			if v, ok := o.Data[it.mime]; ok {
+				if url, ok := c.image(it.mime, it.ext, v); ok {
+					return fmt.Sprintf("![output](%s)", url)
+				}
+			}
+		}
+		if v, ok := o.Data["text/markdown"]; ok {
+			return strings.TrimRight(string(v), "\n")
+		}
+		if v, ok := o.Data["text/html"]; ok {
+			return htmlBlock(string(v))
+		}
+		if v, ok := o.Data["text/plain"]; ok {
+			return fenced("text", strings.TrimRight(string(v), "\n"))
+		}
+	}
+	return ""
+}
+
+// replaceAttachments points "attachment:name" references of a Markdown cell
+// at the stored assets.
+func (c *converter) replaceAttachments(src string, attachments map[string]map[string]text) string {
+	for name, bundle := range attachments {
+		for _, it := range imageTypes {
+			v, ok := bundle[it.mime]
+			if !ok {
+				continue
+			}
+			if url, ok := c.image(it.mime, it.ext, v); ok {
+				src = strings.ReplaceAll(src, "attachment:"+name, url)
+			}
+			break
+		}
+	}
+	return src
+}
+
+// fenced wraps content in a code fence longer than any backtick run inside it.
+func fenced(lang, content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + lang + "\n" + content + "\n" + fence
+}
+
+// htmlBlock makes an HTML output a single CommonMark HTML block: a blank line
+// would end the block and render the rest as Markdown.
+func htmlBlock(html string) string {
+	var b bytes.Buffer
+	b.WriteString("<div>\n")
+	for line := range strings.SplitSeq(html, "\n") {
+		if strings.TrimSpace(line) != "" {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	b.WriteString("</div>")
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package notebook
+
+import (
+	"strings"
+	"testing"
+)
+
+const testNotebook = `{
+  "nbformat": 4,
+  "nbformat_minor": 5,
+  "metadata": {"kernelspec": {"name": "python3", "language": "python"}},
+  "cells": [
+    {"cell_type": "markdown", "source": ["# Analysis\n", "\n", "![diagram](attachment:plot.png)"],
+     "attachments": {"plot.png": {"image/png": "iVBORw0KGgo="}}},
+    {"cell_type": "code", "source": "print(\"hi\")\n", "outputs": [
+      {"output_type": "stream", "name": "stdout", "text": ["hi\n"]},
+      {"output_type": "display_data", "data": {"image/png": "iVBORw0K\nGgo=\n", "text/plain": ["<Figure>"]}},
+      {"output_type": "execute_result", "data": {"text/html": ["<table>\n", "\n", "<tr><td>1</td></tr>\n", "</table>"], "text/plain": "df"}},
+      {"output_type": "display_data", "data": {"image/svg+xml": ["<svg xmlns=\"http://www.w3.org/2000/svg\"/>"]}},
+      {"output_type": "error", "ename": "ValueError", "evalue": "bad", "traceback": ["\u001b[0;31mValueError\u001b[0m: bad"]}
+    ]},
+    {"cell_type": "code", "source": "x = '''` + "```" + `'''", "outputs": [
+      {"output_type": "execute_result", "data": {"text/plain": ["42"]}}
+    ]},
+    {"cell_type": "raw", "source": "raw text"}
+  ]
+}`
+
+func TestConvert(t *testing.T) {
+	doc, err := Convert([]byte(testNotebook), func(name string) string { return "/assets/" + name })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The attachment and the display output carry the same PNG.
+	if len(doc.Assets) != 2 {
+		t.Fatalf("got %d assets, want 2 (PNG and SVG)", len(doc.Assets))
+	}
+	png, svg := doc.Assets[0], doc.Assets[1]
+	if png.ContentType != "image/png" || !strings.HasSuffix(png.Name, ".png") || string(png.Data[1:4]) != "PNG" {
+		t.Errorf("unexpected PNG asset: %s %s %q", png.Name, png.ContentType, png.Data)
+	}
+	if svg.ContentType != "image/svg+xml" || !strings.HasSuffix(svg.Name, ".svg") {
+		t.Errorf("unexpected SVG asset: %s %s", svg.Name, svg.ContentType)
+	}
+
+	for _, want := range []string{
+		"# Analysis\n\n![diagram](/assets/" + png.Name + ")",
+		"```python\nprint(\"hi\")\n```",
+		"```text\nhi\n```",
+		"![output](/assets/" + png.Name + ")",
+		"<div>\n<table>\n<tr><td>1</td></tr>\n</table>\n</div>",
+		"![output](/assets/" + svg.Name + ")",
+		"```text\nValueError: bad\n```",
+		"````python\nx = '''```'''\n````",
+		"```text\n42\n```",
+		"```text\nraw text\n```",
+	} {
+		if !strings.Contains(doc.Markdown, want) {
+			t.Errorf("markdown does not contain %q:\n%s", want, doc.Markdown)
+		}
+	}
+	if strings.Contains(doc.Markdown, "<Figure>") {
+		t.Error("text/plain should not be rendered when an image is available")
+	}
+}
+
+func TestConvert_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not json", `{"cells": [`},
+		{"nbformat 3", `{"nbformat": 3, "worksheets": []}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Convert([]byte(tt.data), func(string) string { return "" }); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
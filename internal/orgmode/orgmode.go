@@ -0,0 +1,505 @@
+package orgmode
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/niklasfasching/go-org/org"
+)
+
+// Convert converts an Org-mode document to Markdown. path is the document's
+// location, used to resolve #+INCLUDE files. The #+TITLE becomes the
+// top-level heading and headlines are shifted below it, as Org's own HTML
+// export does.
+func Convert(data []byte, path string) (string, error) {
+	d := org.New().Silent().Parse(bytes.NewReader(data), path)
+	w := &writer{}
+	out, err := d.Write(w)
+	if err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// writer renders org nodes as Markdown. Block nodes end with a blank line;
+// list items indent their content themselves.
+type writer struct {
+	strings.Builder
+	doc         *org.Document
+	levelOffset int
+	footnotes   []org.FootnoteDefinition
+}
+
+var emphasisMarkers = map[string][2]string{
+	"*":   {"**", "**"},
+	"/":   {"*", "*"},
+	"_":   {"<ins>", "</ins>"},
+	"+":   {"~~", "~~"},
+	"_{}": {"<sub>", "</sub>"},
+	"^{}": {"<sup>", "</sup>"},
+}
+
+func (w *writer) WriterWithExtensions() org.Writer { return w }
+
+func (w *writer) Before(d *org.Document) {
+	w.doc = d
+	if title := d.Get("TITLE"); title != "" && d.GetOption("title") != "nil" {
+		w.WriteString("# " + title + "\n\n")
+		w.levelOffset = 1
+	}
+}
+
+func (w *writer) After(d *org.Document) {
+	for _, f := range w.footnotes {
+		w.WriteFootnoteDefinition(f)
+	}
+}
+
+func (w *writer) String() string {
+	return strings.TrimRight(w.Builder.String(), "\n") + "\n"
+}
+
+func (w *writer) WriteNodesAsString(nodes ...org.Node) string {
+	b := w.Builder
+	w.Builder = strings.Builder{}
+	org.WriteNodes(w, nodes...)
+	out := w.Builder.String()
+	w.Builder = b
+	return out
+}
+
+// block writes s as a block followed by a blank line.
+func (w *writer) block(s string) {
+	if s = strings.TrimRight(s, "\n"); s != "" {
+		w.WriteString(s + "\n\n")
+	}
+}
+
+func (w *writer) WriteKeyword(org.Keyword) {}
+func (w *writer) WriteComment(org.Comment) {}
+
+func (w *writer) WriteInclude(i org.Include) {
+	if n := i.Resolve(); n != nil {
+		if _, ok := n.(org.Keyword); !ok {
+			org.WriteNodes(w, n)
+		}
+	}
+}
+
+func (w *writer) WriteNodeWithMeta(n org.NodeWithMeta) {
+	org.WriteNodes(w, n.Node)
+	for _, caption := range n.Meta.Caption {
+		w.block("*" + strings.TrimSpace(w.WriteNodesAsString(caption...)) + "*")
+	}
+}
+
+func (w *writer) WriteNodeWithName(n org.NodeWithName) { org.WriteNodes(w, n.Node) }
+
+func (w *writer) WriteHeadline(h org.Headline) {
+	if h.IsComment || hasTag(h.Tags, w.doc.Get("EXCLUDE_TAGS")) {
+		return
+	}
+	title := strings.TrimSpace(w.WriteNodesAsString(h.Title...))
+	if h.Status != "" && w.doc.GetOption("todo") != "nil" {
+		title = h.Status + " " + title
+	}
+	w.block(strings.Repeat("#", min(h.Lvl+w.levelOffset, 6)) + " " + title)
+	org.WriteNodes(w, h.Children...)
+}
+
+func hasTag(tags []string, exclude string) bool {
+	for _, t := range tags {
+		for _, e := range strings.Fields(exclude) {
+			if t == e {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (w *writer) WriteBlock(b org.Block) {
+	switch b.Name {
+	case "SRC":
+		lang := ""
+		if len(b.Parameters) > 0 {
+			lang = b.Parameters[0]
+		}
+		w.block(fenced(lang, w.rawContent(b.Children)))
+	case "EXAMPLE", "VERSE":
+		w.block(fenced("text", w.rawContent(b.Children)))
+	case "QUOTE":
+		w.block(quote(w.WriteNodesAsString(b.Children...)))
+	case "EXPORT":
+		// HTML and Markdown pass through; other back-ends are dropped.
+		if len(b.Parameters) > 0 && (strings.EqualFold(b.Parameters[0], "html") || strings.EqualFold(b.Parameters[0], "markdown")) {
+			w.block(w.rawContent(b.Children))
+		}
+	case "COMMENT":
+	default: // CENTER and special blocks
+		org.WriteNodes(w, b.Children...)
+	}
+	if b.Result != nil {
+		org.WriteNodes(w, b.Result)
+	}
+}
+
+// rawContent returns the literal text of a raw block.
+func (w *writer) rawContent(nodes []org.Node) string {
+	var sb strings.Builder
+	for _, n := range nodes {
+		if t, ok := n.(org.Text); ok {
+			sb.WriteString(t.Content)
+		} else {
+			sb.WriteString(org.String(n))
+		}
+	}
+	return strings.Trim(sb.String(), "\n")
+}
+
+func (w *writer) WriteResult(r org.Result) { org.WriteNodes(w, r.Node) }
+
+func (w *writer) WriteLatexBlock(b org.LatexBlock) {
+	w.block("$$\n" + strings.TrimSpace(w.rawContent(b.Content)) + "\n$$")
+}
+
+func (w *writer) WriteInlineBlock(b org.InlineBlock) {
+	content := w.rawContent(b.Children)
+	switch b.Name {
+	case "src":
+		w.WriteString(inlineCode(content))
+	case "export":
+		if len(b.Parameters) > 0 && strings.EqualFold(b.Parameters[0], "html") {
+			w.WriteString(content)
+		}
+	}
+}
+
+func (w *writer) WriteExample(e org.Example) {
+	lines := make([]string, 0, len(e.Children))
+	for _, n := range e.Children {
+		lines = append(lines, w.rawContent([]org.Node{n}))
+	}
+	w.block(fenced("text", strings.Join(lines, "\n")))
+}
+
+func (w *writer) WriteDrawer(org.Drawer)                 {}
+func (w *writer) WritePropertyDrawer(org.PropertyDrawer) {}
+
+func (w *writer) WriteList(l org.List) {
+	var items []string
+	for i, item := range l.Items {
+		var marker string
+		var children []org.Node
+		switch item := item.(type) {
+		case org.ListItem:
+			marker = "-"
+			if l.Kind == "ordered" {
+				marker = fmt.Sprintf("%d.", i+1)
+			}
+			switch item.Status {
+			case "X":
+				marker += " [x]"
+			case " ", "-":
+				marker += " [ ]"
+			}
+			children = item.Children
+		case org.DescriptiveListItem:
+			marker = "- **" + strings.TrimSpace(w.WriteNodesAsString(item.Term...)) + "**:"
+			children = item.Details
+		}
+		items = append(items, listItem(marker, w.itemContent(children)))
+	}
+	w.block(strings.Join(items, "\n"))
+}
+
+// itemContent renders the children of a list item. A paragraph directly
+// followed by a nested list is kept tight so that the list is not loose.
+func (w *writer) itemContent(children []org.Node) string {
+	var sb strings.Builder
+	for i, n := range children {
+		s := strings.TrimRight(w.WriteNodesAsString(n), "\n")
+		if s == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			if _, isList := n.(org.List); isList {
+				if _, isPara := children[i-1].(org.Paragraph); isPara {
+					sb.WriteString("\n")
+				} else {
+					sb.WriteString("\n\n")
+				}
+			} else {
+				sb.WriteString("\n\n")
+			}
+		}
+		sb.WriteString(s)
+	}
+	return sb.String()
+}
+
+// listItem prefixes content with marker and indents its continuation lines
+// to the content column.
+func listItem(marker, content string) string {
+	bullet := marker
+	if i := strings.IndexByte(marker, ' '); i >= 0 {
+		bullet = marker[:i]
+	}
+	indent := strings.Repeat(" ", len(bullet)+1)
+	lines := strings.Split(content, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = indent + lines[i]
+		}
+	}
+	return marker + " " + strings.Join(lines, "\n")
+}
+
+// WriteListItem and WriteDescriptiveListItem are only reached for items
+// outside a list; WriteList renders items itself to number them.
+func (w *writer) WriteListItem(li org.ListItem) {
+	w.WriteString(listItem("-", w.itemContent(li.Children)))
+}
+
+func (w *writer) WriteDescriptiveListItem(di org.DescriptiveListItem) {
+	w.WriteString(listItem("- **"+strings.TrimSpace(w.WriteNodesAsString(di.Term...))+"**:", w.itemContent(di.Details)))
+}
+
+func (w *writer) WriteTable(t org.Table) {
+	var rows [][]string
+	for _, r := range t.Rows {
+		if len(r.Columns) == 0 || r.IsSpecial {
+			continue
+		}
+		cells := make([]string, len(t.ColumnInfos))
+		for i, c := range r.Columns {
+			if i < len(cells) {
+				cells[i] = strings.ReplaceAll(strings.TrimSpace(w.WriteNodesAsString(c.Children...)), "|", `\|`)
+			}
+		}
+		rows = append(rows, cells)
+	}
+	if len(rows) == 0 {
+		return
+	}
+	var sb strings.Builder
+	writeRow := func(cells []string) {
+		sb.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	writeRow(rows[0])
+	aligns := make([]string, len(t.ColumnInfos))
+	for i, ci := range t.ColumnInfos {
+		switch ci.Align {
+		case "center":
+			aligns[i] = ":---:"
+		case "right":
+			aligns[i] = "---:"
+		default:
+			aligns[i] = "---"
+		}
+	}
+	writeRow(aligns)
+	for _, r := range rows[1:] {
+		writeRow(r)
+	}
+	w.block(sb.String())
+}
+
+func (w *writer) WriteHorizontalRule(org.HorizontalRule) { w.block("---") }
+
+func (w *writer) WriteParagraph(p org.Paragraph) {
+	w.block(strings.TrimSpace(w.WriteNodesAsString(p.Children...)))
+}
+
+func (w *writer) WriteText(t org.Text) {
+	if t.IsRaw {
+		w.WriteString(t.Content)
+		return
+	}
+	w.WriteString(markdownEscaper.Replace(t.Content))
+}
+
+// markdownEscaper escapes the characters that would otherwise start
+// Markdown or HTML syntax inside org text.
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`", "*", `\*`, "<", `\<`, "[", `\[`)
+
+func (w *writer) WriteEmphasis(e org.Emphasis) {
+	switch e.Kind {
+	case "~", "=":
+		w.WriteString(inlineCode(w.rawContent(e.Content)))
+		return
+	}
+	m := emphasisMarkers[e.Kind]
+	w.WriteString(m[0])
+	org.WriteNodes(w, e.Content...)
+	w.WriteString(m[1])
+}
+
+func (w *writer) WriteLatexFragment(l org.LatexFragment) {
+	content := w.rawContent(l.Content)
+	switch l.OpeningPair {
+	case `\[`, "$$":
+		w.WriteString("$$" + content + "$$")
+	case `\(`, "$":
+		w.WriteString("$" + content + "$")
+	default: // \begin{...}
+		w.WriteString("\n$$\n" + l.OpeningPair + content + l.ClosingPair + "\n$$\n")
+	}
+}
+
+func (w *writer) WriteStatisticToken(s org.StatisticToken) { w.WriteString("[" + s.Content + "]") }
+func (w *writer) WriteExplicitLineBreak(org.ExplicitLineBreak) {
+	w.WriteString("\\\n")
+}
+
+func (w *writer) WriteLineBreak(l org.LineBreak) {
+	if l.BetweenMultibyteCharacters {
+		return
+	}
+	w.WriteString(strings.Repeat("\n", l.Count))
+}
+
+func (w *writer) WriteRegularLink(l org.RegularLink) {
+	url := l.URL
+	if l.Protocol == "file" {
+		url = strings.TrimPrefix(url, "file:")
+	} else if l.Protocol == "" && strings.HasPrefix(url, "*") {
+		// [[*Heading]] links to a headline of this document.
+		url = "#" + slug(strings.TrimPrefix(url, "*"))
+	}
+	if prefix := w.doc.Links[l.Protocol]; prefix != "" {
+		tag := strings.TrimPrefix(l.URL, l.Protocol+":")
+		if strings.Contains(prefix, "%s") {
+			url = strings.ReplaceAll(prefix, "%s", tag)
+		} else {
+			url = prefix + tag
+		}
+	}
+	url = strings.ReplaceAll(url, " ", "%20")
+
+	if l.Kind() == "image" {
+		if l.Description == nil {
+			w.WriteString(fmt.Sprintf("![](%s)", url))
+		} else {
+			src := strings.TrimPrefix(org.String(l.Description...), "file:")
+			w.WriteString(fmt.Sprintf("[![](%s)](%s)", src, url))
+		}
+		return
+	}
+	if l.Description == nil {
+		if l.AutoLink {
+			w.WriteString(url)
+		} else {
+			w.WriteString(fmt.Sprintf("[%s](%s)", markdownEscaper.Replace(l.URL), url))
+		}
+		return
+	}
+	w.WriteString(fmt.Sprintf("[%s](%s)", w.WriteNodesAsString(l.Description...), url))
+}
+
+// slug approximates the heading IDs the viewer generates (GitHub style).
+func slug(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		switch {
+		case r == ' ':
+			sb.WriteRune('-')
+		case r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r > 127:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func (w *writer) WriteMacro(m org.Macro) {
+	macro := w.doc.Macros[m.Name]
+	for i, p := range m.Parameters {
+		macro = strings.ReplaceAll(macro, fmt.Sprintf("$%d", i+1), p)
+	}
+	w.WriteString(macro)
+}
+
+func (w *writer) WriteTimestamp(t org.Timestamp) {
+	if w.doc.GetOption("<") == "nil" {
+		return
+	}
+	layout := "2006-01-02 Mon 15:04"
+	if t.IsDate {
+		layout = "2006-01-02 Mon"
+	}
+	s := t.Time.Format(layout)
+	if t.Interval != "" {
+		s += " " + t.Interval
+	}
+	w.WriteString(inlineCode(s))
+}
+
+func (w *writer) WriteFootnoteLink(l org.FootnoteLink) {
+	if w.doc.GetOption("f") == "nil" {
+		return
+	}
+	name := l.Name
+	if l.Definition != nil {
+		if name == "" {
+			name = fmt.Sprintf("inline-%d", len(w.footnotes)+1)
+		}
+		def := *l.Definition
+		def.Name = name
+		w.footnotes = append(w.footnotes, def)
+	}
+	w.WriteString("[^" + name + "]")
+}
+
+func (w *writer) WriteFootnoteDefinition(f org.FootnoteDefinition) {
+	if w.doc.GetOption("f") == "nil" {
+		return
+	}
+	content := strings.TrimSpace(w.itemContent(f.Children))
+	lines := strings.Split(content, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = "    " + lines[i]
+		}
+	}
+	w.block("[^" + f.Name + "]: " + strings.Join(lines, "\n"))
+}
+
+// fenced wraps content in a code fence longer than any backtick run inside it.
+func fenced(lang, content string) string {
+	fence := strings.Repeat("`", max(3, longestRun(content, '`')+1))
+	return fence + lang + "\n" + content + "\n" + fence
+}
+
+func inlineCode(content string) string {
+	ticks := strings.Repeat("`", longestRun(content, '`')+1)
+	if strings.HasPrefix(content, "`") || strings.HasSuffix(content, "`") {
+		return ticks + " " + content + " " + ticks
+	}
+	return ticks + content + ticks
+}
+
+func longestRun(s string, c rune) int {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == c {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
+
+func quote(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, l := range lines {
+		if l == "" {
+			lines[i] = ">"
+		} else {
+			lines[i] = "> " + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,91 @@
+package orgmode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			"title shifts headlines",
+			"#+TITLE: Runbook\n\n* Restart\n** Checklist\n",
+			"# Runbook\n\n## Restart\n\n### Checklist\n",
+		},
+		{
+			"headlines without title",
+			"* TODO Restart :ops:\n",
+			"# TODO Restart\n",
+		},
+		{
+			"emphasis",
+			"*bold* /italic/ =verbatim= ~code~ +strike+\n",
+			"**bold** *italic* `verbatim` `code` ~~strike~~\n",
+		},
+		{
+			"src block",
+			"#+BEGIN_SRC bash\nsystemctl restart app\n#+END_SRC\n",
+			"```bash\nsystemctl restart app\n```\n",
+		},
+		{
+			"lists",
+			"- [X] drain\n- [ ] restart\n  1. first\n  2. second\n",
+			"- [x] drain\n- [ ] restart\n  1. first\n  2. second\n",
+		},
+		{
+			"descriptive list",
+			"- CPU :: 2 cores\n",
+			"- **CPU**: 2 cores\n",
+		},
+		{
+			"table",
+			"| Host | Port |\n|------+------|\n| a | 80 |\n",
+			"| Host | Port |\n| --- | ---: |\n| a | 80 |\n",
+		},
+		{
+			"links",
+			"[[https://example.com][docs]], [[file:other.org][other]] and [[./img.png]]\n",
+			"[docs](https://example.com), [other](other.org) and ![](./img.png)\n",
+		},
+		{
+			"comments and commented headlines are dropped",
+			"# note\n* COMMENT hidden\nsecret\n* Shown\n",
+			"# Shown\n",
+		},
+		{
+			"footnotes",
+			"Text[fn:1].\n\n[fn:1] The note.\n",
+			"Text[^1].\n\n[^1]: The note.\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Convert([]byte(tt.in), "test.org")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvert_Include(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "snippet.sh"), []byte("echo hi\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Convert([]byte("#+INCLUDE: \"snippet.sh\" src bash\n"), filepath.Join(dir, "doc.org"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "```bash\necho hi\n```\n"; got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
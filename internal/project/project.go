@@ -0,0 +1,120 @@
+// Package project reads .mo.yaml, a file kept at the root of a repository
+// that declares the groups mo opens there, so that a plain mo sets up the
+// same session for everyone working on it:
+//
+//	ignore: [node_modules]
+//	groups:
+//	  - name: docs
+//	    files: [README.md, docs/getting-started.md]
+//	    watch: ["docs/**/*.md"]
+//	    ignore: [docs/archive]
+//	  - name: design
+//	    watch: [design/]
+//
+// Paths are relative to the directory of the file. Files open in the order
+// they are listed, followed by the files matched by watch patterns.
+package project
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the project file.
+const FileName = ".mo.yaml"
+
+// Project is a parsed project file.
+type Project struct {
+	// Dir is the directory containing the file, which paths are relative to.
+	Dir string `yaml:"-"`
+
+	// Ignore holds ignore patterns applied to every group.
+	Ignore []string `yaml:"ignore"`
+	Groups []Group  `yaml:"groups"`
+}
+
+// Group is a group declared by the project file.
+type Group struct {
+	Name string `yaml:"name"`
+	// Files are opened in order; globs and directories are expanded once.
+	Files []string `yaml:"files"`
+	// Watch holds globs and directories registered as watch patterns.
+	Watch []string `yaml:"watch"`
+	// Ignore holds ignore patterns applied to this group only.
+	Ignore []string `yaml:"ignore"`
+}
+
+// Find returns the path of the project file in dir or the closest parent
+// directory, stopping at the root of the repository dir is in. It returns an
+// empty path when there is none.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		p := filepath.Join(dir, FileName)
+		if fi, err := os.Stat(p); err == nil && fi.Mode().IsRegular() {
+			return p, nil
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads the project file at p.
+func Load(p string) (*Project, error) {
+	data, err := os.ReadFile(p) //nolint:gosec // A project file found by Find
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project file: %w", err)
+	}
+	var proj Project
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&proj); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid project file %s: %w", p, err)
+	}
+	if err := proj.validate(); err != nil {
+		return nil, fmt.Errorf("invalid project file %s: %w", p, err)
+	}
+	proj.Dir = filepath.Dir(p)
+	return &proj, nil
+}
+
+func (p *Project) validate() error {
+	if len(p.Groups) == 0 {
+		return errors.New("no groups")
+	}
+	seen := make(map[string]struct{}, len(p.Groups))
+	for i, g := range p.Groups {
+		if g.Name == "" {
+			return fmt.Errorf("group %d has no name", i+1)
+		}
+		if _, ok := seen[g.Name]; ok {
+			return fmt.Errorf("group %q is declared twice", g.Name)
+		}
+		seen[g.Name] = struct{}{}
+		if len(g.Files) == 0 && len(g.Watch) == 0 {
+			return fmt.Errorf("group %q has no files or watch patterns", g.Name)
+		}
+		for _, pat := range append(append(append([]string{}, p.Ignore...), g.Ignore...), g.Watch...) {
+			if !doublestar.ValidatePattern(filepath.ToSlash(pat)) {
+				return fmt.Errorf("invalid pattern %q in group %q", pat, g.Name)
+			}
+		}
+	}
+	return nil
+}
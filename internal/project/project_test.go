@@ -0,0 +1,84 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	sub := filepath.Join(repo, "docs", "guide")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// Outside the repository: never found from inside it.
+	if err := os.WriteFile(filepath.Join(root, FileName), []byte("groups: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Find(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "" {
+		t.Errorf("got %q, want none", got)
+	}
+
+	want := filepath.Join(repo, FileName)
+	if err := os.WriteFile(want, []byte("groups: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err = Find(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, FileName)
+	if err := os.WriteFile(p, []byte(`ignore: [node_modules]
+groups:
+  - name: docs
+    files: [README.md]
+    watch: ["docs/**/*.md"]
+    ignore: [docs/archive]
+  - name: design
+    watch: [design/]
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	proj, err := Load(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proj.Dir != dir || len(proj.Groups) != 2 || proj.Groups[0].Name != "docs" || proj.Groups[0].Files[0] != "README.md" ||
+		proj.Groups[0].Ignore[0] != "docs/archive" || proj.Groups[1].Watch[0] != "design/" || proj.Ignore[0] != "node_modules" {
+		t.Errorf("unexpected project %+v", proj)
+	}
+
+	invalid := []string{
+		"",
+		"groups:\n  - files: [a.md]\n",
+		"groups:\n  - name: a\n",
+		"groups:\n  - name: a\n    files: [a.md]\n  - name: a\n    files: [b.md]\n",
+		"groups:\n  - name: a\n    flies: [a.md]\n",
+		"groups:\n  - name: a\n    watch: ['[']\n",
+	}
+	for _, content := range invalid {
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(p); err == nil {
+			t.Errorf("want an error for %q", content)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Package registry records the mo servers running on this machine, one file
+// per port, so that they can be found without probing every port.
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k1LoW/mo/internal/atomicfile"
+	"github.com/k1LoW/mo/internal/xdg"
+)
+
+// Entry is a running mo server.
+type Entry struct {
+	PID       int       `json:"pid"`
+	Port      int       `json:"port"`
+	Bind      string    `json:"bind"`
+	StartedAt time.Time `json:"startedAt"`
+	Version   string    `json:"version"`
+}
+
+// Dir returns the path to the registry directory.
+func Dir() (string, error) {
+	stateHome, err := xdg.StateHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateHome, "mo", "servers"), nil
+}
+
+// Path returns the registry file path for the given port.
+func Path(port int) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("mo-%d.json", port)), nil
+}
+
+// Register atomically records e, replacing any entry for the same port.
+func Register(e Entry) error {
+	p, err := Path(e.Port)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(p)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create registry directory: %w", err)
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal registry entry: %w", err)
+	}
+
+	return atomicfile.Write(p, "mo-server-*.tmp", b)
+}
+
+// Unregister removes the entry for port if it was recorded by pid, so that a
+// server exiting late does not remove the entry of its successor.
+// Returns nil if there is no such entry.
+func Unregister(port, pid int) error {
+	p, err := Path(port)
+	if err != nil {
+		return err
+	}
+	e, err := read(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if e.PID != pid {
+		return nil
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove registry entry: %w", err)
+	}
+	return nil
+}
+
+// List returns the recorded servers sorted by port. Entries whose process is
+// no longer alive, and unreadable ones, are removed.
+func List(alive func(pid int) bool) ([]Entry, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "mo-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, p := range paths {
+		raw := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(p), "mo-"), ".json")
+		if _, err := strconv.Atoi(raw); err != nil {
+			continue
+		}
+		e, err := read(p)
+		if err != nil && os.IsNotExist(err) {
+			continue
+		}
+		if err != nil || !alive(e.PID) {
+			slog.Info("removing stale registry entry", "path", p, "pid", e.PID)
+			if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to remove registry entry: %w", err)
+			}
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Port < entries[j].Port })
+	return entries, nil
+}
+
+func read(p string) (Entry, error) {
+	var e Entry
+	data, err := os.ReadFile(p) //nolint:gosec
+	if err != nil {
+		return e, err
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return e, fmt.Errorf("failed to unmarshal registry entry: %w", err)
+	}
+	return e, nil
+}
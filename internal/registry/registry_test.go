@@ -0,0 +1,91 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRegisterList(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	alive := map[int]bool{100: true, 200: true}
+	isAlive := func(pid int) bool { return alive[pid] }
+	for _, e := range []Entry{
+		{PID: 200, Port: 6276, Bind: "localhost", StartedAt: time.Now(), Version: "1.0.0"},
+		{PID: 100, Port: 6275, Bind: "localhost", StartedAt: time.Now(), Version: "1.0.0"},
+		{PID: 300, Port: 6277, Bind: "0.0.0.0", StartedAt: time.Now(), Version: "0.9.0"},
+	} {
+		if err := Register(e); err != nil {
+			t.Fatalf("Register returned error: %v", err)
+		}
+	}
+
+	entries, err := List(isAlive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Port != 6275 || entries[1].Port != 6276 {
+		t.Fatalf("got %+v", entries)
+	}
+	// The entry of the dead server is removed.
+	p, err := Path(6277)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(p); !os.IsNotExist(err) {
+		t.Errorf("stale entry was not removed: %v", err)
+	}
+}
+
+func TestList_SkipsOtherFiles(t *testing.T) {
+	stateHome := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", stateHome)
+
+	if err := Register(Entry{PID: 100, Port: 6275}); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(stateHome, "mo", "servers")
+	if err := os.WriteFile(filepath.Join(dir, "mo-server-1.tmp"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "mo-6276.json"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := List(func(int) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Port != 6275 {
+		t.Errorf("got %+v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "mo-6276.json")); !os.IsNotExist(err) {
+		t.Errorf("unreadable entry was not removed: %v", err)
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	if err := Register(Entry{PID: 100, Port: 6275}); err != nil {
+		t.Fatal(err)
+	}
+	// Another server's entry is kept.
+	if err := Unregister(6275, 200); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := List(func(int) bool { return true }); len(entries) != 1 {
+		t.Fatalf("got %+v, want the entry kept", entries)
+	}
+	if err := Unregister(6275, 100); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := List(func(int) bool { return true }); len(entries) != 0 {
+		t.Errorf("got %+v, want none", entries)
+	}
+	if err := Unregister(6275, 100); err != nil {
+		t.Errorf("Unregister of a missing entry returned %v", err)
+	}
+}
@@ -0,0 +1,485 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fswatcher/fswatcher"
+	"github.com/k1LoW/mo/internal/archive"
+)
+
+// ArchiveData describes a mounted archive: the documents inside the zip or
+// tar file at Path are opened read-only in Group and reloaded when the file
+// changes on disk.
+type ArchiveData struct {
+	Group      string   `json:"group"`
+	Path       string   `json:"path"`
+	Extensions []string `json:"extensions,omitempty"` // without leading dot; empty means "md"
+}
+
+// archiveKey identifies a mount: an archive can be mounted in several groups.
+type archiveKey struct {
+	group string
+	path  string
+}
+
+func (ad ArchiveData) key() archiveKey {
+	return archiveKey{group: ad.Group, path: ad.Path}
+}
+
+// archiveIndexBudget is how much of an archive's content an index keeps in
+// memory.
+const archiveIndexBudget = 64 << 20
+
+// archiveIndex is the index of an archive as it was at modTime and size.
+type archiveIndex struct {
+	modTime time.Time
+	size    int64
+	index   *archive.Index
+}
+
+// archiveSource identifies the file inside an archive an entry was read from.
+type archiveSource struct {
+	archive string // path of the archive on disk
+	name    string // slash-separated path inside the archive
+}
+
+func (ad ArchiveData) matches(name string) bool {
+	return hasExtension(name, ad.Extensions)
+}
+
+func archiveEntryID(archivePath, name string) string {
+	h := sha256.New()
+	h.Write([]byte("archive:"))
+	h.Write([]byte(archivePath))
+	h.Write([]byte{0})
+	h.Write([]byte(name))
+	return "a" + hex.EncodeToString(h.Sum(nil))[:7]
+}
+
+// AddArchive mounts an archive, or reloads it if it is already mounted in the
+// group, and returns the entries of the documents inside.
+func (s *State) AddArchive(ad ArchiveData) ([]*FileEntry, error) {
+	if !archive.IsArchive(ad.Path) {
+		return nil, fmt.Errorf("%s is not a zip or tar archive", ad.Path)
+	}
+	fi, err := os.Stat(ad.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", ad.Path)
+	}
+
+	s.archiveMu.Lock()
+	prev, existed := s.archives[ad.key()]
+	s.archives[ad.key()] = ad
+	s.archiveMu.Unlock()
+	s.watchArchive(ad.Path)
+	s.markDirty()
+
+	slog.Info("archive mounted", "path", ad.Path, "group", ad.Group) //nolint:gosec // G706: structured logging fields, no injection risk
+	entries, err := s.loadArchive(ad)
+	if err == nil && len(entries) == 0 {
+		err = fmt.Errorf("no matching documents in %s", ad.Path)
+	}
+	if err != nil {
+		if existed {
+			// A failed reload leaves the existing mount as it was.
+			s.archiveMu.Lock()
+			s.archives[ad.key()] = prev
+			s.archiveMu.Unlock()
+		} else {
+			s.unmountArchive(ad)
+		}
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Archives returns the mounted archives ordered by group and path.
+func (s *State) Archives() []ArchiveData {
+	s.archiveMu.Lock()
+	defer s.archiveMu.Unlock()
+	var result []ArchiveData
+	for _, ad := range s.archives {
+		result = append(result, ad)
+	}
+	slices.SortFunc(result, func(a, b ArchiveData) int {
+		if c := strings.Compare(a.Group, b.Group); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+	return result
+}
+
+// loadArchive brings the entries of a mounted archive in line with its
+// content: documents are added or updated, and documents no longer in the
+// archive are closed.
+func (s *State) loadArchive(ad ArchiveData) ([]*FileEntry, error) {
+	s.archiveLoadMu.Lock()
+	defer s.archiveLoadMu.Unlock()
+
+	files, err := archive.ReadFiles(ad.Path, ad.matches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", ad.Path, err)
+	}
+
+	var entries []*FileEntry
+	keep := make(map[string]struct{})
+	for _, f := range files {
+		content := string(f.Data)
+		if strings.IndexByte(content, 0) >= 0 {
+			continue
+		}
+		entry := s.addArchiveFile(ad, f.Name, content)
+		keep[entry.ID] = struct{}{}
+		entries = append(entries, entry)
+	}
+
+	// Documents opened through links may not match the extensions; they stay
+	// open as long as the archive still has them.
+	for _, id := range s.archiveEntryIDs(ad) {
+		if _, ok := keep[id]; ok {
+			continue
+		}
+		entry := s.FindFile(id, ad.Group)
+		if entry == nil {
+			continue
+		}
+		data, err := s.readArchiveFile(ad.Path, entry.archive.name)
+		if err != nil || bytes.IndexByte(data, 0) >= 0 {
+			s.RemoveFile(id, ad.Group)
+			continue
+		}
+		s.replaceInMemoryContent(entry, string(data))
+	}
+	return entries, nil
+}
+
+// addArchiveFile adds the document name of a mounted archive, or updates the
+// entry already showing it.
+func (s *State) addArchiveFile(ad ArchiveData, name, content string) *FileEntry {
+	entry := s.addInMemoryFile(&FileEntry{
+		Name:     name,
+		ID:       archiveEntryID(ad.Path, name),
+		Uploaded: true,
+		content:  content,
+		archive:  &archiveSource{archive: ad.Path, name: name},
+	}, ad.Group)
+	s.replaceInMemoryContent(entry, content)
+	return entry
+}
+
+// archiveEntryIDs returns the IDs of the entries of an archive in its group.
+func (s *State) archiveEntryIDs(ad ArchiveData) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ids []string
+	if g, ok := s.groups[ad.Group]; ok {
+		for _, f := range g.Files {
+			if f.archive != nil && f.archive.archive == ad.Path {
+				ids = append(ids, f.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// releaseArchiveLocked unmounts the archive an entry removed from groupName
+// came from once none of its documents are open there. Caller must hold s.mu
+// for write.
+func (s *State) releaseArchiveLocked(src *archiveSource, groupName string) {
+	if g, ok := s.groups[groupName]; ok {
+		for _, f := range g.Files {
+			if f.archive != nil && f.archive.archive == src.archive {
+				return
+			}
+		}
+	}
+	s.archiveMu.Lock()
+	key := archiveKey{group: groupName, path: src.archive}
+	if _, ok := s.archives[key]; ok {
+		delete(s.archives, key)
+		slog.Info("archive unmounted", "path", key.path, "group", key.group) //nolint:gosec // G706: structured logging fields, no injection risk
+	}
+	mounted := s.archiveMountedLocked(src.archive)
+	s.archiveMu.Unlock()
+	if !mounted {
+		s.unwatchArchiveLocked(src.archive)
+	}
+	s.markDirty()
+}
+
+// unmountArchive forgets a mounted archive and closes its documents.
+func (s *State) unmountArchive(ad ArchiveData) {
+	s.archiveMu.Lock()
+	delete(s.archives, ad.key())
+	s.archiveMu.Unlock()
+	for _, id := range s.archiveEntryIDs(ad) {
+		s.RemoveFile(id, ad.Group)
+	}
+	s.mu.Lock()
+	s.archiveMu.Lock()
+	mounted := s.archiveMountedLocked(ad.Path)
+	s.archiveMu.Unlock()
+	if !mounted {
+		s.unwatchArchiveLocked(ad.Path)
+	}
+	s.mu.Unlock()
+	s.markDirty()
+}
+
+// archiveMountedLocked reports whether the archive at p is mounted in any
+// group. Caller must hold s.archiveMu.
+func (s *State) archiveMountedLocked(p string) bool {
+	for key := range s.archives {
+		if key.path == p {
+			return true
+		}
+	}
+	return false
+}
+
+// mountsOf returns the mounts of the archive at p.
+func (s *State) mountsOf(p string) []ArchiveData {
+	s.archiveMu.Lock()
+	defer s.archiveMu.Unlock()
+	var result []ArchiveData
+	for key, ad := range s.archives {
+		if key.path == p {
+			result = append(result, ad)
+		}
+	}
+	return result
+}
+
+func (s *State) watchArchive(p string) {
+	if s.watcher == nil {
+		return
+	}
+	canonical := resolvePathAlias(p)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, watched := s.watchedFiles[p]; watched {
+		return
+	}
+	if err := s.watcher.Add(p, watchOps); err != nil && !errors.Is(err, fswatcher.ErrAlreadyAdded) {
+		s.watchFailed("failed to watch archive", p, err)
+		return
+	}
+	s.watchedFiles[p] = struct{}{}
+	s.watchDiag.clear(p)
+	s.registerPathAlias(p, canonical)
+}
+
+// unwatchArchiveLocked drops the watch of an archive that is no longer
+// mounted. Caller must hold s.mu for write.
+func (s *State) unwatchArchiveLocked(p string) {
+	if s.watcher == nil {
+		return
+	}
+	if _, watched := s.watchedFiles[p]; !watched {
+		return
+	}
+	if err := s.watcher.Remove(p); err != nil {
+		slog.Warn("failed to unwatch archive", "path", p, "error", err)
+	}
+	delete(s.watchedFiles, p)
+	s.watchDiag.clear(p)
+	s.unregisterPathAlias(p)
+	s.forgetArchiveIndex(p)
+}
+
+// handleArchiveEvent reloads the mounts of a changed archive. An archive
+// replaced by a rename is re-watched; one that is gone is unmounted.
+func (s *State) handleArchiveEvent(p string, op fswatcher.Op) {
+	if len(s.mountsOf(p)) == 0 {
+		return
+	}
+	if op.Has(fswatcher.Write) || op.Has(fswatcher.Create) {
+		s.scheduleArchiveReload(p)
+	}
+	if op.Has(fswatcher.Remove) || op.Has(fswatcher.Rename) {
+		time.AfterFunc(100*time.Millisecond, func() {
+			if _, statErr := os.Stat(p); errors.Is(statErr, os.ErrNotExist) {
+				slog.Info("archive deleted, unmounting", "path", p)
+				for _, ad := range s.mountsOf(p) {
+					s.unmountArchive(ad)
+				}
+				return
+			}
+			if err := s.watcher.Add(p, watchOps); err != nil && !errors.Is(err, fswatcher.ErrAlreadyAdded) {
+				s.watchFailed("failed to re-watch archive", p, err)
+				return
+			}
+			s.watchDiag.clear(p)
+			s.scheduleArchiveReload(p)
+		})
+	}
+}
+
+// scheduleArchiveReload reloads the archive at p once writes to it settle;
+// copying a large archive over the old one takes many writes.
+func (s *State) scheduleArchiveReload(p string) {
+	reload := func() {
+		s.forgetArchiveIndex(p)
+		for _, ad := range s.mountsOf(p) {
+			slog.Info("archive changed, reloading", "path", p, "group", ad.Group) //nolint:gosec // G706: structured logging fields, no injection risk
+			if _, err := s.loadArchive(ad); err != nil {
+				// Likely still being written; the next write retries.
+				slog.Warn("failed to reload archive", "path", p, "error", err)
+			}
+		}
+	}
+	if s.fileChangeDebounce <= 0 {
+		reload()
+		return
+	}
+	s.archiveMu.Lock()
+	defer s.archiveMu.Unlock()
+	if timer, ok := s.archiveTimers[p]; ok {
+		timer.Stop()
+	}
+	s.archiveTimers[p] = time.AfterFunc(s.fileChangeDebounce, func() {
+		s.archiveMu.Lock()
+		delete(s.archiveTimers, p)
+		s.archiveMu.Unlock()
+		reload()
+	})
+}
+
+// serveArchiveAsset serves relPath, relative to the directory of the entry's
+// document, from the same archive.
+func (s *State) serveArchiveAsset(w http.ResponseWriter, src *archiveSource, relPath string) {
+	p := path.Clean(path.Join(path.Dir(src.name), relPath))
+	if p == ".." || strings.HasPrefix(p, "../") {
+		http.Error(w, "path outside the archive", http.StatusBadRequest)
+		return
+	}
+	data, err := s.readArchiveFile(src.archive, p)
+	if err != nil {
+		if errors.Is(err, archive.ErrNotFound) {
+			http.Error(w, "file not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ctype := mime.TypeByExtension(path.Ext(p))
+	if ctype == "" {
+		ctype = http.DetectContentType(data)
+	}
+	w.Header().Set("Content-Type", ctype)
+	// The archive may be replaced at any time.
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(data) //nolint:errcheck
+}
+
+// openArchiveLink opens the document a relative link of an archive entry
+// points to, from the same archive.
+func (s *State) openArchiveLink(src *archiveSource, groupName, link string) (*FileEntry, error) {
+	name := path.Clean(path.Join(path.Dir(src.name), link))
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return nil, errors.New("path outside the archive")
+	}
+	if entry := s.FindFile(archiveEntryID(src.archive, name), groupName); entry != nil {
+		return entry, nil
+	}
+	data, err := s.readArchiveFile(src.archive, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.addArchiveFile(ArchiveData{Group: groupName, Path: src.archive}, name, string(data)), nil
+}
+
+// readArchiveFile reads the file with the slash-separated name from the
+// archive at p through its index, which is built on first use and rebuilt
+// once the archive changes on disk.
+func (s *State) readArchiveFile(p, name string) ([]byte, error) {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	s.archiveMu.Lock()
+	ai := s.archiveIndexes[p]
+	s.archiveMu.Unlock()
+	if ai == nil || !ai.modTime.Equal(fi.ModTime()) || ai.size != fi.Size() {
+		ix, err := archive.NewIndex(p, archiveIndexBudget)
+		if err != nil {
+			return nil, err
+		}
+		ai = &archiveIndex{modTime: fi.ModTime(), size: fi.Size(), index: ix}
+		s.archiveMu.Lock()
+		s.archiveIndexes[p] = ai
+		s.archiveMu.Unlock()
+	}
+	return ai.index.ReadFile(name)
+}
+
+// forgetArchiveIndex drops the index of the archive at p.
+func (s *State) forgetArchiveIndex(p string) {
+	s.archiveMu.Lock()
+	delete(s.archiveIndexes, p)
+	s.archiveMu.Unlock()
+}
+
+type archiveRequest struct {
+	Path       string   `json:"path"`
+	Group      string   `json:"group"`
+	Extensions []string `json:"extensions,omitempty"`
+}
+
+// AddArchiveResponse is the JSON response for the add-archive endpoint.
+type AddArchiveResponse struct {
+	Files []*FileEntry `json:"files"`
+}
+
+func handleAddArchive(state *State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req archiveRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		group, err := ResolveGroupName(req.Group)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !filepath.IsAbs(req.Path) {
+			http.Error(w, "path must be an absolute path", http.StatusBadRequest)
+			return
+		}
+
+		entries, err := state.AddArchive(ArchiveData{
+			Group:      group,
+			Path:       filepath.Clean(req.Path),
+			Extensions: req.Extensions,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(AddArchiveResponse{Files: entries}); err != nil {
+			slog.Error("failed to encode response", "error", err)
+		}
+	}
+}
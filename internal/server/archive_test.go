@@ -0,0 +1,180 @@
+package server
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestZip(t *testing.T, p string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content)) //nolint:errcheck
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "docs-bundle.zip")
+	writeTestZip(t, p, map[string]string{
+		"README.md":         "# Bundle\n\nSee [guide](docs/guide.md).\n",
+		"docs/guide.md":     "# Guide\n\n![logo](img/logo.png)\n",
+		"docs/img/logo.png": "\x89PNG",
+		"docs/notes.txt":    "notes",
+	})
+
+	s := newTestState(t)
+	handler := NewHandler(s)
+	ad := ArchiveData{Group: "docs-bundle", Path: p}
+	entries, err := s.AddArchive(ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Name != "docs/guide.md" || entries[1].Name != "README.md" {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	guide := entries[0]
+	if guide.Title != "Guide" || !guide.Uploaded {
+		t.Errorf("unexpected entry %+v", guide)
+	}
+
+	t.Run("relative assets are served from the archive", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/_/api/groups/docs-bundle/files/"+guide.ID+"/raw/img/logo.png", nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != "\x89PNG" {
+			t.Fatalf("got %d %q", rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("Content-Type"); got != "image/png" {
+			t.Errorf("got Content-Type %q", got)
+		}
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/_/api/groups/docs-bundle/files/"+guide.ID+"/raw/img/missing.png", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("got status %d, want %d", rec.Code, http.StatusNotFound)
+		}
+
+		s.archiveMu.Lock()
+		_, indexed := s.archiveIndexes[p]
+		s.archiveMu.Unlock()
+		if !indexed {
+			t.Error("the archive was not indexed")
+		}
+	})
+
+	t.Run("relative links open documents of the archive", func(t *testing.T) {
+		body := `{"fileId":"` + guide.ID + `","path":"notes.txt"}`
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/_/api/groups/docs-bundle/files/open", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+		}
+		var entry FileEntry
+		if err := json.NewDecoder(rec.Body).Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry.Name != "docs/notes.txt" {
+			t.Errorf("got %q", entry.Name)
+		}
+	})
+
+	t.Run("reloads when the archive is replaced", func(t *testing.T) {
+		ch := s.Subscribe()
+		defer s.Unsubscribe(ch)
+		writeTestZip(t, p, map[string]string{
+			"docs/guide.md":  "# Guide v2\n",
+			"docs/notes.txt": "notes",
+		})
+		if _, err := s.loadArchive(ad); err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, f := range s.Groups()[0].Files {
+			names = append(names, f.Name)
+		}
+		if strings.Join(names, ",") != "docs/guide.md,docs/notes.txt" {
+			t.Errorf("got %v", names)
+		}
+		if got := s.FindFile(guide.ID, "docs-bundle"); got == nil || got.Title != "Guide v2" {
+			t.Errorf("unexpected entry %+v", got)
+		}
+		// The index of the old archive is not used.
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/_/api/groups/docs-bundle/files/"+guide.ID+"/raw/img/logo.png", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("got status %d for a removed asset, want %d", rec.Code, http.StatusNotFound)
+		}
+
+		changed := false
+		for len(ch) > 0 {
+			if e := <-ch; e.Name == eventFileChanged && strings.Contains(e.Data, guide.ID) {
+				changed = true
+			}
+		}
+		if !changed {
+			t.Error("no file-changed event was sent")
+		}
+	})
+
+	t.Run("failed reload keeps the mount", func(t *testing.T) {
+		if _, err := s.AddArchive(ArchiveData{Group: "docs-bundle", Path: p, Extensions: []string{"rst"}}); err == nil {
+			t.Fatal("want an error")
+		}
+		if got := s.Archives(); len(got) != 1 || len(got[0].Extensions) != 0 {
+			t.Errorf("got %+v, want the original mount", got)
+		}
+		if s.FindFile(guide.ID, "docs-bundle") == nil {
+			t.Error("documents of the mount were closed")
+		}
+	})
+
+	t.Run("saved as a mount", func(t *testing.T) {
+		s.mu.RLock()
+		data := s.snapshotRestoreData()
+		s.mu.RUnlock()
+		if len(data.UploadedFiles) != 0 {
+			t.Errorf("got %d uploaded files, want 0", len(data.UploadedFiles))
+		}
+		if len(data.Archives) != 1 || data.Archives[0].Path != p {
+			t.Errorf("unexpected archives %+v", data.Archives)
+		}
+	})
+
+	t.Run("unmounted when its documents are closed", func(t *testing.T) {
+		for _, f := range s.Groups()[0].Files {
+			s.RemoveFile(f.ID, "docs-bundle")
+		}
+		if got := s.Archives(); len(got) != 0 {
+			t.Errorf("got %+v, want no archives", got)
+		}
+	})
+
+	t.Run("archives without documents are rejected", func(t *testing.T) {
+		empty := filepath.Join(t.TempDir(), "empty.zip")
+		writeTestZip(t, empty, map[string]string{"a.txt": "a"})
+		if _, err := s.AddArchive(ArchiveData{Group: "default", Path: empty}); err == nil {
+			t.Error("want an error")
+		}
+		if got := s.Archives(); len(got) != 0 {
+			t.Errorf("got %+v, want no archives", got)
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/fswatcher/fswatcher"
+)
+
+// trackAsset records that the document at docPath references the local asset
+// at assetPath (an image or other file served through the raw endpoint) and
+// watches the asset, so that regenerating it reloads the document.
+func (s *State) trackAsset(docPath, assetPath string) {
+	if s.watcher == nil || docPath == assetPath {
+		return
+	}
+	s.mu.RLock()
+	_, tracked := s.assetDeps[assetPath][docPath]
+	s.mu.RUnlock()
+	if tracked {
+		return
+	}
+
+	canonical := resolvePathAlias(assetPath)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	docs, ok := s.assetDeps[assetPath]
+	if !ok {
+		if _, watched := s.watchedFiles[assetPath]; !watched {
+			if err := s.watcher.Add(assetPath, watchOps); err != nil && !errors.Is(err, fswatcher.ErrAlreadyAdded) {
+				s.watchFailed("failed to watch asset", assetPath, err)
+				return
+			}
+			s.watchedFiles[assetPath] = struct{}{}
+			s.watchDiag.clear(assetPath)
+			s.registerPathAlias(assetPath, canonical)
+		}
+		docs = make(map[string]struct{})
+		s.assetDeps[assetPath] = docs
+		slog.Info("asset watched", "path", assetPath, "document", docPath) //nolint:gosec // G706: structured logging fields, no injection risk
+	}
+	docs[docPath] = struct{}{}
+}
+
+// setDocumentIncludes tracks the files the document at docPath includes like
+// assets, replacing the set recorded by its previous read so that a file no
+// longer included stops reloading the document.
+func (s *State) setDocumentIncludes(docPath string, included []string) {
+	for _, p := range included {
+		s.trackAsset(docPath, p)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.includes[docPath]
+	if len(included) > 0 {
+		s.includes[docPath] = included
+	} else {
+		delete(s.includes, docPath)
+	}
+	for _, p := range old {
+		if slices.Contains(included, p) {
+			continue
+		}
+		docs, ok := s.assetDeps[p]
+		if !ok {
+			continue
+		}
+		delete(docs, docPath)
+		if len(docs) == 0 {
+			s.unwatchAssetLocked(p)
+		}
+	}
+}
+
+// assetDocuments returns the paths of the documents that reference the asset
+// at assetPath.
+func (s *State) assetDocuments(assetPath string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	docs := make([]string, 0, len(s.assetDeps[assetPath]))
+	for doc := range s.assetDeps[assetPath] {
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+// untrackDocumentAssets forgets the assets referenced by a document that is no
+// longer open, unwatching assets nothing else references. Caller must hold
+// s.mu for write.
+func (s *State) untrackDocumentAssets(docPath string) {
+	delete(s.includes, docPath)
+	for assetPath, docs := range s.assetDeps {
+		if _, ok := docs[docPath]; !ok {
+			continue
+		}
+		delete(docs, docPath)
+		if len(docs) == 0 {
+			s.unwatchAssetLocked(assetPath)
+		}
+	}
+}
+
+// unwatchAssetLocked drops the asset at assetPath and its watch, unless the
+// path is also open as a document. Caller must hold s.mu for write.
+func (s *State) unwatchAssetLocked(assetPath string) {
+	delete(s.assetDeps, assetPath)
+	for _, g := range s.groups {
+		for _, f := range g.Files {
+			if f.Path == assetPath {
+				return
+			}
+		}
+	}
+	if err := s.watcher.Remove(assetPath); err != nil {
+		slog.Warn("failed to unwatch asset", "path", assetPath, "error", err)
+	}
+	delete(s.watchedFiles, assetPath)
+	s.watchDiag.clear(assetPath)
+	s.unregisterPathAlias(assetPath)
+}
+
+// handleAssetEvent reloads the documents referencing the asset at assetPath.
+// Like document watches, an asset replaced by an atomic save is re-watched;
+// an asset that is gone is untracked until a document requests it again.
+func (s *State) handleAssetEvent(assetPath string, op fswatcher.Op) {
+	docs := s.assetDocuments(assetPath)
+	if len(docs) == 0 {
+		return
+	}
+	if op.Has(fswatcher.Write) || op.Has(fswatcher.Create) {
+		slog.Info("asset changed", "path", assetPath)
+		for _, doc := range docs {
+			s.scheduleFileChanged(doc)
+		}
+	}
+	if op.Has(fswatcher.Remove) || op.Has(fswatcher.Rename) {
+		time.AfterFunc(100*time.Millisecond, func() {
+			if _, statErr := os.Stat(assetPath); errors.Is(statErr, os.ErrNotExist) {
+				slog.Info("asset deleted, no longer watching", "path", assetPath)
+				s.mu.Lock()
+				if _, ok := s.assetDeps[assetPath]; ok {
+					s.unwatchAssetLocked(assetPath)
+				}
+				s.mu.Unlock()
+			} else if err := s.watcher.Add(assetPath, watchOps); err != nil && !errors.Is(err, fswatcher.ErrAlreadyAdded) {
+				s.watchFailed("failed to re-watch asset", assetPath, err)
+			} else {
+				s.watchDiag.clear(assetPath)
+			}
+			for _, doc := range docs {
+				s.scheduleFileChanged(doc)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/k1LoW/donegroup"
+)
+
+func TestAssetChange_ReloadsDocument(t *testing.T) {
+	ctx, cancel := donegroup.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewState(ctx)
+	t.Cleanup(s.CloseAllSubscribers)
+
+	dir := t.TempDir()
+	doc := filepath.Join(dir, "doc.md")
+	img := filepath.Join(dir, "images", "diagram.svg")
+	os.WriteFile(doc, []byte("# Doc\n\n![diagram](images/diagram.svg)\n"), 0o600) //nolint:errcheck
+	os.MkdirAll(filepath.Dir(img), 0o700)                                         //nolint:errcheck
+	os.WriteFile(img, []byte("<svg/>"), 0o600)                                    //nolint:errcheck
+
+	entry, err := s.AddFile(doc, DefaultGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(s)
+	req := httptest.NewRequest("GET", "/_/api/groups/default/files/"+entry.ID+"/raw/images/diagram.svg", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("got Cache-Control %q, want no-cache", got)
+	}
+	if docs := s.assetDocuments(img); len(docs) != 1 || docs[0] != doc {
+		t.Fatalf("got asset documents %v, want [%s]", docs, doc)
+	}
+
+	ch := s.Subscribe()
+	defer s.Unsubscribe(ch)
+
+	os.WriteFile(img, []byte("<svg><rect/></svg>"), 0o600) //nolint:errcheck
+
+	deadline := time.After(3 * time.Second)
+	for {
+		select {
+		case e := <-ch:
+			if e.Name != eventFileChanged {
+				continue
+			}
+			var got fileChangedEvent
+			if err := json.Unmarshal([]byte(e.Data), &got); err != nil {
+				t.Fatalf("failed to decode event: %v", err)
+			}
+			if got.ID != entry.ID {
+				t.Fatalf("got file-changed for %q, want %q", got.ID, entry.ID)
+			}
+			return
+		case <-deadline:
+			t.Fatal("timed out waiting for file-changed after asset change")
+		}
+	}
+}
+
+func TestUntrackDocumentAssets(t *testing.T) {
+	ctx, cancel := donegroup.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewState(ctx)
+	t.Cleanup(s.CloseAllSubscribers)
+
+	dir := t.TempDir()
+	docA := filepath.Join(dir, "a.md")
+	docB := filepath.Join(dir, "b.md")
+	img := filepath.Join(dir, "shared.png")
+	for _, p := range []string{docA, docB, img} {
+		os.WriteFile(p, []byte("x"), 0o600) //nolint:errcheck
+	}
+	entryA, err := s.AddFile(docA, DefaultGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entryB, err := s.AddFile(docB, DefaultGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.trackAsset(docA, img)
+	s.trackAsset(docB, img)
+
+	s.RemoveFile(entryA.ID, DefaultGroup)
+	if docs := s.assetDocuments(img); len(docs) != 1 || docs[0] != docB {
+		t.Fatalf("got asset documents %v, want [%s]", docs, docB)
+	}
+
+	s.RemoveFile(entryB.ID, DefaultGroup)
+	if docs := s.assetDocuments(img); len(docs) != 0 {
+		t.Fatalf("got asset documents %v, want none", docs)
+	}
+	if st := s.WatcherStatus(); st.WatchedFiles != 0 {
+		t.Errorf("got watchedFiles=%d, want 0 after closing all documents", st.WatchedFiles)
+	}
+}
+
+func TestRemoveFile_KeepsWatchOfIncludedFile(t *testing.T) {
+	ctx, cancel := donegroup.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewState(ctx)
+	t.Cleanup(s.CloseAllSubscribers)
+
+	dir := t.TempDir()
+	doc := filepath.Join(dir, "doc.md")
+	part := filepath.Join(dir, "part.md")
+	os.WriteFile(doc, []byte("# Doc\n\n<!-- include: part.md -->\n"), 0o600) //nolint:errcheck
+	os.WriteFile(part, []byte("Part one\n"), 0o600)                          //nolint:errcheck
+
+	docEntry, err := s.AddFile(doc, DefaultGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partEntry, err := s.AddFile(part, DefaultGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.trackAsset(doc, part)
+
+	// Closing the included file keeps reloading the document including it.
+	s.RemoveFile(partEntry.ID, DefaultGroup)
+	if docs := s.assetDocuments(part); len(docs) != 1 || docs[0] != doc {
+		t.Fatalf("got asset documents %v, want [%s]", docs, doc)
+	}
+
+	ch := s.Subscribe()
+	defer s.Unsubscribe(ch)
+
+	os.WriteFile(part, []byte("Part two\n"), 0o600) //nolint:errcheck
+
+	deadline := time.After(3 * time.Second)
+	for {
+		select {
+		case e := <-ch:
+			if e.Name != eventFileChanged {
+				continue
+			}
+			var got fileChangedEvent
+			if err := json.Unmarshal([]byte(e.Data), &got); err != nil {
+				t.Fatalf("failed to decode event: %v", err)
+			}
+			if got.ID != docEntry.ID {
+				t.Fatalf("got file-changed for %q, want %q", got.ID, docEntry.ID)
+			}
+			return
+		case <-deadline:
+			t.Fatal("timed out waiting for file-changed after the closed include changed")
+		}
+	}
+}
+
+func TestReadDocument_ReplacesIncludes(t *testing.T) {
+	ctx, cancel := donegroup.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewState(ctx)
+	t.Cleanup(s.CloseAllSubscribers)
+
+	dir := t.TempDir()
+	doc := filepath.Join(dir, "doc.md")
+	first := filepath.Join(dir, "first.md")
+	second := filepath.Join(dir, "second.md")
+	os.WriteFile(doc, []byte("<!-- include: first.md -->\n"), 0o600) //nolint:errcheck
+	os.WriteFile(first, []byte("First\n"), 0o600)                    //nolint:errcheck
+	os.WriteFile(second, []byte("Second\n"), 0o600)                  //nolint:errcheck
+	if _, err := s.AddFile(doc, DefaultGroup); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.readDocument(doc); err != nil {
+		t.Fatal(err)
+	}
+	if docs := s.assetDocuments(first); len(docs) != 1 || docs[0] != doc {
+		t.Fatalf("got documents %v for first.md, want [%s]", docs, doc)
+	}
+
+	os.WriteFile(doc, []byte("<!-- include: second.md -->\n"), 0o600) //nolint:errcheck
+	if _, err := s.readDocument(doc); err != nil {
+		t.Fatal(err)
+	}
+	if docs := s.assetDocuments(first); len(docs) != 0 {
+		t.Errorf("got documents %v for first.md, want none after it is no longer included", docs)
+	}
+	if docs := s.assetDocuments(second); len(docs) != 1 || docs[0] != doc {
+		t.Errorf("got documents %v for second.md, want [%s]", docs, doc)
+	}
+	s.mu.RLock()
+	_, watched := s.watchedFiles[first]
+	s.mu.RUnlock()
+	if watched {
+		t.Error("first.md is still watched")
+	}
+}
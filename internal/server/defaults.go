@@ -0,0 +1,80 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/fs"
+	"log/slog"
+	"net/http"
+
+	"github.com/k1LoW/mo/internal/config"
+)
+
+// UIDefaults are the settings the SPA starts with until the browser has
+// saved its own. Empty fields leave the SPA's defaults.
+type UIDefaults struct {
+	Theme    string
+	FontSize string
+}
+
+// SetIgnore sets the patterns of files skipped when glob patterns are
+// expanded, matched as by config.Ignored against the path relative to the
+// pattern's base directory. Files opened by name are never skipped.
+func (s *State) SetIgnore(patterns []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ignore = patterns
+}
+
+// SetUIDefaults sets the settings pushed to the SPA in index.html.
+func (s *State) SetUIDefaults(d UIDefaults) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uiDefaults = d
+}
+
+// ignored reports whether rel, relative to a pattern's base directory, is
+// excluded by the ignore patterns.
+func (s *State) ignored(rel string) bool {
+	s.mu.RLock()
+	patterns := s.ignore
+	s.mu.RUnlock()
+	return config.Ignored(patterns, rel)
+}
+
+// serveIndex serves index.html with the UI defaults as meta tags, read by
+// the SPA at startup. It reports false, writing nothing, when there are no
+// defaults to add.
+func (s *State) serveIndex(w http.ResponseWriter, distFS fs.FS) bool {
+	s.mu.RLock()
+	d := s.uiDefaults
+	s.mu.RUnlock()
+	if d == (UIDefaults{}) {
+		return false
+	}
+	index, err := fs.ReadFile(distFS, "index.html")
+	if err != nil {
+		slog.Error("failed to read index.html", "error", err)
+		return false
+	}
+	var meta bytes.Buffer
+	for _, m := range []struct{ name, value string }{
+		{"theme", d.Theme},
+		{"font-size", d.FontSize},
+	} {
+		if m.value != "" {
+			fmt.Fprintf(&meta, `<meta name="mo-default-%s" content="%s" />`, m.name, html.EscapeString(m.value))
+		}
+	}
+	if bytes.Contains(index, []byte("</head>")) {
+		meta.WriteString("</head>")
+		index = bytes.Replace(index, []byte("</head>"), meta.Bytes(), 1)
+	} else {
+		index = append(meta.Bytes(), index...)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(index) //nolint:errcheck
+	return true
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k1LoW/mo/internal/config"
+)
+
+func TestUIDefaults(t *testing.T) {
+	s := newTestState(t)
+	handler := NewHandler(s)
+
+	get := func(path string) string {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Body.String()
+	}
+
+	if body := get("/"); strings.Contains(body, "mo-default-") {
+		t.Errorf("got defaults without configuring any: %s", body)
+	}
+
+	s.SetUIDefaults(UIDefaults{Theme: "dark", FontSize: "large"})
+	for _, path := range []string{"/", "/index.html", "/design"} {
+		body := get(path)
+		if !strings.Contains(body, `<meta name="mo-default-theme" content="dark" />`) ||
+			!strings.Contains(body, `<meta name="mo-default-font-size" content="large" />`) {
+			t.Errorf("GET %s: no defaults in %s", path, body)
+		}
+	}
+}
+
+func TestAddPatternIgnore(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.md", "b.draft.md", "node_modules/pkg/README.md", "docs/c.md"} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("# x\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := newTestState(t)
+	s.SetIgnore([]string{"node_modules", "*.draft.md"})
+	entries, err := s.AddPattern(filepath.Join(dir, "**", "*.md"), "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		rel, _ := filepath.Rel(dir, e.Path)
+		names = append(names, filepath.ToSlash(rel))
+	}
+	if got := strings.Join(names, ","); got != "a.md,docs/c.md" {
+		t.Errorf("got %s", got)
+	}
+
+	p := filepath.Join(dir, "docs", "d.draft.md")
+	if err := os.WriteFile(p, []byte("# d\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s.handleCreateForGlobs(p)
+	if got := len(s.Groups()[0].Files); got != 2 {
+		t.Errorf("got %d files, want 2", got)
+	}
+}
+
+func TestAddPatternWithIgnore(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.md", "archive/old.md"} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("# x\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := newTestState(t)
+	pattern := filepath.Join(dir, "**", "*.md")
+	ignore := config.AbsIgnore(dir, []string{"archive"})
+	entries, err := s.AddPatternWithIgnore(pattern, "docs", ignore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name != "a.md" {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+
+	p := filepath.Join(dir, "archive", "new.md")
+	if err := os.WriteFile(p, []byte("# new\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s.handleCreateForGlobs(p)
+	if got := len(s.Groups()[0].Files); got != 1 {
+		t.Errorf("got %d files, want 1", got)
+	}
+
+	s.mu.RLock()
+	data := s.snapshotRestoreData()
+	s.mu.RUnlock()
+	if got := data.PatternIgnores["docs"][pattern]; len(got) != 1 || got[0] != ignore[0] {
+		t.Errorf("got pattern ignores %+v", data.PatternIgnores)
+	}
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	diffOpEqual  = "equal"
+	diffOpInsert = "insert"
+	diffOpDelete = "delete"
+
+	// maxDiffEditDistance bounds the work done by the Myers diff. Beyond it,
+	// the differing middle section is reported as a single replaced block.
+	maxDiffEditDistance = 1000
+
+	// defaultDiffContext is the number of unchanged lines shown around each
+	// hunk by the diff endpoint.
+	defaultDiffContext = 3
+)
+
+// DiffLine is a single line of a diff hunk.
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffHunk describes a changed region. Start values are 1-based line numbers;
+// a hunk with zero lines on one side is a pure insertion or deletion located
+// just before that line.
+type DiffHunk struct {
+	OldStart int        `json:"oldStart"`
+	OldLines int        `json:"oldLines"`
+	NewStart int        `json:"newStart"`
+	NewLines int        `json:"newLines"`
+	Lines    []DiffLine `json:"lines,omitempty"`
+}
+
+// lineOp is one step of a line-level edit script, annotated with the 1-based
+// line numbers it applies to in the old and new text.
+type lineOp struct {
+	op      string
+	text    string
+	oldLine int
+	newLine int
+}
+
+// splitLines splits content into lines for diffing.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(content, "\n")
+}
+
+// diffLines returns the line-level edit script that turns a into b.
+func diffLines(a, b []string) []lineOp {
+	ops, ok := myersDiff(a, b, maxDiffEditDistance)
+	if !ok {
+		ops = replaceDiff(a, b)
+	}
+	oldLine, newLine := 1, 1
+	for i := range ops {
+		ops[i].oldLine = oldLine
+		ops[i].newLine = newLine
+		switch ops[i].op {
+		case diffOpEqual:
+			oldLine++
+			newLine++
+		case diffOpDelete:
+			oldLine++
+		case diffOpInsert:
+			newLine++
+		}
+	}
+	return ops
+}
+
+// myersDiff implements the greedy Myers O(ND) algorithm. It gives up and
+// returns false once the edit distance exceeds maxD.
+func myersDiff(a, b []string, maxD int) ([]lineOp, bool) {
+	n, m := len(a), len(b)
+	maxD = min(maxD, n+m)
+	off := maxD + 1
+	v := make([]int, 2*maxD+3)
+	// trace[d] holds v[-d-1 .. d+1] as it was before step d.
+	var trace [][]int
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, slices.Clone(v[off-d-1:off+d+2]))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrackMyers(a, b, trace), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func backtrackMyers(a, b []string, trace [][]int) []lineOp {
+	x, y := len(a), len(b)
+	var ops []lineOp
+	for d := len(trace) - 1; d >= 0; d-- {
+		snap := trace[d]
+		at := func(k int) int { return snap[k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, lineOp{op: diffOpEqual, text: a[x-1]})
+			x--
+			y--
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			ops = append(ops, lineOp{op: diffOpInsert, text: b[y-1]})
+			y--
+		} else {
+			ops = append(ops, lineOp{op: diffOpDelete, text: a[x-1]})
+			x--
+		}
+	}
+	slices.Reverse(ops)
+	return ops
+}
+
+// replaceDiff is the fallback for very different inputs: the common prefix
+// and suffix are kept and everything between them is replaced.
+func replaceDiff(a, b []string) []lineOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ops := make([]lineOp, 0, len(a)+len(b)-prefix-suffix)
+	for _, l := range a[:prefix] {
+		ops = append(ops, lineOp{op: diffOpEqual, text: l})
+	}
+	for _, l := range a[prefix : len(a)-suffix] {
+		ops = append(ops, lineOp{op: diffOpDelete, text: l})
+	}
+	for _, l := range b[prefix : len(b)-suffix] {
+		ops = append(ops, lineOp{op: diffOpInsert, text: l})
+	}
+	for _, l := range a[len(a)-suffix:] {
+		ops = append(ops, lineOp{op: diffOpEqual, text: l})
+	}
+	return ops
+}
+
+// buildHunks groups an edit script into hunks with up to context unchanged
+// lines around each change. Lines are included only when withLines is set.
+func buildHunks(ops []lineOp, context int, withLines bool) []DiffHunk {
+	var hunks []DiffHunk
+	i := 0
+	for i < len(ops) {
+		if ops[i].op == diffOpEqual {
+			i++
+			continue
+		}
+		start := max(0, i-context)
+		// Extend the hunk while the next change is close enough that the
+		// context windows would overlap.
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].op == diffOpEqual {
+				if j-end > 2*context {
+					break
+				}
+				continue
+			}
+			end = j
+		}
+		stop := min(len(ops), end+context+1)
+
+		h := DiffHunk{OldStart: ops[start].oldLine, NewStart: ops[start].newLine}
+		for _, op := range ops[start:stop] {
+			switch op.op {
+			case diffOpEqual:
+				h.OldLines++
+				h.NewLines++
+			case diffOpDelete:
+				h.OldLines++
+			case diffOpInsert:
+				h.NewLines++
+			}
+			if withLines {
+				h.Lines = append(h.Lines, DiffLine{Op: op.op, Text: op.text})
+			}
+		}
+		hunks = append(hunks, h)
+		i = stop
+	}
+	return hunks
+}
+
+// firstChangedLine returns the 1-based line in the new text at which the
+// first change appears, or 0 when there are no changes.
+func firstChangedLine(ops []lineOp, newLineCount int) int {
+	for _, op := range ops {
+		if op.op == diffOpEqual {
+			continue
+		}
+		// A deletion at the very end points past the last line; clamp it.
+		return max(1, min(op.newLine, newLineCount))
+	}
+	return 0
+}
+
+// unifiedDiff renders hunks (built with lines) in unified diff format.
+func unifiedDiff(fromLabel, toLabel string, hunks []DiffHunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromLabel, toLabel)
+	for _, h := range hunks {
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+		for _, l := range h.Lines {
+			switch l.Op {
+			case diffOpEqual:
+				sb.WriteByte(' ')
+			case diffOpDelete:
+				sb.WriteByte('-')
+			case diffOpInsert:
+				sb.WriteByte('+')
+			}
+			sb.WriteString(l.Text)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func applyOps(ops []lineOp) (oldText, newText []string) {
+	for _, op := range ops {
+		switch op.op {
+		case diffOpEqual:
+			oldText = append(oldText, op.text)
+			newText = append(newText, op.text)
+		case diffOpDelete:
+			oldText = append(oldText, op.text)
+		case diffOpInsert:
+			newText = append(newText, op.text)
+		}
+	}
+	return oldText, newText
+}
+
+func sameHunkRange(a, b DiffHunk) bool {
+	return a.OldStart == b.OldStart && a.OldLines == b.OldLines &&
+		a.NewStart == b.NewStart && a.NewLines == b.NewLines
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      string
+		wantFirst int
+		wantHunks []DiffHunk
+	}{
+		{
+			name:      "identical",
+			a:         "a\nb\nc",
+			b:         "a\nb\nc",
+			wantFirst: 0,
+		},
+		{
+			name:      "modified line",
+			a:         "a\nb\nc",
+			b:         "a\nB\nc",
+			wantFirst: 2,
+			wantHunks: []DiffHunk{{OldStart: 2, OldLines: 1, NewStart: 2, NewLines: 1}},
+		},
+		{
+			name:      "inserted lines",
+			a:         "a\nb",
+			b:         "a\nx\ny\nb",
+			wantFirst: 2,
+			wantHunks: []DiffHunk{{OldStart: 2, OldLines: 0, NewStart: 2, NewLines: 2}},
+		},
+		{
+			name:      "deleted at end",
+			a:         "a\nb\nc",
+			b:         "a\nb",
+			wantFirst: 2,
+			wantHunks: []DiffHunk{{OldStart: 3, OldLines: 1, NewStart: 3, NewLines: 0}},
+		},
+		{
+			name:      "two separate changes",
+			a:         "1\n2\n3\n4\n5\n6\n7\n8",
+			b:         "1\nX\n3\n4\n5\n6\nY\n8",
+			wantFirst: 2,
+			wantHunks: []DiffHunk{
+				{OldStart: 2, OldLines: 1, NewStart: 2, NewLines: 1},
+				{OldStart: 7, OldLines: 1, NewStart: 7, NewLines: 1},
+			},
+		},
+		{
+			name:      "from empty",
+			a:         "",
+			b:         "a\nb",
+			wantFirst: 1,
+			wantHunks: []DiffHunk{{OldStart: 1, OldLines: 0, NewStart: 1, NewLines: 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := splitLines(tt.a), splitLines(tt.b)
+			ops := diffLines(a, b)
+
+			gotOld, gotNew := applyOps(ops)
+			if strings.Join(gotOld, "\n") != tt.a || strings.Join(gotNew, "\n") != tt.b {
+				t.Fatalf("edit script does not reproduce inputs: old=%q new=%q", gotOld, gotNew)
+			}
+			if got := firstChangedLine(ops, len(b)); got != tt.wantFirst {
+				t.Errorf("got firstChangedLine=%d, want %d", got, tt.wantFirst)
+			}
+			hunks := buildHunks(ops, 0, false)
+			if len(hunks) != len(tt.wantHunks) {
+				t.Fatalf("got %d hunks %+v, want %d", len(hunks), hunks, len(tt.wantHunks))
+			}
+			for i, h := range hunks {
+				if !sameHunkRange(h, tt.wantHunks[i]) {
+					t.Errorf("hunk %d: got %+v, want %+v", i, h, tt.wantHunks[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDiffLines_FallbackBeyondMaxEditDistance(t *testing.T) {
+	var a, b []string
+	for i := range maxDiffEditDistance {
+		a = append(a, "old "+strings.Repeat("x", i%7))
+		b = append(b, "new "+strings.Repeat("y", i%5))
+	}
+	a = append([]string{"head"}, append(a, "tail")...)
+	b = append([]string{"head"}, append(b, "tail")...)
+
+	ops := diffLines(a, b)
+	gotOld, gotNew := applyOps(ops)
+	if len(gotOld) != len(a) || len(gotNew) != len(b) {
+		t.Fatalf("edit script does not reproduce inputs: %d/%d lines", len(gotOld), len(gotNew))
+	}
+	if got := firstChangedLine(ops, len(b)); got != 2 {
+		t.Errorf("got firstChangedLine=%d, want 2", got)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	ops := diffLines(splitLines("a\nb\nc\nd"), splitLines("a\nB\nc\nd"))
+	got := unifiedDiff("revision 1", "revision 2", buildHunks(ops, 1, true))
+	want := "--- revision 1\n+++ revision 2\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := unifiedDiff("a", "b", nil); got != "" {
+		t.Errorf("got %q for no hunks, want empty", got)
+	}
+}
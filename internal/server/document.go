@@ -0,0 +1,181 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/k1LoW/mo/internal/asciidoc"
+	"github.com/k1LoW/mo/internal/csvtable"
+	"github.com/k1LoW/mo/internal/include"
+	"github.com/k1LoW/mo/internal/notebook"
+	"github.com/k1LoW/mo/internal/orgmode"
+)
+
+// documentAsset is an image extracted while converting a document, served
+// from memory by the asset endpoint.
+type documentAsset struct {
+	contentType string
+	data        []byte
+}
+
+// documentAssetPath is the URL path prefix of converted documents' assets.
+// Asset names are content hashes, so the URLs do not depend on the group or
+// file ID and never change meaning.
+const documentAssetPath = "/_/api/assets/"
+
+// documentConverter converts the content of a document format mo shows as
+// Markdown.
+type documentConverter func(s *State, path string, data []byte) (string, error)
+
+// documentConverters holds the converters by lower-case file extension.
+var documentConverters = map[string]documentConverter{
+	".ipynb":    convertNotebook,
+	".org":      convertOrg,
+	".adoc":     convertAsciiDoc,
+	".asciidoc": convertAsciiDoc,
+}
+
+// isConvertedDocument reports whether the file at path is shown by converting
+// it to Markdown on the server rather than as-is.
+func isConvertedDocument(path string) bool {
+	_, ok := documentConverters[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// readDocument returns the Markdown shown for the file at path, expanding
+// include directives, rendering code blocks with configured commands and
+// converting formats such as Jupyter notebooks, Org-mode and AsciiDoc.
+// Conversion failures (e.g. a notebook read halfway through a save) are
+// rendered as an alert instead of an error, so that the next reload
+// recovers.
+func (s *State) readDocument(path string) (string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // Path is server-managed, not user-supplied
+	if err != nil {
+		return "", err
+	}
+	if isTablePath(path) {
+		return csvtable.DecodeText(data), nil
+	}
+	convert, ok := documentConverters[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		// Included files are tracked like assets, so editing one reloads
+		// every document that includes it.
+		content, included := include.Expand(path, string(data), os.ReadFile)
+		s.setDocumentIncludes(path, included)
+		return s.renderFences(path, content), nil
+	}
+	content, err := convert(s, path, data)
+	if err != nil {
+		return fmt.Sprintf("> [!CAUTION]\n> Failed to read %s: %s\n", filepath.Base(path), err), nil
+	}
+	return content, nil
+}
+
+func convertNotebook(s *State, path string, data []byte) (string, error) {
+	doc, err := notebook.Convert(data, func(name string) string { return documentAssetPath + name })
+	if err != nil {
+		return "", err
+	}
+	s.setDocumentAssets(path, doc.Assets)
+	return doc.Markdown, nil
+}
+
+func convertOrg(_ *State, path string, data []byte) (string, error) {
+	return orgmode.Convert(data, path)
+}
+
+func convertAsciiDoc(_ *State, _ string, data []byte) (string, error) {
+	return asciidoc.Convert(data), nil
+}
+
+// documentTitle extracts the title of the file at path. Converted documents
+// are titled by their converted Markdown. Returns ("", false) on read error.
+func (s *State) documentTitle(path string) (string, bool) {
+	if !isConvertedDocument(path) {
+		return extractTitleFromFile(path)
+	}
+	content, err := s.readDocument(path)
+	if err != nil {
+		return "", false
+	}
+	if len(content) > headFileSizeLimit {
+		content = content[:headFileSizeLimit]
+	}
+	return extractTitle(content), true
+}
+
+// readSearchableContent returns the text of entry that search matches
+// against.
+func (s *State) readSearchableContent(entry *FileEntry) (string, error) {
+	if entry.Uploaded {
+		return entry.content, nil
+	}
+	return s.readDocument(entry.Path)
+}
+
+// setDocumentAssets replaces the assets extracted from the document at path,
+// dropping those no other document references anymore.
+func (s *State) setDocumentAssets(path string, assets []notebook.Asset) {
+	s.docAssetsMu.Lock()
+	defer s.docAssetsMu.Unlock()
+
+	old := s.docAssetOwners[path]
+	names := make([]string, 0, len(assets))
+	for _, a := range assets {
+		names = append(names, a.Name)
+		if _, ok := s.docAssets[a.Name]; !ok {
+			s.docAssets[a.Name] = documentAsset{contentType: a.ContentType, data: a.Data}
+		}
+	}
+	if len(names) > 0 {
+		s.docAssetOwners[path] = names
+	} else {
+		delete(s.docAssetOwners, path)
+	}
+
+	for _, name := range old {
+		if slices.Contains(names, name) {
+			continue
+		}
+		referenced := false
+		for _, owned := range s.docAssetOwners {
+			if slices.Contains(owned, name) {
+				referenced = true
+				break
+			}
+		}
+		if !referenced {
+			delete(s.docAssets, name)
+		}
+	}
+}
+
+// forgetDocumentAssets drops the assets of a document that is no longer open.
+func (s *State) forgetDocumentAssets(path string) {
+	s.setDocumentAssets(path, nil)
+}
+
+func handleDocumentAsset(state *State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state.docAssetsMu.Lock()
+		asset, ok := state.docAssets[r.PathValue("name")]
+		state.docAssetsMu.Unlock()
+		if !ok {
+			http.Error(w, "asset not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", asset.contentType)
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		// SVG outputs may carry scripts; never run them when opened directly.
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if _, err := w.Write(asset.data); err != nil {
+			slog.Error("failed to write asset", "error", err)
+		}
+	}
+}
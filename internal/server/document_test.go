@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k1LoW/donegroup"
+)
+
+const testNotebook = `{
+  "nbformat": 4,
+  "nbformat_minor": 5,
+  "metadata": {"language_info": {"name": "python"}},
+  "cells": [
+    {"cell_type": "markdown", "source": ["# Report"]},
+    {"cell_type": "code", "source": ["plot()"], "outputs": [
+      {"output_type": "display_data", "data": {"image/png": "iVBORw0KGgo="}}
+    ]}
+  ]
+}`
+
+func TestNotebookDocument(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "report.ipynb")
+	if err := os.WriteFile(p, []byte(testNotebook), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestState(t)
+	handler := NewHandler(s)
+	entry, err := s.AddFile(p, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Title != "Report" {
+		t.Errorf("got title %q, want %q", entry.Title, "Report")
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/_/api/groups/default/files/"+entry.ID+"/content", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	var resp fileContentResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp.Content, "```python\nplot()\n```") {
+		t.Errorf("content is not converted:\n%s", resp.Content)
+	}
+	m := regexp.MustCompile(`!\[output\]\((/_/api/assets/[^)]+)\)`).FindStringSubmatch(resp.Content)
+	if m == nil {
+		t.Fatalf("no asset image in content:\n%s", resp.Content)
+	}
+
+	t.Run("asset endpoint", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", m[1], nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "image/png" {
+			t.Errorf("got Content-Type %q, want image/png", ct)
+		}
+		if !strings.HasPrefix(rec.Body.String(), "\x89PNG") {
+			t.Errorf("unexpected body %q", rec.Body.String())
+		}
+	})
+
+	t.Run("broken notebook renders an alert", func(t *testing.T) {
+		if err := os.WriteFile(p, []byte(`{"cells": [`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		content, err := s.readDocument(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(content, "> [!CAUTION]") {
+			t.Errorf("got %q, want a caution alert", content)
+		}
+		if err := os.WriteFile(p, []byte(testNotebook), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("closing the notebook drops its assets", func(t *testing.T) {
+		s.RemoveFile(entry.ID, "default")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", m[1], nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("got status %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestProseDocuments(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantTitle string
+		wantLine  string
+	}{
+		{"runbook.org", "#+TITLE: Runbook\n\n* Restart\n  Run =systemctl restart app=.\n", "Runbook", "## Restart"},
+		{"runbook.adoc", "= Runbook\n\n== Restart\n\nRun `systemctl restart app`.\n", "Runbook", "## Restart"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(p, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			s := newTestState(t)
+			entry, err := s.AddFile(p, "default")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry.Title != tt.wantTitle {
+				t.Errorf("got title %q, want %q", entry.Title, tt.wantTitle)
+			}
+			content, err := s.readSearchableContent(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(content, tt.wantLine) || !strings.Contains(content, "`systemctl restart app`") {
+				t.Errorf("content is not converted:\n%s", content)
+			}
+		})
+	}
+}
+
+func TestIncludeDocument(t *testing.T) {
+	ctx, cancel := donegroup.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewState(ctx)
+	t.Cleanup(s.CloseAllSubscribers)
+
+	dir := t.TempDir()
+	doc := filepath.Join(dir, "guide.md")
+	partial := filepath.Join(dir, "shared", "warning.md")
+	os.WriteFile(doc, []byte("# Guide\n\n<!-- include: shared/warning.md -->\n"), 0o600) //nolint:errcheck
+	os.MkdirAll(filepath.Dir(partial), 0o700)                                            //nolint:errcheck
+	os.WriteFile(partial, []byte("Be careful.\n"), 0o600)                                //nolint:errcheck
+
+	entry, err := s.AddFile(doc, DefaultGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(s)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/_/api/groups/default/files/"+entry.ID+"/content", nil))
+	var resp fileContentResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "# Guide\n\nBe careful.\n" {
+		t.Errorf("got %q", resp.Content)
+	}
+
+	ch := s.Subscribe()
+	defer s.Unsubscribe(ch)
+	os.WriteFile(partial, []byte("Be very careful.\n"), 0o600) //nolint:errcheck
+
+	deadline := time.After(3 * time.Second)
+	for {
+		select {
+		case e := <-ch:
+			if e.Name != eventFileChanged {
+				continue
+			}
+			var got fileChangedEvent
+			if err := json.Unmarshal([]byte(e.Data), &got); err != nil {
+				t.Fatalf("failed to decode event: %v", err)
+			}
+			if got.ID != entry.ID || got.Revision != 2 {
+				t.Fatalf("unexpected event %+v", got)
+			}
+			return
+		case <-deadline:
+			t.Fatal("timed out waiting for file-changed after editing the included file")
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fswatcher/fswatcher"
+	"github.com/k1LoW/mo/internal/git"
+)
+
+// GitInfo is the git metadata of a file in a work tree.
+type GitInfo struct {
+	Root   string    `json:"root"`
+	Status string    `json:"status,omitempty"` // modified, staged or untracked; empty when clean
+	Commit string    `json:"commit,omitempty"` // last commit that touched the file
+	Author string    `json:"author,omitempty"`
+	Date   time.Time `json:"date,omitzero"`
+}
+
+func (gi *GitInfo) equal(o *GitInfo) bool {
+	// Dates parsed from git carry a fresh location each time, so compare the
+	// instant rather than the struct.
+	return gi.Root == o.Root && gi.Status == o.Status && gi.Commit == o.Commit &&
+		gi.Author == o.Author && gi.Date.Equal(o.Date)
+}
+
+// gitRepo tracks a work tree that contains open files.
+type gitRepo struct {
+	gitDir string      // watched git directory; empty until the first refresh
+	timer  *time.Timer // pending refresh
+}
+
+const defaultGitInfoDebounce = 300 * time.Millisecond
+
+// findGitRoot returns the work tree root containing dir by looking for a
+// ".git" entry upwards, or "" when dir is not inside a work tree. It avoids
+// running git for every file that is opened.
+func findGitRoot(dir string) string {
+	for {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// scheduleGitRefresh refreshes the git metadata of the files under root after
+// a short delay, coalescing bursts such as a commit touching the index, refs
+// and logs. It is safe to call while holding s.mu.
+func (s *State) scheduleGitRefresh(root string) {
+	s.gitMu.Lock()
+	defer s.gitMu.Unlock()
+	repo, ok := s.gitRepos[root]
+	if !ok {
+		repo = &gitRepo{}
+		s.gitRepos[root] = repo
+	}
+	if repo.timer != nil {
+		repo.timer.Stop()
+	}
+	repo.timer = time.AfterFunc(s.gitInfoDebounce, func() {
+		s.refreshGitInfo(root)
+		s.refreshReviewsAt(root)
+	})
+}
+
+// gitPathsUnder returns the paths of the open files whose work tree is root.
+func (s *State) gitPathsUnder(root string) []string {
+	s.mu.RLock()
+	seen := make(map[string]struct{})
+	var candidates []string
+	for _, g := range s.groups {
+		for _, f := range g.Files {
+			if f.Uploaded {
+				continue
+			}
+			if _, ok := seen[f.Path]; ok {
+				continue
+			}
+			seen[f.Path] = struct{}{}
+			candidates = append(candidates, f.Path)
+		}
+	}
+	s.mu.RUnlock()
+
+	// Files in nested repositories belong to those instead.
+	var paths []string
+	for _, p := range candidates {
+		if findGitRoot(filepath.Dir(p)) == root {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// refreshGitInfo updates the git metadata of every open file in the work tree
+// at root and watches its git directory, or forgets the work tree once no
+// open file is in it and no review group follows it.
+func (s *State) refreshGitInfo(root string) {
+	s.gitRefreshMu.Lock()
+	defer s.gitRefreshMu.Unlock()
+
+	paths := s.gitPathsUnder(root)
+	if len(paths) == 0 {
+		if s.hasReviewAt(root) {
+			s.watchGitDir(root)
+		} else {
+			s.forgetGitRepo(root)
+		}
+		return
+	}
+	s.watchGitDir(root)
+
+	rels := make(map[string]string, len(paths))
+	relList := make([]string, 0, len(paths))
+	for _, p := range paths {
+		rel, err := git.RelPath(root, p)
+		if err != nil {
+			continue
+		}
+		rels[p] = rel
+		relList = append(relList, rel)
+	}
+	statuses, err := git.Status(root, relList)
+	if err != nil {
+		slog.Warn("failed to read git status", "root", root, "error", err)
+		return
+	}
+	commits, err := git.LastCommits(root, relList)
+	if err != nil {
+		slog.Warn("failed to read last commits", "root", root, "error", err)
+	}
+	infos := make(map[string]*GitInfo, len(rels))
+	for p, rel := range rels {
+		info := &GitInfo{Root: root, Status: statuses[rel]}
+		if c := commits[rel]; c != nil {
+			info.Commit = c.Hash
+			info.Author = c.Author
+			info.Date = c.Date
+		}
+		infos[p] = info
+	}
+
+	changed := false
+	s.mu.Lock()
+	for _, g := range s.groups {
+		for _, f := range g.Files {
+			info, ok := infos[f.Path]
+			if !ok || f.Uploaded {
+				continue
+			}
+			if f.GitInfo == nil || !f.GitInfo.equal(info) {
+				// Replace rather than mutate: Groups() hands out shallow
+				// copies that share this pointer.
+				f.GitInfo = info
+				changed = true
+			}
+		}
+	}
+	s.mu.Unlock()
+	if changed {
+		s.sendEvent(sseEvent{Name: eventUpdate, Data: "{}"})
+	}
+}
+
+// watchGitDir watches the git directory of the work tree at root, so that
+// commits, checkouts and staging refresh the metadata of its files.
+func (s *State) watchGitDir(root string) {
+	if s.watcher == nil {
+		return
+	}
+	s.gitMu.Lock()
+	repo, ok := s.gitRepos[root]
+	if !ok {
+		repo = &gitRepo{}
+		s.gitRepos[root] = repo
+	}
+	watched := repo.gitDir != ""
+	s.gitMu.Unlock()
+	if watched {
+		return
+	}
+
+	gitDir, err := git.GitDir(root)
+	if err != nil {
+		slog.Warn("failed to find git directory", "root", root, "error", err)
+		return
+	}
+	if err := s.watcher.Add(gitDir, watchOps); err != nil && !errors.Is(err, fswatcher.ErrAlreadyAdded) {
+		s.watchFailed("failed to watch git directory", gitDir, err)
+		return
+	}
+	s.watchDiag.clear(gitDir)
+
+	s.gitMu.Lock()
+	repo.gitDir = gitDir
+	s.gitDirs[gitDir] = root
+	s.gitMu.Unlock()
+	slog.Info("git directory watched", "path", gitDir, "root", root)
+}
+
+// forgetGitRepo stops tracking the work tree at root.
+func (s *State) forgetGitRepo(root string) {
+	s.gitMu.Lock()
+	repo, ok := s.gitRepos[root]
+	if ok {
+		if repo.timer != nil {
+			repo.timer.Stop()
+		}
+		delete(s.gitRepos, root)
+		if repo.gitDir != "" {
+			delete(s.gitDirs, repo.gitDir)
+		}
+	}
+	s.gitMu.Unlock()
+	if ok && repo.gitDir != "" && s.watcher != nil {
+		if err := s.watcher.Remove(repo.gitDir); err != nil {
+			slog.Warn("failed to unwatch git directory", "path", repo.gitDir, "error", err)
+		}
+		s.watchDiag.clear(repo.gitDir)
+	}
+}
+
+// handleGitDirEvent schedules a refresh when an event path is inside a
+// watched git directory. paths holds the translated and raw forms of the
+// event path.
+func (s *State) handleGitDirEvent(paths ...string) {
+	for _, p := range paths {
+		s.gitMu.Lock()
+		root, ok := s.gitDirs[filepath.Dir(p)]
+		s.gitMu.Unlock()
+		if ok {
+			s.forgetReviewTracked(root)
+			s.scheduleGitRefresh(root)
+			return
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/k1LoW/donegroup"
+)
+
+func TestRefreshGitInfo(t *testing.T) {
+	dir := t.TempDir()
+	commit := commitFiles(t, dir, map[string]string{"a.md": "# A\n", "docs/b.md": "# B\n"})
+	os.WriteFile(filepath.Join(dir, "new.md"), []byte("# New\n"), 0o600) //nolint:errcheck
+
+	s := newTestState(t)
+	for _, p := range []string{"a.md", "docs/b.md", "new.md"} {
+		if _, err := s.AddFile(filepath.Join(dir, filepath.FromSlash(p)), DefaultGroup); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.refreshGitInfo(dir)
+
+	infos := make(map[string]*GitInfo)
+	for _, f := range s.Groups()[0].Files {
+		infos[f.Name] = f.GitInfo
+	}
+	if info := infos["a.md"]; info == nil || info.Root != dir || info.Status != "" || info.Commit != commit || info.Author != "mo" || info.Date.IsZero() {
+		t.Errorf("a.md: got %+v, want clean at %s by mo", info, commit)
+	}
+	if info := infos["b.md"]; info == nil || info.Commit != commit {
+		t.Errorf("b.md: got %+v, want commit %s", info, commit)
+	}
+	if info := infos["new.md"]; info == nil || info.Status != "untracked" || info.Commit != "" {
+		t.Errorf("new.md: got %+v, want untracked without commit", info)
+	}
+
+	t.Run("edit marks the file modified", func(t *testing.T) {
+		os.WriteFile(filepath.Join(dir, "a.md"), []byte("# A edited\n"), 0o600) //nolint:errcheck
+		s.refreshGitInfo(dir)
+		entry := s.FindFile(FileID(filepath.Join(dir, "a.md")), DefaultGroup)
+		if entry.GitInfo == nil || entry.GitInfo.Status != "modified" {
+			t.Errorf("got %+v, want status modified", entry.GitInfo)
+		}
+	})
+
+	t.Run("exposed in groups API", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		NewHandler(s).ServeHTTP(rec, httptest.NewRequest("GET", "/_/api/groups", nil))
+		var groups []struct {
+			Files []struct {
+				Name string   `json:"name"`
+				Git  *GitInfo `json:"git"`
+			} `json:"files"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&groups); err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range groups[0].Files {
+			if f.Git == nil || f.Git.Root != dir {
+				t.Errorf("%s: got git %+v, want root %s", f.Name, f.Git, dir)
+			}
+		}
+	})
+
+	t.Run("outside a work tree", func(t *testing.T) {
+		other := filepath.Join(t.TempDir(), "plain.md")
+		os.WriteFile(other, []byte("# Plain\n"), 0o600) //nolint:errcheck
+		entry, err := s.AddFile(other, DefaultGroup)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.GitInfo != nil {
+			t.Errorf("got %+v, want no git metadata", entry.GitInfo)
+		}
+	})
+}
+
+func TestGitInfo_RefreshesOnCommit(t *testing.T) {
+	ctx, cancel := donegroup.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewState(ctx)
+	t.Cleanup(s.CloseAllSubscribers)
+	s.gitInfoDebounce = 50 * time.Millisecond
+
+	dir := t.TempDir()
+	first := commitFiles(t, dir, map[string]string{"a.md": "# A\n"})
+	path := filepath.Join(dir, "a.md")
+	os.WriteFile(path, []byte("# A edited\n"), 0o600) //nolint:errcheck
+	if _, err := s.AddFile(path, DefaultGroup); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForGitInfo := func(desc string, ok func(*GitInfo) bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if entry := s.FindFile(FileID(path), DefaultGroup); entry != nil {
+				s.mu.RLock()
+				info := entry.GitInfo
+				s.mu.RUnlock()
+				if info != nil && ok(info) {
+					return
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("timed out waiting for %s", desc)
+	}
+	waitForGitInfo("modified status", func(info *GitInfo) bool {
+		return info.Status == "modified" && info.Commit == first
+	})
+
+	// Committing only touches the git directory, not the watched file.
+	cmd := exec.Command("git", "commit", "-q", "-am", "edit")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=mo", "GIT_AUTHOR_EMAIL=mo@example.com",
+		"GIT_COMMITTER_NAME=mo", "GIT_COMMITTER_EMAIL=mo@example.com",
+		"GIT_CONFIG_GLOBAL=/dev/null",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git commit: %v\n%s", err, out)
+	}
+	waitForGitInfo("clean status after commit", func(info *GitInfo) bool {
+		return info.Status == "" && info.Commit != first
+	})
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/k1LoW/mo/internal/git"
+)
+
+// GitSource identifies a file read from a git revision.
+type GitSource struct {
+	Root   string `json:"root"`   // work tree root
+	Commit string `json:"commit"` // resolved commit hash
+	Path   string `json:"path"`   // slash-separated path relative to Root
+	Rev    string `json:"rev"`    // revision as given by the user, e.g. HEAD~3
+}
+
+// AbsPath returns the path of the file in the work tree.
+func (src *GitSource) AbsPath() string {
+	return filepath.Join(src.Root, filepath.FromSlash(src.Path))
+}
+
+// AddGitFile adds a read-only entry holding content read from a git revision.
+// Relative assets of the entry are served from the same revision.
+func (s *State) AddGitFile(src GitSource, name, content, groupName string) *FileEntry {
+	h := sha256.New()
+	h.Write([]byte("git:"))
+	h.Write([]byte(src.AbsPath()))
+	h.Write([]byte{0})
+	h.Write([]byte(src.Commit))
+	id := "r" + hex.EncodeToString(h.Sum(nil))[:7]
+
+	return s.addInMemoryFile(&FileEntry{
+		Name:     name,
+		ID:       id,
+		Uploaded: true,
+		Revision: src.Rev,
+		content:  content,
+		git:      &src,
+	}, groupName)
+}
+
+// serveGitAsset serves relPath, relative to the directory of the entry read
+// from src, at the same revision.
+func serveGitAsset(w http.ResponseWriter, src *GitSource, relPath string) {
+	p := path.Clean(path.Join(path.Dir(src.Path), relPath))
+	if p == ".." || strings.HasPrefix(p, "../") {
+		http.Error(w, "path outside the repository", http.StatusBadRequest)
+		return
+	}
+	data, err := git.Show(src.Root, src.Commit, p)
+	if err != nil {
+		if errors.Is(err, git.ErrNotFound) {
+			http.Error(w, "file not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ctype := mime.TypeByExtension(path.Ext(p))
+	if ctype == "" {
+		ctype = http.DetectContentType(data)
+	}
+	w.Header().Set("Content-Type", ctype)
+	// Content at a commit never changes.
+	w.Header().Set("Cache-Control", "max-age=31536000, immutable")
+	w.Write(data) //nolint:errcheck
+}
+
+type worktreeDiffResponse struct {
+	ID      string     `json:"id"`
+	Rev     string     `json:"rev"`
+	Path    string     `json:"path"`
+	Exists  bool       `json:"exists"`
+	Hunks   []DiffHunk `json:"hunks"`
+	Unified string     `json:"unified"`
+}
+
+// handleWorktreeDiff diffs an entry read from a git revision, or a review
+// entry's merge-base version, against the current content of the same file
+// in the work tree.
+func handleWorktreeDiff(state *State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		group, err := resolveGroupFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		entry := state.FindFile(r.PathValue("id"), group)
+		if entry == nil {
+			http.Error(w, "file not found", http.StatusNotFound)
+			return
+		}
+
+		var src *GitSource
+		var old string
+		switch {
+		case entry.git != nil:
+			src = entry.git
+			old = entry.content
+		case entry.gitBase != nil:
+			src = entry.gitBase
+			data, err := git.Show(src.Root, src.Commit, src.Path)
+			if err != nil && !errors.Is(err, git.ErrNotFound) {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			// A file added on the branch diffs against nothing.
+			old = string(data)
+		default:
+			http.Error(w, "file was not read from a git revision", http.StatusBadRequest)
+			return
+		}
+
+		absPath := src.AbsPath()
+		exists := true
+		current, err := os.ReadFile(absPath) //nolint:gosec // Path is server-managed, not user-supplied
+		if err != nil {
+			if !os.IsNotExist(err) {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			exists = false
+		}
+
+		hunks := buildHunks(diffLines(splitLines(old), splitLines(string(current))), defaultDiffContext, true)
+		if hunks == nil {
+			hunks = []DiffHunk{}
+		}
+		resp := worktreeDiffResponse{
+			ID:      entry.ID,
+			Rev:     src.Rev,
+			Path:    absPath,
+			Exists:  exists,
+			Hunks:   hunks,
+			Unified: unifiedDiff(src.Path+"@"+src.Rev, src.Path, hunks),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			slog.Error("failed to encode response", "error", err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// commitFiles writes files into the git repository at dir (initializing it if
+// needed), commits them and returns the commit hash.
+func commitFiles(t *testing.T, dir string, files map[string]string) string {
+	t.Helper()
+	gitCmd := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=mo", "GIT_AUTHOR_EMAIL=mo@example.com",
+			"GIT_COMMITTER_NAME=mo", "GIT_COMMITTER_EMAIL=mo@example.com",
+			"GIT_CONFIG_GLOBAL=/dev/null",
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		gitCmd("init", "-q", "-b", "main")
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0o700)     //nolint:errcheck
+		os.WriteFile(p, []byte(content), 0o600) //nolint:errcheck
+	}
+	gitCmd("add", "-A")
+	gitCmd("commit", "-q", "-m", "update")
+	return gitCmd("rev-parse", "HEAD")
+}
+
+func TestAddGitFile(t *testing.T) {
+	dir := t.TempDir()
+	commit := commitFiles(t, dir, map[string]string{
+		"docs/guide.md":     "# Guide\n\n![logo](img/logo.svg)\n",
+		"docs/img/logo.svg": "<svg>old</svg>",
+	})
+	commitFiles(t, dir, map[string]string{
+		"docs/guide.md":     "# Guide\n\nNew intro.\n\n![logo](img/logo.svg)\n",
+		"docs/img/logo.svg": "<svg>new</svg>",
+	})
+
+	s := newTestState(t)
+	src := GitSource{Root: dir, Commit: commit, Path: "docs/guide.md", Rev: "HEAD~1"}
+	entry := s.AddGitFile(src, "guide.md@HEAD~1", "# Guide\n\n![logo](img/logo.svg)\n", DefaultGroup)
+	if !entry.Uploaded || entry.Revision != "HEAD~1" || entry.Title != "Guide" {
+		t.Fatalf("got entry %+v, want read-only entry at HEAD~1 titled Guide", entry)
+	}
+	if again := s.AddGitFile(src, "guide.md@HEAD~1", "ignored", DefaultGroup); again != entry {
+		t.Error("adding the same revision twice should return the existing entry")
+	}
+
+	handler := NewHandler(s)
+	base := "/_/api/groups/default/files/" + entry.ID
+
+	t.Run("serves assets at the same revision", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", base+"/raw/img/logo.svg", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+		}
+		if got := rec.Body.String(); got != "<svg>old</svg>" {
+			t.Errorf("got %q, want the asset at HEAD~1", got)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "image/svg+xml") {
+			t.Errorf("got Content-Type %q, want image/svg+xml", ct)
+		}
+	})
+
+	t.Run("rejects assets outside the repository", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		serveGitAsset(rec, entry.git, "../../../etc/passwd")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("got status %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("diffs against the work tree", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", base+"/worktree-diff", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+		}
+		var resp worktreeDiffResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if !resp.Exists || len(resp.Hunks) != 1 {
+			t.Fatalf("got exists=%v hunks=%d, want one hunk", resp.Exists, len(resp.Hunks))
+		}
+		if !strings.Contains(resp.Unified, "+New intro.\n") {
+			t.Errorf("unified diff missing added line:\n%s", resp.Unified)
+		}
+	})
+
+	t.Run("is kept in restore data", func(t *testing.T) {
+		data := s.snapshotRestoreData()
+		if len(data.UploadedFiles) != 1 || data.UploadedFiles[0].Git == nil {
+			t.Fatalf("got uploaded files %+v, want the git entry", data.UploadedFiles)
+		}
+		if data.UploadedFiles[0].Git.Commit != commit {
+			t.Errorf("got commit %q, want %q", data.UploadedFiles[0].Git.Commit, commit)
+		}
+	})
+}
+
+func TestHandleUploadFile_GitSource(t *testing.T) {
+	s := newTestState(t)
+	handler := NewHandler(s)
+
+	t.Run("rejects an invalid commit", func(t *testing.T) {
+		body, _ := json.Marshal(uploadFileRequest{
+			Name:    "a.md@main",
+			Content: "# A",
+			Git:     &GitSource{Root: t.TempDir(), Commit: "--output=/tmp/x", Path: "a.md", Rev: "main"},
+		})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/_/api/groups/default/files/upload", bytes.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("got status %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("adds a revision entry", func(t *testing.T) {
+		body, _ := json.Marshal(uploadFileRequest{
+			Name:    "a.md@main",
+			Content: "# A",
+			Git:     &GitSource{Root: t.TempDir(), Commit: strings.Repeat("a", 40), Path: "a.md", Rev: "main"},
+		})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/_/api/groups/default/files/upload", bytes.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+		}
+		var entry FileEntry
+		if err := json.NewDecoder(rec.Body).Decode(&entry); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if entry.Revision != "main" || !strings.HasPrefix(entry.ID, "r") {
+			t.Errorf("got entry %+v, want a revision entry", entry)
+		}
+	})
+}
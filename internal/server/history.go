@@ -0,0 +1,275 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultHistoryLimit is the number of revisions kept in memory per file.
+	defaultHistoryLimit = 20
+	// maxHistoryContentSize skips history for files larger than this, so a
+	// huge generated file does not pin many copies of itself in memory.
+	maxHistoryContentSize = 2 << 20 // 2MB
+)
+
+// Revision is a snapshot of a file's content kept for diffing.
+type Revision struct {
+	Revision int       `json:"revision"`
+	Time     time.Time `json:"time"`
+	Lines    int       `json:"lines"`
+	content  string
+}
+
+// fileHistory is the bounded list of recent revisions of one file.
+type fileHistory struct {
+	next      int
+	revisions []*Revision
+}
+
+// fileChangedEvent is the payload of the file-changed SSE event. Revision and
+// change information is omitted when no history is available for the file.
+type fileChangedEvent struct {
+	ID               string     `json:"id"`
+	Revision         int        `json:"revision,omitempty"`
+	FirstChangedLine int        `json:"firstChangedLine,omitempty"`
+	Hunks            []DiffHunk `json:"hunks,omitempty"`
+}
+
+// recordRevision appends content as a new revision of the file with the given
+// ID. It returns the previous and the new latest revision; prev is nil for the
+// first revision, and cur equals prev when the content did not change.
+func (s *State) recordRevision(id, content string) (prev, cur *Revision) {
+	if len(content) > maxHistoryContentSize {
+		return nil, nil
+	}
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	h, ok := s.history[id]
+	if !ok {
+		h = &fileHistory{next: 1}
+		s.history[id] = h
+	}
+	if n := len(h.revisions); n > 0 {
+		prev = h.revisions[n-1]
+		if prev.content == content {
+			return prev, prev
+		}
+	}
+	cur = &Revision{
+		Revision: h.next,
+		Time:     time.Now(),
+		Lines:    strings.Count(content, "\n") + 1,
+		content:  content,
+	}
+	h.next++
+	h.revisions = append(h.revisions, cur)
+	if limit := s.historyLimit; limit > 0 && len(h.revisions) > limit {
+		h.revisions = append(h.revisions[:0:0], h.revisions[len(h.revisions)-limit:]...)
+	}
+	return prev, cur
+}
+
+// seedHistory records the initial revision of a file read from disk, unless
+// the file already has history (e.g. it is open in another group).
+func (s *State) seedHistory(id, absPath string) {
+	s.historyMu.Lock()
+	_, ok := s.history[id]
+	s.historyMu.Unlock()
+	if ok {
+		return
+	}
+	content, err := s.readDocument(absPath)
+	if err != nil {
+		return
+	}
+	s.recordRevision(id, content)
+}
+
+// forgetHistory drops the revisions of a file that is no longer open.
+func (s *State) forgetHistory(id string) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	delete(s.history, id)
+}
+
+// History returns the revisions kept for the file with the given ID, oldest
+// first.
+func (s *State) History(id string) []Revision {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	h, ok := s.history[id]
+	if !ok {
+		return nil
+	}
+	result := make([]Revision, len(h.revisions))
+	for i, r := range h.revisions {
+		result[i] = *r
+	}
+	return result
+}
+
+// findRevision returns the kept revision with the given number. A zero rev
+// selects the latest revision and a negative rev counts back from it.
+func (s *State) findRevision(id string, rev int) *Revision {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	h, ok := s.history[id]
+	if !ok || len(h.revisions) == 0 {
+		return nil
+	}
+	if rev <= 0 {
+		i := len(h.revisions) - 1 + rev
+		if i < 0 {
+			return nil
+		}
+		return h.revisions[i]
+	}
+	for _, r := range h.revisions {
+		if r.Revision == rev {
+			return r
+		}
+	}
+	return nil
+}
+
+// changeEvent builds the file-changed payload describing the change from
+// prev to cur.
+func changeEvent(id string, prev, cur *Revision) fileChangedEvent {
+	e := fileChangedEvent{ID: id}
+	if cur == nil {
+		return e
+	}
+	e.Revision = cur.Revision
+	if prev == nil || prev == cur {
+		return e
+	}
+	newLines := splitLines(cur.content)
+	ops := diffLines(splitLines(prev.content), newLines)
+	e.FirstChangedLine = firstChangedLine(ops, len(newLines))
+	e.Hunks = buildHunks(ops, 0, false)
+	return e
+}
+
+type fileHistoryResponse struct {
+	ID        string     `json:"id"`
+	Revisions []Revision `json:"revisions"`
+}
+
+type fileDiffResponse struct {
+	ID      string     `json:"id"`
+	From    Revision   `json:"from"`
+	To      Revision   `json:"to"`
+	Hunks   []DiffHunk `json:"hunks"`
+	Unified string     `json:"unified"`
+}
+
+func handleFileHistory(state *State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		group, err := resolveGroupFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		id := r.PathValue("id")
+		if state.FindFile(id, group) == nil {
+			http.Error(w, "file not found", http.StatusNotFound)
+			return
+		}
+
+		resp := fileHistoryResponse{ID: id, Revisions: state.History(id)}
+		if resp.Revisions == nil {
+			resp.Revisions = []Revision{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			slog.Error("failed to encode response", "error", err)
+		}
+	}
+}
+
+// handleFileDiff diffs two kept revisions of a file. "to" defaults to the
+// latest revision and "from" to the one before "to".
+func handleFileDiff(state *State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		group, err := resolveGroupFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		id := r.PathValue("id")
+		if state.FindFile(id, group) == nil {
+			http.Error(w, "file not found", http.StatusNotFound)
+			return
+		}
+
+		q := r.URL.Query()
+		toRev, err := parseRevisionParam(q.Get("to"), 0)
+		if err != nil {
+			http.Error(w, "invalid to revision", http.StatusBadRequest)
+			return
+		}
+		diffContext, err := parseRevisionParam(q.Get("context"), defaultDiffContext)
+		if err != nil {
+			http.Error(w, "invalid context", http.StatusBadRequest)
+			return
+		}
+		to := state.findRevision(id, toRev)
+		if to == nil {
+			http.Error(w, "revision not found", http.StatusNotFound)
+			return
+		}
+		fromRev, err := parseRevisionParam(q.Get("from"), to.Revision-1)
+		if err != nil {
+			http.Error(w, "invalid from revision", http.StatusBadRequest)
+			return
+		}
+		var from *Revision
+		if fromRev > 0 {
+			from = state.findRevision(id, fromRev)
+		}
+		if from == nil {
+			http.Error(w, "revision not found", http.StatusNotFound)
+			return
+		}
+
+		hunks := buildHunks(diffLines(splitLines(from.content), splitLines(to.content)), diffContext, true)
+		if hunks == nil {
+			hunks = []DiffHunk{}
+		}
+		resp := fileDiffResponse{
+			ID:    id,
+			From:  *from,
+			To:    *to,
+			Hunks: hunks,
+			Unified: unifiedDiff(
+				fmt.Sprintf("revision %d", from.Revision),
+				fmt.Sprintf("revision %d", to.Revision),
+				hunks,
+			),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			slog.Error("failed to encode response", "error", err)
+		}
+	}
+}
+
+// parseRevisionParam parses a non-negative integer query parameter, returning
+// def when it is empty.
+func parseRevisionParam(v string, def int) (int, error) {
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid value: %q", v)
+	}
+	return n, nil
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordRevision(t *testing.T) {
+	t.Run("skips unchanged content", func(t *testing.T) {
+		s := newTestState(t)
+		_, first := s.recordRevision("id", "a")
+		prev, cur := s.recordRevision("id", "a")
+		if prev != first || cur != first {
+			t.Fatal("unchanged content should not create a new revision")
+		}
+		if got := len(s.History("id")); got != 1 {
+			t.Errorf("got %d revisions, want 1", got)
+		}
+	})
+
+	t.Run("keeps only the latest revisions", func(t *testing.T) {
+		s := newTestState(t)
+		s.historyLimit = 3
+		for i := range 5 {
+			s.recordRevision("id", fmt.Sprintf("rev %d", i))
+		}
+		revs := s.History("id")
+		if len(revs) != 3 {
+			t.Fatalf("got %d revisions, want 3", len(revs))
+		}
+		if revs[0].Revision != 3 || revs[2].Revision != 5 {
+			t.Errorf("got revisions %d..%d, want 3..5", revs[0].Revision, revs[2].Revision)
+		}
+		if s.findRevision("id", 1) != nil {
+			t.Error("dropped revision should not be found")
+		}
+		if r := s.findRevision("id", -1); r == nil || r.Revision != 4 {
+			t.Errorf("got %v for rev -1, want revision 4", r)
+		}
+	})
+
+	t.Run("skips oversized content", func(t *testing.T) {
+		s := newTestState(t)
+		_, cur := s.recordRevision("id", strings.Repeat("x", maxHistoryContentSize+1))
+		if cur != nil {
+			t.Fatal("oversized content should not be recorded")
+		}
+	})
+}
+
+func TestNotifyFileChangedByPath_ReportsChangedRegion(t *testing.T) {
+	s := newTestState(t)
+
+	f := filepath.Join(t.TempDir(), "doc.md")
+	os.WriteFile(f, []byte("# Doc\n\none\ntwo\nthree\n"), 0o600) //nolint:errcheck
+	entry, err := s.AddFile(f, DefaultGroup)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ch := s.Subscribe()
+	defer s.Unsubscribe(ch)
+
+	os.WriteFile(f, []byte("# Doc\n\none\nTWO\nthree\nfour\n"), 0o600) //nolint:errcheck
+	s.notifyFileChangedByPath(f)
+
+	var got fileChangedEvent
+	for got.ID == "" {
+		select {
+		case e := <-ch:
+			if e.Name != eventFileChanged {
+				continue
+			}
+			if err := json.Unmarshal([]byte(e.Data), &got); err != nil {
+				t.Fatalf("failed to decode event: %v", err)
+			}
+		default:
+			t.Fatal("no file-changed event was sent")
+		}
+	}
+	if got.ID != entry.ID {
+		t.Fatalf("got id %q, want %q", got.ID, entry.ID)
+	}
+	if got.Revision != 2 {
+		t.Errorf("got revision %d, want 2", got.Revision)
+	}
+	if got.FirstChangedLine != 4 {
+		t.Errorf("got firstChangedLine %d, want 4", got.FirstChangedLine)
+	}
+	want := []DiffHunk{
+		{OldStart: 4, OldLines: 1, NewStart: 4, NewLines: 1},
+		{OldStart: 6, OldLines: 0, NewStart: 6, NewLines: 1},
+	}
+	if len(got.Hunks) != len(want) {
+		t.Fatalf("got hunks %+v, want %+v", got.Hunks, want)
+	}
+	for i := range want {
+		if !sameHunkRange(got.Hunks[i], want[i]) {
+			t.Errorf("hunk %d: got %+v, want %+v", i, got.Hunks[i], want[i])
+		}
+	}
+}
+
+func TestRemoveFile_ForgetsHistory(t *testing.T) {
+	s := newTestState(t)
+	f := filepath.Join(t.TempDir(), "doc.md")
+	os.WriteFile(f, []byte("# Doc"), 0o600) //nolint:errcheck
+	entry, err := s.AddFile(f, DefaultGroup)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.AddFile(f, "other"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s.RemoveFile(entry.ID, DefaultGroup)
+	if len(s.History(entry.ID)) == 0 {
+		t.Fatal("history should be kept while another group shows the file")
+	}
+	s.RemoveFile(entry.ID, "other")
+	if len(s.History(entry.ID)) != 0 {
+		t.Fatal("history should be dropped once the file is closed everywhere")
+	}
+}
+
+func TestHandleFileHistoryAndDiff(t *testing.T) {
+	s := newTestState(t)
+	f := filepath.Join(t.TempDir(), "doc.md")
+	os.WriteFile(f, []byte("a\nb\nc\n"), 0o600) //nolint:errcheck
+	entry, err := s.AddFile(f, DefaultGroup)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	os.WriteFile(f, []byte("a\nB\nc\n"), 0o600) //nolint:errcheck
+	s.notifyFileChangedByPath(f)
+	os.WriteFile(f, []byte("a\nB\nc\nd\n"), 0o600) //nolint:errcheck
+	s.notifyFileChangedByPath(f)
+
+	handler := NewHandler(s)
+	base := "/_/api/groups/default/files/" + entry.ID
+
+	t.Run("history lists revisions", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", base+"/history", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+		}
+		var resp fileHistoryResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(resp.Revisions) != 3 {
+			t.Fatalf("got %d revisions, want 3", len(resp.Revisions))
+		}
+	})
+
+	t.Run("diff defaults to the latest change", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", base+"/diff", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+		}
+		var resp fileDiffResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.From.Revision != 2 || resp.To.Revision != 3 {
+			t.Errorf("got %d..%d, want 2..3", resp.From.Revision, resp.To.Revision)
+		}
+		if !strings.Contains(resp.Unified, "+d\n") {
+			t.Errorf("unified diff missing inserted line:\n%s", resp.Unified)
+		}
+	})
+
+	t.Run("diff between explicit revisions", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", base+"/diff?from=1&to=3&context=0", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+		}
+		var resp fileDiffResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(resp.Hunks) != 2 {
+			t.Errorf("got %d hunks, want 2", len(resp.Hunks))
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			url  string
+			want int
+		}{
+			{base + "/diff?from=9", http.StatusNotFound},
+			{base + "/diff?to=x", http.StatusBadRequest},
+			{"/_/api/groups/default/files/nope/history", http.StatusNotFound},
+		}
+		for _, tt := range tests {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", tt.url, nil))
+			if rec.Code != tt.want {
+				t.Errorf("%s: got status %d, want %d", tt.url, rec.Code, tt.want)
+			}
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+const eventNavigate = "navigate"
+
+// Location is a place in a document: a heading anchor or a source line.
+type Location struct {
+	Anchor string `json:"anchor,omitempty"`
+	Line   int    `json:"line,omitempty"`
+}
+
+type navigateEvent struct {
+	Group string `json:"group"`
+	ID    string `json:"id"`
+	Location
+}
+
+// NavigateResponse reports whether a browser received a navigation.
+type NavigateResponse struct {
+	Delivered bool `json:"delivered"`
+}
+
+// Navigate tells the connected browsers to show the file id of group at loc.
+// It reports whether any browser is connected to receive it.
+func (s *State) Navigate(id, group string, loc Location) (bool, error) {
+	if s.FindFile(id, group) == nil {
+		return false, ErrFileNotFound
+	}
+	b, err := json.Marshal(navigateEvent{Group: group, ID: id, Location: loc})
+	if err != nil {
+		return false, err
+	}
+	s.subMu.RLock()
+	connected := len(s.subscribers) > 0
+	s.subMu.RUnlock()
+	if !connected {
+		return false, nil
+	}
+	s.sendEvent(sseEvent{Name: eventNavigate, Data: string(b)})
+	return true, nil
+}
+
+func handleNavigate(state *State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		group, err := resolveGroupFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		id := r.PathValue("id")
+		if id == "" {
+			http.Error(w, "missing file id", http.StatusBadRequest)
+			return
+		}
+		var loc Location
+		if err := json.NewDecoder(r.Body).Decode(&loc); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if loc.Line < 0 {
+			http.Error(w, "line must be positive", http.StatusBadRequest)
+			return
+		}
+		delivered, err := state.Navigate(id, group, loc)
+		if err != nil {
+			if errors.Is(err, ErrFileNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(NavigateResponse{Delivered: delivered}); err != nil {
+			slog.Error("failed to encode response", "error", err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandleNavigate(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "README.md")
+	if err := os.WriteFile(p, []byte("# Title\n\n## Installation\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestState(t)
+	entry, err := s.AddFile(p, DefaultGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewHandler(s)
+
+	navigate := func(t *testing.T, id, body string) (*httptest.ResponseRecorder, NavigateResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/_/api/groups/default/files/"+id+"/navigate", strings.NewReader(body)))
+		var resp NavigateResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec, resp
+	}
+
+	t.Run("not delivered without a browser", func(t *testing.T) {
+		rec, resp := navigate(t, entry.ID, `{"anchor":"installation"}`)
+		if rec.Code != http.StatusOK || resp.Delivered {
+			t.Errorf("got %d %+v, want 200 and not delivered", rec.Code, resp)
+		}
+	})
+
+	t.Run("sent to connected browsers", func(t *testing.T) {
+		ch := s.Subscribe()
+		defer s.Unsubscribe(ch)
+		rec, resp := navigate(t, entry.ID, `{"line":3}`)
+		if rec.Code != http.StatusOK || !resp.Delivered {
+			t.Fatalf("got %d %+v, want 200 and delivered", rec.Code, resp)
+		}
+		e := <-ch
+		if e.Name != eventNavigate {
+			t.Fatalf("got event %q, want %q", e.Name, eventNavigate)
+		}
+		want := `{"group":"default","id":"` + entry.ID + `","line":3}`
+		if e.Data != want {
+			t.Errorf("got %s, want %s", e.Data, want)
+		}
+	})
+
+	t.Run("unknown file", func(t *testing.T) {
+		rec, _ := navigate(t, "missing", `{"line":1}`)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("got status %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
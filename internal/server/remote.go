@@ -0,0 +1,272 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+)
+
+// RemoteSource identifies a document fetched over HTTP(S).
+type RemoteSource struct {
+	URL string `json:"url"`
+	// Interval is how often the URL is polled for changes; zero uses
+	// DefaultRemoteInterval.
+	Interval time.Duration `json:"interval,omitempty"`
+}
+
+const (
+	// DefaultRemoteInterval is the default poll interval of remote entries.
+	DefaultRemoteInterval = 30 * time.Second
+	// MinRemoteInterval keeps a misconfigured interval from hammering the
+	// remote server.
+	MinRemoteInterval = time.Second
+
+	remoteMaxSize      = 10 << 20 // 10MB, the same limit as uploads
+	remoteFetchTimeout = 30 * time.Second
+	remoteTick         = time.Second
+)
+
+// remoteState is the source of a remote entry and the validators of the
+// last response, guarded by s.mu.
+type remoteState struct {
+	src          RemoteSource
+	etag         string
+	lastModified string
+	checked      time.Time
+}
+
+func (src RemoteSource) interval() time.Duration {
+	if src.Interval <= 0 {
+		return DefaultRemoteInterval
+	}
+	return max(src.Interval, MinRemoteInterval)
+}
+
+// ParseRemoteURL reports whether s is an http(s) URL that can be opened as a
+// remote entry.
+func ParseRemoteURL(s string) (*url.URL, bool) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, false
+	}
+	return u, true
+}
+
+func remoteID(rawURL string) string {
+	h := sha256.New()
+	h.Write([]byte("remote:"))
+	h.Write([]byte(rawURL))
+	return "h" + hex.EncodeToString(h.Sum(nil))[:7]
+}
+
+// remoteName is the entry name for u: the last path segment, or the host for
+// a URL without a path.
+func remoteName(u *url.URL) string {
+	if name := path.Base(u.Path); name != "." && name != "/" {
+		return name
+	}
+	return u.Host
+}
+
+// errNotModified is returned by fetchRemote when the validators still match.
+var errNotModified = errors.New("not modified")
+
+// fetchRemote fetches src, sending the validators of rs when given. It
+// returns the body and the validators of the response.
+func (s *State) fetchRemote(ctx context.Context, src RemoteSource, rs *remoteState) (content string, etag, lastModified string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, remoteFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
+	if err != nil {
+		return "", "", "", err
+	}
+	if rs != nil {
+		if rs.etag != "" {
+			req.Header.Set("If-None-Match", rs.etag)
+		}
+		if rs.lastModified != "" {
+			req.Header.Set("If-Modified-Since", rs.lastModified)
+		}
+	}
+	resp, err := s.remoteClient.Do(req) //nolint:gosec // URL is given by the user, like local paths
+	if err != nil {
+		return "", "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return "", "", "", errNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", "", "", fmt.Errorf("fetch %s: %s", src.URL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, remoteMaxSize+1))
+	if err != nil {
+		return "", "", "", fmt.Errorf("fetch %s: %w", src.URL, err)
+	}
+	if len(data) > remoteMaxSize {
+		return "", "", "", fmt.Errorf("%s: file too large (max 10MB)", src.URL)
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", "", "", fmt.Errorf("%s: %w", src.URL, ErrBinaryFile)
+	}
+	return string(data), resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"), nil
+}
+
+// AddRemoteFile fetches src and adds it as a read-only entry that is polled
+// for changes. Relative assets and links of the entry resolve against the URL.
+func (s *State) AddRemoteFile(ctx context.Context, src RemoteSource, groupName string) (*FileEntry, error) {
+	u, ok := ParseRemoteURL(src.URL)
+	if !ok {
+		return nil, fmt.Errorf("not an http(s) URL: %s", src.URL)
+	}
+	content, etag, lastModified, err := s.fetchRemote(ctx, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.addInMemoryFile(&FileEntry{
+		Name:     remoteName(u),
+		ID:       remoteID(src.URL),
+		Uploaded: true,
+		URL:      src.URL,
+		content:  content,
+		remote: &remoteState{
+			src:          src,
+			etag:         etag,
+			lastModified: lastModified,
+			checked:      time.Now(),
+		},
+	}, groupName), nil
+}
+
+// RestoreRemoteFile adds a remote entry with content saved by a previous
+// session. It is refreshed on the next poll.
+func (s *State) RestoreRemoteFile(src RemoteSource, name, content, groupName string) *FileEntry {
+	return s.addInMemoryFile(&FileEntry{
+		Name:     name,
+		ID:       remoteID(src.URL),
+		Uploaded: true,
+		URL:      src.URL,
+		content:  content,
+		remote:   &remoteState{src: src},
+	}, groupName)
+}
+
+// remoteLoop polls the remote entries whose interval has elapsed.
+func (s *State) remoteLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.remoteTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.refreshRemotes(ctx, time.Now())
+		}
+	}
+}
+
+// refreshRemotes re-fetches the remote entries due at now. Requests are
+// conditional, so unchanged documents cost a 304.
+func (s *State) refreshRemotes(ctx context.Context, now time.Time) {
+	type due struct {
+		entry *FileEntry
+		rs    remoteState
+	}
+	var entries []due
+	s.mu.Lock()
+	for _, g := range s.groups {
+		for _, f := range g.Files {
+			if f.remote != nil && now.Sub(f.remote.checked) >= f.remote.src.interval() {
+				f.remote.checked = now
+				entries = append(entries, due{f, *f.remote})
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	for _, d := range entries {
+		content, etag, lastModified, err := s.fetchRemote(ctx, d.rs.src, &d.rs)
+		if errors.Is(err, errNotModified) {
+			continue
+		}
+		if err != nil {
+			slog.Warn("failed to refresh remote file", "url", d.rs.src.URL, "error", err) //nolint:gosec // G706: structured logging fields, no injection risk
+			continue
+		}
+		s.updateRemoteContent(d.entry, content, etag, lastModified)
+	}
+}
+
+// updateRemoteContent replaces the content of a remote entry and notifies
+// clients when it changed.
+func (s *State) updateRemoteContent(entry *FileEntry, content, etag, lastModified string) {
+	s.mu.Lock()
+	entry.remote.etag = etag
+	entry.remote.lastModified = lastModified
+	s.mu.Unlock()
+	s.replaceInMemoryContent(entry, content)
+}
+
+// resolveRemote resolves ref, a relative link or asset path of a remote
+// entry, against the entry's URL.
+func resolveRemote(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	u := b.ResolveReference(r)
+	if _, ok := ParseRemoteURL(u.String()); !ok {
+		return "", fmt.Errorf("not an http(s) URL: %s", u)
+	}
+	return u.String(), nil
+}
+
+type remoteFileRequest struct {
+	URL      string        `json:"url"`
+	Interval time.Duration `json:"interval,omitempty"`
+}
+
+func handleAddRemoteFile(state *State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		group, err := resolveGroupFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var req remoteFileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, ok := ParseRemoteURL(req.URL); !ok {
+			http.Error(w, "url must be an http(s) URL", http.StatusBadRequest)
+			return
+		}
+
+		entry, err := state.AddRemoteFile(r.Context(), RemoteSource{URL: req.URL, Interval: req.Interval}, group)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(entry); err != nil {
+			slog.Error("failed to encode response", "error", err)
+		}
+	}
+}
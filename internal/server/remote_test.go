@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// remoteDoc serves a document with an ETag and counts the conditional
+// requests answered with 304.
+type remoteDoc struct {
+	mu          sync.Mutex
+	content     string
+	etag        string
+	notModified int
+}
+
+func (d *remoteDoc) set(content, etag string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.content, d.etag = content, etag
+}
+
+func (d *remoteDoc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if r.URL.Path != "/docs/README.md" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Header.Get("If-None-Match") == d.etag {
+		d.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", d.etag)
+	w.Write([]byte(d.content)) //nolint:errcheck
+}
+
+func TestRemoteFile(t *testing.T) {
+	doc := &remoteDoc{}
+	doc.set("# Remote\n\nfirst\n", `"v1"`)
+	ts := httptest.NewServer(doc)
+	t.Cleanup(ts.Close)
+
+	s := newTestState(t)
+	handler := NewHandler(s)
+	ctx := context.Background()
+	src := RemoteSource{URL: ts.URL + "/docs/README.md", Interval: time.Minute}
+	entry, err := s.AddRemoteFile(ctx, src, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Name != "README.md" || entry.Title != "Remote" || entry.URL != src.URL {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+
+	t.Run("content", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/_/api/groups/default/files/"+entry.ID+"/content", nil))
+		var resp fileContentResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Content != "# Remote\n\nfirst\n" {
+			t.Errorf("got %q", resp.Content)
+		}
+	})
+
+	t.Run("relative assets redirect to the origin", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/_/api/groups/default/files/"+entry.ID+"/raw/img/logo.png", nil))
+		if rec.Code != http.StatusFound {
+			t.Fatalf("got status %d, want %d", rec.Code, http.StatusFound)
+		}
+		if got, want := rec.Header().Get("Location"), ts.URL+"/docs/img/logo.png"; got != want {
+			t.Errorf("got Location %q, want %q", got, want)
+		}
+	})
+
+	t.Run("polls only after the interval", func(t *testing.T) {
+		s.refreshRemotes(ctx, time.Now())
+		s.refreshRemotes(ctx, time.Now().Add(2*time.Minute))
+		doc.mu.Lock()
+		defer doc.mu.Unlock()
+		if doc.notModified != 1 {
+			t.Errorf("got %d conditional requests, want 1", doc.notModified)
+		}
+	})
+
+	t.Run("refresh sends file-changed", func(t *testing.T) {
+		ch := s.Subscribe()
+		defer s.Unsubscribe(ch)
+		doc.set("# Remote\n\nsecond\n", `"v2"`)
+		s.refreshRemotes(ctx, time.Now().Add(10*time.Minute))
+
+		var got fileChangedEvent
+		for got.ID == "" {
+			select {
+			case e := <-ch:
+				if e.Name != eventFileChanged {
+					continue
+				}
+				if err := json.Unmarshal([]byte(e.Data), &got); err != nil {
+					t.Fatal(err)
+				}
+			default:
+				t.Fatal("no file-changed event was sent")
+			}
+		}
+		if got.ID != entry.ID || got.Revision != 2 || got.FirstChangedLine != 3 {
+			t.Errorf("unexpected event %+v", got)
+		}
+	})
+
+	t.Run("saved with its source", func(t *testing.T) {
+		s.mu.RLock()
+		data := s.snapshotRestoreData()
+		s.mu.RUnlock()
+		if len(data.UploadedFiles) != 1 {
+			t.Fatalf("got %d uploaded files, want 1", len(data.UploadedFiles))
+		}
+		uf := data.UploadedFiles[0]
+		if uf.Remote == nil || *uf.Remote != src || uf.Content != "# Remote\n\nsecond\n" {
+			t.Errorf("unexpected restore data %+v", uf)
+		}
+	})
+
+	t.Run("fetch errors", func(t *testing.T) {
+		if _, err := s.AddRemoteFile(ctx, RemoteSource{URL: ts.URL + "/missing.md"}, "default"); err == nil {
+			t.Error("want an error for a 404")
+		}
+		if _, err := s.AddRemoteFile(ctx, RemoteSource{URL: "ftp://example.com/a.md"}, "default"); err == nil {
+			t.Error("want an error for a non-http URL")
+		}
+	})
+}
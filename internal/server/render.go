@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/k1LoW/mo/internal/fencerender"
+	"github.com/k1LoW/mo/internal/notebook"
+)
+
+const (
+	// maxRenderCache bounds the number of rendered blocks kept; every edit of
+	// a diagram adds one.
+	maxRenderCache = 512
+	// maxConcurrentRenders bounds the renderer commands running at once.
+	maxConcurrentRenders = 4
+	// renderRetryDelay is how long a timed-out block is shown as failed
+	// before reading its document runs the command again.
+	renderRetryDelay = 30 * time.Second
+)
+
+// renderResult is the outcome of rendering a code block, cached by the hash
+// of the renderer and the block's code.
+type renderResult struct {
+	asset   notebook.Asset
+	err     string
+	retryAt time.Time // set for timeouts, which are not kept for good
+}
+
+// SetRenderers configures the commands that render fenced code blocks by
+// language, and how long each may run.
+func (s *State) SetRenderers(renderers []fencerender.Renderer, timeout time.Duration) {
+	byLang := make(map[string]fencerender.Renderer, len(renderers))
+	for _, r := range renderers {
+		byLang[r.Lang] = r
+	}
+	s.renderMu.Lock()
+	defer s.renderMu.Unlock()
+	s.renderers = byLang
+	s.renderTimeout = timeout
+	clear(s.renderCache)
+	if len(renderers) > 0 {
+		slog.Info("code block renderers configured", "langs", rendererLangs(renderers), "timeout", timeout)
+	}
+}
+
+// renderFences replaces the code blocks of the document at path that have a
+// renderer with the rendered SVG, served as an asset of the document. A block
+// that fails to render is kept, below an alert with the error. Commands run
+// in the background: until a block is rendered it is shown below a notice,
+// and the document is reloaded once it is.
+func (s *State) renderFences(path, content string) string {
+	s.renderMu.Lock()
+	renderers := s.renderers
+	timeout := s.renderTimeout
+	s.renderMu.Unlock()
+	if len(renderers) == 0 {
+		return content
+	}
+
+	var assets []notebook.Asset
+	content = fencerender.Replace(content, func(lang string) bool {
+		_, ok := renderers[lang]
+		return ok
+	}, func(lang, code, block string) string {
+		res, ok := s.render(path, renderers[lang], code, timeout)
+		switch {
+		case !ok:
+			return fmt.Sprintf("> [!NOTE]\n> Rendering %s...\n\n%s", lang, block)
+		case res.err != "":
+			return fmt.Sprintf("> [!CAUTION]\n> Failed to render %s: %s\n\n%s", lang, res.err, block)
+		}
+		assets = append(assets, res.asset)
+		return fmt.Sprintf("![%s](%s%s)\n", lang, documentAssetPath, res.asset.Name)
+	})
+	s.setDocumentAssets(path, assets)
+	return content
+}
+
+// render returns the result of rendering code with r for the document at
+// docPath, reusing the result of an earlier run on the same code. Failures
+// are cached too, so a broken command runs once per version of a block; a
+// timed-out one runs again after renderRetryDelay. When there is no result
+// yet, the command is started in the background and ok is false.
+func (s *State) render(docPath string, r fencerender.Renderer, code string, timeout time.Duration) (res renderResult, ok bool) {
+	h := sha256.New()
+	h.Write([]byte(r.String()))
+	h.Write([]byte{0})
+	h.Write([]byte(code))
+	key := hex.EncodeToString(h.Sum(nil))
+
+	s.renderMu.Lock()
+	res, ok = s.renderCache[key]
+	if ok && (res.retryAt.IsZero() || time.Now().Before(res.retryAt)) {
+		s.renderMu.Unlock()
+		return res, true
+	}
+	docs, running := s.renderPending[key]
+	if !running {
+		docs = make(map[string]struct{})
+		s.renderPending[key] = docs
+	}
+	docs[docPath] = struct{}{}
+	s.renderMu.Unlock()
+
+	if !running {
+		go s.runRender(key, r, code, timeout)
+	}
+	return renderResult{}, false
+}
+
+// runRender renders code with r, caches the result under key and reloads the
+// documents waiting for it.
+func (s *State) runRender(key string, r fencerender.Renderer, code string, timeout time.Duration) {
+	s.renderSem <- struct{}{}
+	out, err := r.Run(context.Background(), code, timeout)
+	<-s.renderSem
+
+	var res renderResult
+	if err != nil {
+		slog.Warn("failed to render code block", "lang", r.Lang, "error", err)
+		res = renderResult{err: err.Error()}
+		if errors.Is(err, fencerender.ErrTimedOut) {
+			// The machine may just have been busy.
+			res.retryAt = time.Now().Add(renderRetryDelay)
+		}
+	} else {
+		sum := sha256.Sum256(out)
+		res = renderResult{asset: notebook.Asset{
+			Name:        hex.EncodeToString(sum[:])[:16] + ".svg",
+			ContentType: "image/svg+xml",
+			Data:        out,
+		}}
+	}
+
+	s.renderMu.Lock()
+	if len(s.renderCache) >= maxRenderCache {
+		clear(s.renderCache)
+	}
+	s.renderCache[key] = res
+	docs := s.renderPending[key]
+	delete(s.renderPending, key)
+	s.renderMu.Unlock()
+
+	for doc := range docs {
+		s.scheduleFileChanged(doc)
+	}
+}
+
+// rendererLangs returns the languages with a renderer, for logging.
+func rendererLangs(renderers []fencerender.Renderer) string {
+	langs := make([]string, 0, len(renderers))
+	for _, r := range renderers {
+		langs = append(langs, r.Lang)
+	}
+	return strings.Join(langs, ",")
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k1LoW/mo/internal/fencerender"
+)
+
+func TestRenderFences(t *testing.T) {
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+	doc := filepath.Join(dir, "arch.md")
+	os.WriteFile(doc, []byte("# Arch\n\n```dot\ndigraph { a -> b }\n```\n\n```broken\nx\n```\n"), 0o600) //nolint:errcheck
+
+	s := newTestState(t)
+	s.SetRenderers([]fencerender.Renderer{
+		{Lang: "dot", Command: []string{"sh", "-c", `echo run >> "$0"; printf '<svg>%s</svg>' "$(cat)"`, runs}},
+		{Lang: "broken", Command: []string{"sh", "-c", "echo 'syntax error' >&2; exit 1"}},
+	}, time.Second)
+
+	entry, err := s.AddFile(doc, DefaultGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := s.Subscribe()
+	defer s.Unsubscribe(ch)
+
+	// Blocks are rendered in the background; the document is reloaded once
+	// they are.
+	content, err := s.readDocument(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "> [!NOTE]\n> Rendering dot...\n\n```dot\n") {
+		t.Errorf("no placeholder in %q", content)
+	}
+	for strings.Contains(content, "> [!NOTE]") {
+		waitForFileChanged(t, ch, entry.ID)
+		if content, err = s.readDocument(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := regexp.MustCompile(`!\[dot\]\(/_/api/assets/([0-9a-f]+\.svg)\)\n`).FindStringSubmatch(content)
+	if m == nil {
+		t.Fatalf("no rendered image in %q", content)
+	}
+	if !strings.Contains(content, "> [!CAUTION]\n> Failed to render broken: sh: syntax error\n\n```broken\nx\n```\n") {
+		t.Errorf("no inline error in %q", content)
+	}
+
+	rec := httptest.NewRecorder()
+	NewHandler(s).ServeHTTP(rec, httptest.NewRequest("GET", "/_/api/assets/"+m[1], nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "<svg>digraph { a -> b }</svg>" {
+		t.Errorf("got %d %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "image/svg+xml" {
+		t.Errorf("got Content-Type %q", got)
+	}
+
+	// Unchanged blocks are served from the cache.
+	if _, err := s.readDocument(doc); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(runs); string(data) != "run\n" {
+		t.Errorf("renderer ran %d times, want 1", strings.Count(string(data), "run"))
+	}
+}
+
+func TestRenderFences_RetriesTimeouts(t *testing.T) {
+	dir := t.TempDir()
+	doc := filepath.Join(dir, "slow.md")
+	os.WriteFile(doc, []byte("```dot\ndigraph {}\n```\n"), 0o600) //nolint:errcheck
+
+	s := newTestState(t)
+	s.SetRenderers([]fencerender.Renderer{
+		{Lang: "dot", Command: []string{"sleep", "5"}},
+	}, 50*time.Millisecond)
+	entry, err := s.AddFile(doc, DefaultGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := s.Subscribe()
+	defer s.Unsubscribe(ch)
+
+	if _, err := s.readDocument(doc); err != nil {
+		t.Fatal(err)
+	}
+	waitForFileChanged(t, ch, entry.ID)
+	content, err := s.readDocument(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "> [!CAUTION]\n> Failed to render dot: sleep timed out after 50ms") {
+		t.Fatalf("no timeout error in %q", content)
+	}
+
+	// Once the retry delay has passed, the block is rendered again.
+	s.renderMu.Lock()
+	for key, res := range s.renderCache {
+		res.retryAt = time.Now().Add(-time.Second)
+		s.renderCache[key] = res
+	}
+	s.renderMu.Unlock()
+	if content, err = s.readDocument(doc); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "Rendering dot...") {
+		t.Errorf("timed-out block was not rendered again: %q", content)
+	}
+}
+
+// waitForFileChanged waits for a file-changed event of the entry id on ch.
+func waitForFileChanged(t *testing.T, ch chan sseEvent, id string) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-ch:
+			if e.Name == eventFileChanged && strings.Contains(e.Data, id) {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("timed out waiting for file-changed of %s", id)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/k1LoW/mo/internal/git"
+)
+
+// ReviewData describes a review group: the files of a git work tree that
+// differ from the merge-base with Base, kept in sync as the branch changes.
+type ReviewData struct {
+	Group      string   `json:"group"`
+	Root       string   `json:"root"`
+	Base       string   `json:"base"`
+	Extensions []string `json:"extensions,omitempty"` // without leading dot; empty means "md"
+}
+
+// matches reports whether the slash-separated path p has one of the review's
+// extensions.
+func (rd ReviewData) matches(p string) bool {
+	return hasExtension(p, rd.Extensions)
+}
+
+// hasExtension reports whether the slash-separated path p has one of exts,
+// given without the leading dot. Empty exts means "md".
+func hasExtension(p string, exts []string) bool {
+	if len(exts) == 0 {
+		exts = []string{"md"}
+	}
+	return slices.Contains(exts, strings.TrimPrefix(path.Ext(p), "."))
+}
+
+// AddReview registers a review group, or replaces the one with the same
+// group name, and populates it with the files currently changed.
+func (s *State) AddReview(rd ReviewData) ([]*FileEntry, error) {
+	if rd.Root == "" || rd.Base == "" {
+		return nil, errors.New("review requires a repository root and a base revision")
+	}
+	if _, err := git.MergeBase(rd.Root, rd.Base); err != nil {
+		return nil, err
+	}
+
+	s.reviewMu.Lock()
+	s.reviews[rd.Group] = rd
+	delete(s.reviewTracked, rd.Group)
+	s.reviewMu.Unlock()
+	s.markDirty()
+
+	slog.Info("review added", "root", rd.Root, "base", rd.Base, "group", rd.Group) //nolint:gosec // G706: structured logging fields, no injection risk
+	s.watchGitDir(rd.Root)
+	return s.refreshReview(rd.Group)
+}
+
+// RemoveReview stops syncing a review group and closes the files it opened.
+func (s *State) RemoveReview(groupName string) bool {
+	s.reviewMu.Lock()
+	rd, ok := s.reviews[groupName]
+	delete(s.reviews, groupName)
+	s.reviewMu.Unlock()
+	if !ok {
+		return false
+	}
+
+	s.reviewRefreshMu.Lock()
+	for _, id := range s.reviewEntryIDs(groupName) {
+		s.RemoveFile(id, groupName)
+	}
+	s.watchReviewDirs(groupName, nil)
+	s.reviewRefreshMu.Unlock()
+	// Let the git watch go once no open file or review needs it.
+	s.scheduleGitRefresh(rd.Root)
+	s.markDirty()
+	slog.Info("review removed", "group", groupName)
+	return true
+}
+
+// Reviews returns the registered review groups ordered by group name.
+func (s *State) Reviews() []ReviewData {
+	s.reviewMu.Lock()
+	defer s.reviewMu.Unlock()
+	var result []ReviewData
+	for _, rd := range s.reviews {
+		result = append(result, rd)
+	}
+	slices.SortFunc(result, func(a, b ReviewData) int { return strings.Compare(a.Group, b.Group) })
+	return result
+}
+
+// reviewEntryIDs returns the IDs of the entries a review opened in groupName.
+func (s *State) reviewEntryIDs(groupName string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ids []string
+	if g, ok := s.groups[groupName]; ok {
+		for _, f := range g.Files {
+			if f.GitStatus != "" {
+				ids = append(ids, f.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// refreshReview brings a review group in line with the work tree: changed
+// files are opened with their status, deleted files are shown as they were
+// at the merge-base, and files that no longer differ are closed. It also
+// keeps the work-tree directories of the group watched.
+func (s *State) refreshReview(groupName string) ([]*FileEntry, error) {
+	// Refreshes run from both the watches and the API; serialize them so
+	// two passes don't race to add and remove the same entries.
+	s.reviewRefreshMu.Lock()
+	defer s.reviewRefreshMu.Unlock()
+
+	s.reviewMu.Lock()
+	rd, ok := s.reviews[groupName]
+	s.reviewMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no review for group %q", groupName)
+	}
+
+	mergeBase, err := git.MergeBase(rd.Root, rd.Base)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := git.ChangedFiles(rd.Root, mergeBase)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*FileEntry
+	keep := make(map[string]struct{})
+	var changedDirs []string
+	for _, c := range changes {
+		if !rd.matches(c.Path) {
+			continue
+		}
+		if c.Status != git.StatusDeleted {
+			changedDirs = append(changedDirs, path.Dir(c.Path))
+		}
+		src := GitSource{Root: rd.Root, Commit: mergeBase, Path: c.Path, Rev: rd.Base}
+		var entry *FileEntry
+		if c.Status == git.StatusDeleted {
+			content, err := git.Show(rd.Root, mergeBase, c.Path)
+			if err != nil {
+				slog.Warn("failed to read deleted file", "path", c.Path, "error", err)
+				continue
+			}
+			entry = s.AddGitFile(src, path.Base(c.Path), string(content), groupName)
+		} else {
+			entry, err = s.AddFile(src.AbsPath(), groupName)
+			if err != nil {
+				// Binary files and files removed since git listed them.
+				slog.Debug("skipping changed file", "path", c.Path, "error", err)
+				continue
+			}
+		}
+		s.setReviewStatus(entry, c.Status, &src)
+		keep[entry.ID] = struct{}{}
+		entries = append(entries, entry)
+	}
+
+	for _, id := range s.reviewEntryIDs(groupName) {
+		if _, ok := keep[id]; !ok {
+			s.RemoveFile(id, groupName)
+		}
+	}
+	s.watchReviewDirs(groupName, changedDirs)
+	return entries, nil
+}
+
+// watchReviewDirs watches the work-tree directories of a review group that
+// hold changed files (changedDirs, relative to the root) or tracked files with
+// the review's extensions, and the root itself, so that edits and new files
+// next to them refresh it. Commits and staging are seen through the git
+// directory. Watches the group no longer needs are dropped (all of them once
+// the review is removed). Callers hold s.reviewRefreshMu.
+func (s *State) watchReviewDirs(groupName string, changedDirs []string) {
+	s.reviewMu.Lock()
+	rd, ok := s.reviews[groupName]
+	old := s.reviewDirs[groupName]
+	tracked, listed := s.reviewTracked[groupName]
+	s.reviewMu.Unlock()
+
+	dirs := make(map[string]struct{})
+	if ok && s.watcher != nil {
+		if !listed {
+			tracked = s.listReviewTracked(rd)
+			s.reviewMu.Lock()
+			s.reviewTracked[groupName] = tracked
+			s.reviewMu.Unlock()
+		}
+		dirs[rd.Root] = struct{}{}
+		for _, d := range tracked {
+			dirs[filepath.Join(rd.Root, filepath.FromSlash(d))] = struct{}{}
+		}
+		for _, d := range changedDirs {
+			dirs[filepath.Join(rd.Root, filepath.FromSlash(d))] = struct{}{}
+		}
+	}
+	for d := range dirs {
+		if _, ok := old[d]; !ok {
+			s.addDirWatch(d)
+		}
+	}
+	for d := range old {
+		if _, ok := dirs[d]; !ok {
+			s.removeDirWatch(d)
+		}
+	}
+
+	s.reviewMu.Lock()
+	if len(dirs) == 0 {
+		delete(s.reviewDirs, groupName)
+		delete(s.reviewTracked, groupName)
+	} else {
+		s.reviewDirs[groupName] = dirs
+	}
+	s.reviewMu.Unlock()
+}
+
+// listReviewTracked returns the directories, relative to the root, of the
+// tracked files with the extensions of rd.
+func (s *State) listReviewTracked(rd ReviewData) []string {
+	exts := rd.Extensions
+	if len(exts) == 0 {
+		exts = []string{"md"}
+	}
+	pathspecs := make([]string, len(exts))
+	for i, ext := range exts {
+		pathspecs[i] = "*." + ext
+	}
+	files, err := git.TrackedFiles(rd.Root, pathspecs...)
+	if err != nil {
+		slog.Warn("failed to list tracked files", "root", rd.Root, "error", err)
+		return nil
+	}
+	dirs := make([]string, len(files))
+	for i, f := range files {
+		dirs[i] = path.Dir(f)
+	}
+	slices.Sort(dirs)
+	return slices.Compact(dirs)
+}
+
+// forgetReviewTracked drops the tracked directories listed for the review
+// groups at root, so that the next refresh lists them again. It is called
+// when the git directory changes, which is when the index may have.
+func (s *State) forgetReviewTracked(root string) {
+	s.reviewMu.Lock()
+	defer s.reviewMu.Unlock()
+	for group, rd := range s.reviews {
+		if rd.Root == root {
+			delete(s.reviewTracked, group)
+		}
+	}
+}
+
+// hasReviewAt reports whether a review group follows the work tree at root.
+func (s *State) hasReviewAt(root string) bool {
+	s.reviewMu.Lock()
+	defer s.reviewMu.Unlock()
+	for _, rd := range s.reviews {
+		if rd.Root == root {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshReviewsAt refreshes the review groups following the work tree at
+// root.
+func (s *State) refreshReviewsAt(root string) {
+	for _, rd := range s.Reviews() {
+		if rd.Root != root {
+			continue
+		}
+		if _, err := s.refreshReview(rd.Group); err != nil {
+			slog.Warn("failed to refresh review", "group", rd.Group, "error", err)
+		}
+	}
+}
+
+// handleReviewEvent schedules a refresh of the review groups whose work tree
+// contains an event path. Events inside the git directory are left to
+// handleGitDirEvent. paths holds the translated and raw forms of the event
+// path.
+func (s *State) handleReviewEvent(paths ...string) {
+	for _, rd := range s.Reviews() {
+		for _, p := range paths {
+			rel, err := filepath.Rel(rd.Root, p)
+			if err != nil || rel == "." || !filepath.IsLocal(rel) {
+				continue
+			}
+			if first, _, _ := strings.Cut(filepath.ToSlash(rel), "/"); first == ".git" {
+				continue
+			}
+			s.scheduleGitRefresh(rd.Root)
+			break
+		}
+	}
+}
+
+// setReviewStatus records the git status of a review entry and the merge-base
+// it is compared against, notifying clients when either changed.
+func (s *State) setReviewStatus(entry *FileEntry, status string, base *GitSource) {
+	s.mu.Lock()
+	changed := entry.GitStatus != status || entry.gitBase == nil || *entry.gitBase != *base
+	entry.GitStatus = status
+	entry.gitBase = base
+	s.mu.Unlock()
+	if changed {
+		s.sendEvent(sseEvent{Name: eventUpdate, Data: "{}"})
+	}
+}
+
+type reviewRequest struct {
+	Root       string   `json:"root"`
+	Base       string   `json:"base"`
+	Group      string   `json:"group"`
+	Extensions []string `json:"extensions,omitempty"`
+}
+
+// AddReviewResponse is the JSON response for the add-review endpoint.
+type AddReviewResponse struct {
+	Base  string       `json:"base"`
+	Files []*FileEntry `json:"files"`
+}
+
+func handleAddReview(state *State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req reviewRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		group, err := ResolveGroupName(req.Group)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !filepath.IsAbs(req.Root) {
+			http.Error(w, "root must be an absolute path", http.StatusBadRequest)
+			return
+		}
+
+		entries, err := state.AddReview(ReviewData{
+			Group:      group,
+			Root:       req.Root,
+			Base:       req.Base,
+			Extensions: req.Extensions,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if entries == nil {
+			entries = []*FileEntry{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(AddReviewResponse{Base: req.Base, Files: entries}); err != nil {
+			slog.Error("failed to encode response", "error", err)
+		}
+	}
+}
+
+func handleRemoveReview(state *State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		group, err := resolveGroupFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !state.RemoveReview(group) {
+			http.Error(w, "review not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
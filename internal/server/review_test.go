@@ -0,0 +1,265 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k1LoW/donegroup"
+)
+
+func reviewStatuses(s *State, group string) map[string]string {
+	got := make(map[string]string)
+	for _, g := range s.Groups() {
+		if g.Name != group {
+			continue
+		}
+		for _, f := range g.Files {
+			got[f.Name] = f.GitStatus
+		}
+	}
+	return got
+}
+
+func TestRefreshReview(t *testing.T) {
+	dir := t.TempDir()
+	base := commitFiles(t, dir, map[string]string{
+		"a.md":     "# A\n",
+		"b.md":     "# B\n",
+		"notes.md": "# Notes\n",
+	})
+	os.Remove(filepath.Join(dir, "b.md")) //nolint:errcheck
+	commitFiles(t, dir, map[string]string{
+		"a.md":      "# A\n\nchanged\n",
+		"docs/c.md": "# C\n",
+		"c.txt":     "not reviewed\n",
+	})
+	os.WriteFile(filepath.Join(dir, "untracked.md"), []byte("# New\n"), 0o600) //nolint:errcheck
+
+	s := newTestState(t)
+	if _, err := s.AddReview(ReviewData{Group: "review", Root: dir, Base: base}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"a.md":         "modified",
+		"b.md":         "deleted",
+		"c.md":         "added",
+		"untracked.md": "added",
+	}
+	got := reviewStatuses(s, "review")
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("%s: got status %q, want %q", name, got[name], status)
+		}
+	}
+
+	t.Run("reverted files leave the group", func(t *testing.T) {
+		os.WriteFile(filepath.Join(dir, "a.md"), []byte("# A\n"), 0o600) //nolint:errcheck
+		os.Remove(filepath.Join(dir, "untracked.md"))                    //nolint:errcheck
+		if _, err := s.refreshReview("review"); err != nil {
+			t.Fatal(err)
+		}
+		got := reviewStatuses(s, "review")
+		if _, ok := got["a.md"]; ok {
+			t.Errorf("a.md should be removed after reverting, got %v", got)
+		}
+		if _, ok := got["untracked.md"]; ok {
+			t.Errorf("untracked.md should be removed after deleting, got %v", got)
+		}
+		if got["c.md"] != "added" {
+			t.Errorf("c.md should stay, got %v", got)
+		}
+	})
+
+	t.Run("snapshot stores the review instead of its files", func(t *testing.T) {
+		s.mu.RLock()
+		data := s.snapshotRestoreData()
+		s.mu.RUnlock()
+		if len(data.Reviews) != 1 || data.Reviews[0].Base != base {
+			t.Fatalf("got reviews %+v, want one with base %s", data.Reviews, base)
+		}
+		if paths := data.Groups["review"]; len(paths) != 0 {
+			t.Errorf("got review group paths %v, want none", paths)
+		}
+		for _, uf := range data.UploadedFiles {
+			if uf.Group == "review" {
+				t.Errorf("deleted review file %q should not be saved as an upload", uf.Name)
+			}
+		}
+	})
+
+	t.Run("remove review closes its files", func(t *testing.T) {
+		if !s.RemoveReview("review") {
+			t.Fatal("RemoveReview returned false")
+		}
+		if got := reviewStatuses(s, "review"); len(got) != 0 {
+			t.Errorf("got %v, want an empty group", got)
+		}
+		if s.RemoveReview("review") {
+			t.Error("second RemoveReview should return false")
+		}
+	})
+}
+
+func TestAddReview_InvalidBase(t *testing.T) {
+	dir := t.TempDir()
+	commitFiles(t, dir, map[string]string{"a.md": "# A\n"})
+
+	s := newTestState(t)
+	if _, err := s.AddReview(ReviewData{Group: "review", Root: dir, Base: "no-such-branch"}); err == nil {
+		t.Fatal("expected error for unknown base")
+	}
+	if len(s.Reviews()) != 0 {
+		t.Error("failed review should not be registered")
+	}
+}
+
+func TestHandleReviewAPI(t *testing.T) {
+	dir := t.TempDir()
+	base := commitFiles(t, dir, map[string]string{"a.md": "# A\n\nold\n"})
+	commitFiles(t, dir, map[string]string{
+		"a.md": "# A\n\nnew\n",
+		"b.md": "# B\n",
+	})
+
+	s := newTestState(t)
+	handler := NewHandler(s)
+
+	body, _ := json.Marshal(map[string]string{"root": dir, "base": base, "group": "review"})
+	req := httptest.NewRequest("POST", "/_/api/reviews", strings.NewReader(string(body)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var resp AddReviewResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Files) != 2 {
+		t.Fatalf("got %d files, want 2", len(resp.Files))
+	}
+
+	t.Run("worktree diff against merge-base", func(t *testing.T) {
+		for _, f := range resp.Files {
+			req := httptest.NewRequest("GET", "/_/api/groups/review/files/"+f.ID+"/worktree-diff", nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s: got status %d, want %d", f.Name, rec.Code, http.StatusOK)
+			}
+			var diff worktreeDiffResponse
+			if err := json.NewDecoder(rec.Body).Decode(&diff); err != nil {
+				t.Fatal(err)
+			}
+			switch f.Name {
+			case "a.md":
+				if !strings.Contains(diff.Unified, "-old") || !strings.Contains(diff.Unified, "+new") {
+					t.Errorf("a.md: unexpected diff:\n%s", diff.Unified)
+				}
+			case "b.md":
+				if !strings.Contains(diff.Unified, "+# B") {
+					t.Errorf("b.md: added file should diff against nothing:\n%s", diff.Unified)
+				}
+			}
+		}
+	})
+
+	t.Run("relative root", func(t *testing.T) {
+		body, _ := json.Marshal(map[string]string{"root": "repo", "base": base})
+		req := httptest.NewRequest("POST", "/_/api/reviews", strings.NewReader(string(body)))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("got status %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/_/api/reviews/review", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("got status %d, want %d", rec.Code, http.StatusNoContent)
+		}
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("DELETE", "/_/api/reviews/review", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("got status %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestReview_RefreshesOnChange(t *testing.T) {
+	ctx, cancel := donegroup.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewState(ctx)
+	t.Cleanup(s.CloseAllSubscribers)
+	s.gitInfoDebounce = 50 * time.Millisecond
+
+	dir := t.TempDir()
+	base := commitFiles(t, dir, map[string]string{"docs/a.md": "# A\n"})
+	if _, err := s.AddReview(ReviewData{Group: "review", Root: dir, Base: base}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForStatuses := func(desc string, want map[string]string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if fmt.Sprint(reviewStatuses(s, "review")) == fmt.Sprint(want) {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("timed out waiting for %s: got %v, want %v", desc, reviewStatuses(s, "review"), want)
+	}
+
+	os.WriteFile(filepath.Join(dir, "docs", "a.md"), []byte("# A\n\nedited\n"), 0o600) //nolint:errcheck
+	waitForStatuses("the edited file", map[string]string{"a.md": "modified"})
+
+	// A new file next to a tracked one.
+	os.WriteFile(filepath.Join(dir, "docs", "b.md"), []byte("# B\n"), 0o600) //nolint:errcheck
+	waitForStatuses("the new file", map[string]string{"a.md": "modified", "b.md": "added"})
+
+	// A file in a new directory is seen once it is staged.
+	drafts := filepath.Join(dir, "drafts")
+	os.MkdirAll(drafts, 0o700)                                          //nolint:errcheck
+	os.WriteFile(filepath.Join(drafts, "c.md"), []byte("# C\n"), 0o600) //nolint:errcheck
+	if s.isWatchedDir(drafts) {
+		t.Error("a directory without tracked or changed files should not be watched")
+	}
+	cmd := exec.Command("git", "add", "drafts/c.md")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git add: %v\n%s", err, out)
+	}
+	waitForStatuses("the staged file", map[string]string{"a.md": "modified", "b.md": "added", "c.md": "added"})
+	if !s.isWatchedDir(drafts) {
+		t.Error("the directory of a changed file should be watched")
+	}
+
+	if !s.RemoveReview("review") {
+		t.Fatal("RemoveReview returned false")
+	}
+	s.mu.RLock()
+	watched := len(s.watchedDirs)
+	s.mu.RUnlock()
+	if watched != 0 {
+		t.Errorf("got %d watched directories after removing the review, want 0", watched)
+	}
+}
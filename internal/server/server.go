@@ -15,6 +15,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -23,6 +24,11 @@ import (
 	"github.com/bmatcuk/doublestar/v4"
 	"github.com/fswatcher/fswatcher"
 	"github.com/k1LoW/donegroup"
+	"github.com/k1LoW/mo/internal/archive"
+	"github.com/k1LoW/mo/internal/config"
+	"github.com/k1LoW/mo/internal/csvtable"
+	"github.com/k1LoW/mo/internal/fencerender"
+	"github.com/k1LoW/mo/internal/git"
 	"github.com/k1LoW/mo/internal/static"
 	"github.com/k1LoW/mo/version"
 	"golang.org/x/text/collate"
@@ -35,7 +41,21 @@ type FileEntry struct {
 	Path     string `json:"path"`
 	Title    string `json:"title,omitempty"`
 	Uploaded bool   `json:"uploaded,omitempty"`
-	content  string // in-memory content for uploaded files
+	// Revision is the git revision an entry was read from (e.g. HEAD~3).
+	Revision string `json:"revision,omitempty"`
+	// GitStatus is set on entries opened by a review group: added, modified
+	// or deleted relative to the review's merge-base.
+	GitStatus string `json:"gitStatus,omitempty"`
+	// GitInfo is the git metadata of a file in a work tree.
+	GitInfo *GitInfo `json:"git,omitempty"`
+	// URL is the address of an entry fetched over HTTP(S).
+	URL     string         `json:"url,omitempty"`
+	content string         // in-memory content for uploaded files
+	git     *GitSource     // source of entries read from a git revision
+	gitBase *GitSource     // merge-base version of a review entry
+	remote  *remoteState   // source of entries fetched over HTTP(S)
+	archive *archiveSource // source of entries read from an archive
+	named   bool           // uploaded under a stable name (see AddNamedFile)
 }
 
 const headFileSizeLimit = 8192
@@ -173,10 +193,11 @@ const watchOps = fswatcher.Create | fswatcher.Write | fswatcher.Remove | fswatch
 
 // GlobPattern represents a glob pattern being watched for new files.
 type GlobPattern struct {
-	Pattern      string // Absolute glob pattern
-	PatternSlash string // Pre-converted to forward slashes for doublestar matching
-	BaseDir      string // Base directory extracted via SplitPattern
-	Group        string // Target group for matched files
+	Pattern      string   // Absolute glob pattern
+	PatternSlash string   // Pre-converted to forward slashes for doublestar matching
+	BaseDir      string   // Base directory extracted via SplitPattern
+	Group        string   // Target group for matched files
+	Ignore       []string // Absolute patterns of files skipped (see config.AbsIgnore)
 }
 
 // IsRecursive returns true if the pattern contains ** for recursive matching.
@@ -194,6 +215,15 @@ type State struct {
 	shutdownCh  chan struct{}
 	patterns    []*GlobPattern
 	watchedDirs map[string]int // directory → reference count
+	// watchedFiles holds the file paths that currently have a watch.
+	watchedFiles map[string]struct{}
+	watchDiag    watchDiagnostics
+	// assetDeps maps a watched asset path (an image or other file served
+	// through the raw endpoint) to the document paths that reference it.
+	assetDeps map[string]map[string]struct{}
+	// includes maps a document path to the files its include directives
+	// pulled in on its last read, which are tracked in assetDeps as well.
+	includes map[string][]string
 	// pathAliases maps a canonical (symlink-resolved) path back to the
 	// original path we stored. The fswatcher watcher canonicalizes paths,
 	// so events arrive with the resolved form (e.g. /private/var/...) while
@@ -208,6 +238,80 @@ type State struct {
 	fileChangeDebounce time.Duration
 	fileChangeTimers   map[string]*time.Timer
 
+	// history keeps recent revisions of each file by ID so that reloads can
+	// report what changed. It has its own mutex to keep file I/O and diffing
+	// out of s.mu.
+	historyMu    sync.Mutex
+	history      map[string]*fileHistory
+	historyLimit int
+
+	// reviews holds the review groups by group name, reviewDirs the
+	// work-tree directories each one watches and reviewTracked the
+	// directories of its tracked files, listed again after the git directory
+	// changes. Like history, they have their own mutex because refreshing
+	// runs git outside s.mu.
+	reviewMu        sync.Mutex
+	reviews         map[string]ReviewData
+	reviewDirs      map[string]map[string]struct{}
+	reviewTracked   map[string][]string
+	reviewRefreshMu sync.Mutex
+
+	// gitRepos holds the work trees containing open files by root, and
+	// gitDirs maps their watched git directories back to the root.
+	gitMu           sync.Mutex
+	gitRepos        map[string]*gitRepo
+	gitDirs         map[string]string
+	gitRefreshMu    sync.Mutex
+	gitInfoDebounce time.Duration
+
+	// docAssets holds the images extracted from converted documents by name,
+	// and docAssetOwners the asset names of each document path.
+	docAssetsMu    sync.Mutex
+	docAssets      map[string]documentAsset
+	docAssetOwners map[string][]string
+
+	// remoteClient fetches remote entries, which remoteLoop checks every
+	// remoteTick.
+	remoteClient *http.Client
+	remoteTick   time.Duration
+
+	// archives holds the mounted archives, archiveTimers the pending
+	// reloads of changed archives by path, and archiveIndexes the indexes
+	// their assets and linked documents are read through. archiveLoadMu
+	// serializes loads.
+	archiveMu      sync.Mutex
+	archives       map[archiveKey]ArchiveData
+	archiveTimers  map[string]*time.Timer
+	archiveIndexes map[string]*archiveIndex
+	archiveLoadMu  sync.Mutex
+
+	// renderers holds the commands rendering fenced code blocks by
+	// language, renderCache their results by input hash, and renderPending
+	// the documents waiting for the blocks being rendered. renderSem bounds
+	// the commands running at once.
+	renderMu      sync.Mutex
+	renderers     map[string]fencerender.Renderer
+	renderTimeout time.Duration
+	renderCache   map[string]renderResult
+	renderPending map[string]map[string]struct{}
+	renderSem     chan struct{}
+
+	// ignore holds the patterns of files left out when glob patterns are
+	// expanded, and uiDefaults the settings the SPA starts with. Both are
+	// set once from the user config before serving.
+	ignore     []string
+	uiDefaults UIDefaults
+
+	// tables holds the parsed CSV and TSV entries by ID (see table.go).
+	tableMu sync.Mutex
+	tables  map[string]*cachedTable
+
+	// streams holds the uploaded entries growing from streamed input by ID
+	// (see stream.go).
+	streamMu       sync.Mutex
+	streams        map[string]*stream
+	streamThrottle time.Duration
+
 	backupCh     chan struct{}     // dirty signal (buffered, size 1)
 	backupSaveFn func(RestoreData) // backup write callback
 	backupDone   chan struct{}     // closed when backupLoop exits
@@ -217,31 +321,66 @@ const defaultFileChangeDebounce = 200 * time.Millisecond
 
 func NewState(ctx context.Context) *State {
 	w, err := fswatcher.NewWatcher()
+	s := newState(w)
 	if err != nil {
 		slog.Warn("failed to create file watcher", "error", err)
+		s.watchDiag.recordError(err)
+	}
+
+	if w != nil {
+		donegroup.Go(ctx, func() error {
+			s.watchLoop()
+			return nil
+		})
 	}
+	donegroup.Go(ctx, func() error {
+		s.remoteLoop(ctx)
+		return nil
+	})
+
+	return s
+}
 
-	s := &State{
+// newState returns a State with every map and default set, watching with w
+// (nil for none). NewState and tests build states through it.
+func newState(w *fswatcher.Watcher) *State {
+	return &State{
 		groups:             make(map[string]*Group),
 		subscribers:        make(map[chan sseEvent]struct{}),
 		watcher:            w,
 		restartCh:          make(chan string, 1),
 		shutdownCh:         make(chan struct{}, 1),
 		watchedDirs:        make(map[string]int),
+		watchedFiles:       make(map[string]struct{}),
+		assetDeps:          make(map[string]map[string]struct{}),
+		includes:           make(map[string][]string),
 		pathAliases:        make(map[string]string),
 		aliasReverse:       make(map[string]string),
 		fileChangeDebounce: defaultFileChangeDebounce,
 		fileChangeTimers:   make(map[string]*time.Timer),
+		history:            make(map[string]*fileHistory),
+		historyLimit:       defaultHistoryLimit,
+		reviews:            make(map[string]ReviewData),
+		reviewDirs:         make(map[string]map[string]struct{}),
+		reviewTracked:      make(map[string][]string),
+		gitRepos:           make(map[string]*gitRepo),
+		gitDirs:            make(map[string]string),
+		gitInfoDebounce:    defaultGitInfoDebounce,
+		docAssets:          make(map[string]documentAsset),
+		docAssetOwners:     make(map[string][]string),
+		remoteClient:       &http.Client{},
+		remoteTick:         remoteTick,
+		archives:           make(map[archiveKey]ArchiveData),
+		archiveTimers:      make(map[string]*time.Timer),
+		archiveIndexes:     make(map[string]*archiveIndex),
+		renderTimeout:      fencerender.DefaultTimeout,
+		renderCache:        make(map[string]renderResult),
+		renderPending:      make(map[string]map[string]struct{}),
+		renderSem:          make(chan struct{}, maxConcurrentRenders),
+		tables:             make(map[string]*cachedTable),
+		streams:            make(map[string]*stream),
+		streamThrottle:     defaultStreamThrottle,
 	}
-
-	if w != nil {
-		donegroup.Go(ctx, func() error {
-			s.watchLoop()
-			return nil
-		})
-	}
-
-	return s
 }
 
 // ErrBinaryFile is returned when a file is detected as binary.
@@ -250,6 +389,10 @@ var ErrBinaryFile = errors.New("binary file is not supported")
 // ErrFileNotFound is returned when a file is not found in the specified group.
 var ErrFileNotFound = errors.New("file not found")
 
+// ErrPatternNotFound is returned when a pattern is not registered in the
+// specified group.
+var ErrPatternNotFound = errors.New("pattern not found")
+
 // readFileHead reads the first 8KB of the file at path.
 // Returns the bytes read and any error (os.ErrNotExist is passed through).
 // Non-regular files return an error.
@@ -288,15 +431,20 @@ func (s *State) AddFile(absPath, groupName string) (*FileEntry, error) {
 		if !os.IsNotExist(err) {
 			return nil, fmt.Errorf("failed to read file %s: %w", absPath, err)
 		}
-	} else if len(head) > 0 && bytes.IndexByte(head, 0) >= 0 {
+	} else if len(head) > 0 && bytes.IndexByte(head, 0) >= 0 && (!isTablePath(absPath) || !csvtable.HasUTF16BOM(head)) {
 		return nil, fmt.Errorf("%s: %w", absPath, ErrBinaryFile)
 	}
 
 	title := extractTitle(string(head))
+	if isConvertedDocument(absPath) {
+		title, _ = s.documentTitle(absPath)
+	}
+	gitRoot := findGitRoot(filepath.Dir(absPath))
 	var canonical string
 	if s.watcher != nil {
 		canonical = resolvePathAlias(absPath)
 	}
+	s.seedHistory(FileID(absPath), absPath)
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -323,28 +471,45 @@ func (s *State) AddFile(absPath, groupName string) (*FileEntry, error) {
 	g.Files = append(g.Files, entry)
 
 	if s.watcher != nil {
-		if err := s.watcher.Add(absPath, watchOps); err != nil {
-			slog.Warn("failed to watch file", "path", absPath, "error", err)
+		if err := s.watcher.Add(absPath, watchOps); err != nil && !errors.Is(err, fswatcher.ErrAlreadyAdded) {
+			s.watchFailed("failed to watch file", absPath, err)
 		} else {
+			s.watchedFiles[absPath] = struct{}{}
+			s.watchDiag.clear(absPath)
 			s.registerPathAlias(absPath, canonical)
 		}
 	}
 
 	slog.Info("file added", "path", absPath, "group", groupName, "id", entry.ID) //nolint:gosec // G706: structured logging fields, no injection risk
 
+	if gitRoot != "" {
+		s.scheduleGitRefresh(gitRoot)
+	}
 	s.sendEvent(sseEvent{Name: eventUpdate, Data: "{}"})
 	return entry, nil
 }
 
 func (s *State) AddUploadedFile(name, content, groupName string) *FileEntry {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	h := sha256.New()
 	h.Write([]byte("upload:"))
 	h.Write([]byte(content))
 	id := "u" + hex.EncodeToString(h.Sum(nil))[:7]
 
+	return s.addInMemoryFile(&FileEntry{
+		Name:     name,
+		ID:       id,
+		Uploaded: true,
+		content:  content,
+	}, groupName)
+}
+
+// addInMemoryFile adds an entry whose content is held in memory rather than
+// read from disk. An entry with the same ID already in the group is returned
+// instead.
+func (s *State) addInMemoryFile(entry *FileEntry, groupName string) *FileEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	g, ok := s.groups[groupName]
 	if !ok {
 		g = &Group{Name: groupName}
@@ -353,32 +518,55 @@ func (s *State) AddUploadedFile(name, content, groupName string) *FileEntry {
 
 	// Check for duplicate within the target group only (consistent with AddFile)
 	for _, f := range g.Files {
-		if f.ID == id {
+		if f.ID == entry.ID {
 			return f
 		}
 	}
 
-	head := content
+	head := entry.content
 	if len(head) > headFileSizeLimit {
 		head = head[:headFileSizeLimit]
 	}
-	title := extractTitle(head)
+	entry.Title = extractTitle(head)
 
-	entry := &FileEntry{
-		Name:     name,
-		ID:       id,
-		Title:    title,
-		Uploaded: true,
-		content:  content,
-	}
+	s.recordRevision(entry.ID, entry.content)
 	g.Files = append(g.Files, entry)
 
-	slog.Info("uploaded file added", "name", name, "group", groupName, "id", entry.ID) //nolint:gosec // G706: structured logging fields, no injection risk
+	slog.Info("uploaded file added", "name", entry.Name, "group", groupName, "id", entry.ID) //nolint:gosec // G706: structured logging fields, no injection risk
 
 	s.sendEvent(sseEvent{Name: eventUpdate, Data: "{}"})
 	return entry
 }
 
+// replaceInMemoryContent replaces the content of an in-memory entry and
+// notifies clients, reporting whether the content changed.
+func (s *State) replaceInMemoryContent(entry *FileEntry, content string) bool {
+	head := content
+	if len(head) > headFileSizeLimit {
+		head = head[:headFileSizeLimit]
+	}
+	title := extractTitle(head)
+
+	s.mu.Lock()
+	changed := entry.content != content
+	entry.content = content
+	titleChanged := entry.Title != title
+	entry.Title = title
+	id := entry.ID
+	s.mu.Unlock()
+
+	if !changed {
+		return false
+	}
+	s.markDirty()
+	if titleChanged {
+		s.sendEvent(sseEvent{Name: eventUpdate, Data: "{}"})
+	}
+	prev, cur := s.recordRevision(id, content)
+	s.notifyFileChanged([]string{id}, map[string]fileChangedEvent{id: changeEvent(id, prev, cur)})
+	return true
+}
+
 func (s *State) Groups() []Group {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -521,11 +709,13 @@ func (s *State) RemoveFilesByPath(absPath string) bool {
 
 	s.mu.Lock()
 	removed := false
+	var gitInfo *GitInfo
 	for name, g := range s.groups {
 		filtered := g.Files[:0]
 		for _, f := range g.Files {
 			if f.Path == absPath {
 				removed = true
+				gitInfo = f.GitInfo
 				slog.Info("file removed", "path", f.Path, "id", f.ID, "group", name) //nolint:gosec // G706: structured logging fields, no injection risk
 				continue
 			}
@@ -542,14 +732,28 @@ func (s *State) RemoveFilesByPath(absPath string) bool {
 		}
 	}
 	if removed && s.watcher != nil {
-		if err := s.watcher.Remove(absPath); err != nil {
-			slog.Warn("failed to unwatch file", "path", absPath, "error", err)
+		s.untrackDocumentAssets(absPath)
+		// Keep the watch while another open document includes or embeds
+		// the file.
+		if len(s.assetDeps[absPath]) == 0 {
+			if err := s.watcher.Remove(absPath); err != nil {
+				slog.Warn("failed to unwatch file", "path", absPath, "error", err)
+			}
+			delete(s.watchedFiles, absPath)
+			s.watchDiag.clear(absPath)
+			s.unregisterPathAlias(absPath)
 		}
-		s.unregisterPathAlias(absPath)
 	}
 	s.mu.Unlock()
 
+	if gitInfo != nil {
+		// Unwatches the git directory once no open file is in the work tree.
+		s.scheduleGitRefresh(gitInfo.Root)
+	}
 	if removed {
+		s.forgetHistory(FileID(absPath))
+		s.forgetTables(FileID(absPath))
+		s.forgetDocumentAssets(absPath)
 		s.sendEvent(sseEvent{Name: eventUpdate, Data: "{}"})
 	}
 	return removed
@@ -560,11 +764,15 @@ func (s *State) RemoveFile(id, groupName string) bool {
 	defer s.mu.Unlock()
 
 	var removedPath string
+	var gitInfo *GitInfo
+	var archiveSrc *archiveSource
 	found := false
 	if g, ok := s.groups[groupName]; ok {
 		for i, f := range g.Files {
 			if f.ID == id {
 				removedPath = f.Path
+				gitInfo = f.GitInfo
+				archiveSrc = f.archive
 				g.Files = append(g.Files[:i], g.Files[i+1:]...)
 				if len(g.Files) == 0 && !s.groupHasPatterns(groupName) {
 					delete(s.groups, groupName)
@@ -579,6 +787,12 @@ func (s *State) RemoveFile(id, groupName string) bool {
 	}
 
 	slog.Info("file removed", "path", removedPath, "id", id) //nolint:gosec // G706: removedPath is from internal state, not direct user input
+	s.closeStream(id)
+	s.forgetTables(id)
+
+	if archiveSrc != nil {
+		s.releaseArchiveLocked(archiveSrc, groupName)
+	}
 
 	// Remove watcher only if no other file references the same path
 	if s.watcher != nil && removedPath != "" {
@@ -595,13 +809,43 @@ func (s *State) RemoveFile(id, groupName string) bool {
 			}
 		}
 		if !stillReferenced {
-			if err := s.watcher.Remove(removedPath); err != nil {
-				slog.Warn("failed to unwatch file", "path", removedPath, "error", err)
+			s.untrackDocumentAssets(removedPath)
+			// Keep the watch while another open document includes or
+			// embeds the file.
+			if len(s.assetDeps[removedPath]) == 0 {
+				if err := s.watcher.Remove(removedPath); err != nil {
+					slog.Warn("failed to unwatch file", "path", removedPath, "error", err)
+				}
+				delete(s.watchedFiles, removedPath)
+				s.watchDiag.clear(removedPath)
+				s.unregisterPathAlias(removedPath)
 			}
-			s.unregisterPathAlias(removedPath)
 		}
 	}
 
+	// Drop the revision history once no group shows the file anymore.
+	idReferenced := false
+	for _, g := range s.groups {
+		for _, f := range g.Files {
+			if f.ID == id {
+				idReferenced = true
+				break
+			}
+		}
+		if idReferenced {
+			break
+		}
+	}
+	if !idReferenced {
+		s.forgetHistory(id)
+		if removedPath != "" {
+			s.forgetDocumentAssets(removedPath)
+		}
+	}
+	if gitInfo != nil {
+		s.scheduleGitRefresh(gitInfo.Root)
+	}
+
 	s.sendEvent(sseEvent{Name: eventUpdate, Data: "{}"})
 	return true
 }
@@ -661,6 +905,12 @@ func (s *State) ShutdownCh() <-chan struct{} {
 // It performs an initial expansion to add existing matches and starts
 // watching the base directory for new files.
 func (s *State) AddPattern(absPattern, groupName string) ([]*FileEntry, error) {
+	return s.AddPatternWithIgnore(absPattern, groupName, nil)
+}
+
+// AddPatternWithIgnore is AddPattern skipping the files that match ignore,
+// absolute patterns as returned by config.AbsIgnore.
+func (s *State) AddPatternWithIgnore(absPattern, groupName string, ignore []string) ([]*FileEntry, error) {
 	// Use forward slashes for doublestar
 	dsPattern := filepath.ToSlash(absPattern)
 	base, relPat := doublestar.SplitPattern(dsPattern)
@@ -687,6 +937,7 @@ func (s *State) AddPattern(absPattern, groupName string) ([]*FileEntry, error) {
 			PatternSlash: dsPattern,
 			BaseDir:      base,
 			Group:        groupName,
+			Ignore:       ignore,
 		}
 		s.patterns = append(s.patterns, gp)
 		// Ensure the group exists even if no files match yet.
@@ -708,6 +959,9 @@ func (s *State) AddPattern(absPattern, groupName string) ([]*FileEntry, error) {
 
 	var entries []*FileEntry
 	for _, m := range matches {
+		if s.ignored(m) || config.IgnoredAbs(ignore, filepath.Join(base, m)) {
+			continue
+		}
 		abs := filepath.Join(base, m)
 		entry, err := s.AddFile(abs, groupName)
 		if err != nil {
@@ -744,6 +998,83 @@ func (s *State) PatternsForGroup(groupName string) []string {
 	return result
 }
 
+// MovePattern re-homes a watch pattern from one group to another, taking the
+// files it matches along in their current order. Files already in the target
+// group stay where they are.
+func (s *State) MovePattern(absPattern, sourceGroupName, targetGroup string) error {
+	if sourceGroupName == targetGroup {
+		return fmt.Errorf("pattern is already in group %q", targetGroup)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var gp *GlobPattern
+	for _, p := range s.patterns {
+		if p.Pattern == absPattern && p.Group == sourceGroupName {
+			gp = p
+		}
+		if p.Pattern == absPattern && p.Group == targetGroup {
+			return fmt.Errorf("pattern %q already exists in group %q", absPattern, targetGroup)
+		}
+	}
+	if gp == nil {
+		return ErrPatternNotFound
+	}
+	gp.Group = targetGroup
+
+	tg, ok := s.groups[targetGroup]
+	if !ok {
+		tg = &Group{Name: targetGroup}
+		s.groups[targetGroup] = tg
+	}
+	if sg, ok := s.groups[sourceGroupName]; ok {
+		kept := sg.Files[:0]
+		for _, f := range sg.Files {
+			if matched, _ := doublestar.Match(gp.PatternSlash, filepath.ToSlash(f.Path)); !matched || f.Uploaded {
+				kept = append(kept, f)
+				continue
+			}
+			if !slices.ContainsFunc(tg.Files, func(t *FileEntry) bool { return t.Path == f.Path }) {
+				tg.Files = append(tg.Files, f)
+			}
+		}
+		clear(sg.Files[len(kept):])
+		sg.Files = kept
+		if len(sg.Files) == 0 && !s.groupHasPatterns(sourceGroupName) {
+			delete(s.groups, sourceGroupName)
+		}
+	}
+
+	slog.Info("pattern moved", "pattern", absPattern, "from", sourceGroupName, "to", targetGroup)
+	s.sendEvent(sseEvent{Name: eventUpdate, Data: "{}"})
+	return nil
+}
+
+// CopyPattern registers a watch pattern of one group in another as well,
+// with the same ignore rules.
+func (s *State) CopyPattern(absPattern, sourceGroupName, targetGroup string) ([]*FileEntry, error) {
+	if sourceGroupName == targetGroup {
+		return nil, fmt.Errorf("pattern is already in group %q", targetGroup)
+	}
+	s.mu.RLock()
+	var ignore []string
+	found := false
+	for _, p := range s.patterns {
+		if p.Pattern == absPattern && p.Group == sourceGroupName {
+			ignore, found = p.Ignore, true
+		}
+		if p.Pattern == absPattern && p.Group == targetGroup {
+			s.mu.RUnlock()
+			return nil, fmt.Errorf("pattern %q already exists in group %q", absPattern, targetGroup)
+		}
+	}
+	s.mu.RUnlock()
+	if !found {
+		return nil, ErrPatternNotFound
+	}
+	return s.AddPatternWithIgnore(absPattern, targetGroup, ignore)
+}
+
 // RemovePattern removes a glob pattern from the watch list.
 // Returns true if the pattern was found and removed.
 func (s *State) RemovePattern(absPattern, groupName string) bool {
@@ -779,16 +1110,26 @@ func (s *State) RemovePattern(absPattern, groupName string) bool {
 
 // UploadedFileData represents an uploaded file's content for persistence.
 type UploadedFileData struct {
-	Name    string `json:"name"`
-	Content string `json:"content"`
-	Group   string `json:"group"`
+	Name    string        `json:"name"`
+	Content string        `json:"content"`
+	Group   string        `json:"group"`
+	Git     *GitSource    `json:"git,omitempty"`
+	Remote  *RemoteSource `json:"remote,omitempty"`
+	// Named is set on files uploaded under a stable name, whose ID does not
+	// change with their content.
+	Named bool `json:"named,omitempty"`
 }
 
 // RestoreData represents the state to be persisted across restarts.
 type RestoreData struct {
-	Groups        map[string][]string `json:"groups"`
-	Patterns      map[string][]string `json:"patterns,omitempty"`
-	UploadedFiles []UploadedFileData  `json:"uploadedFiles,omitempty"`
+	Groups   map[string][]string `json:"groups"`
+	Patterns map[string][]string `json:"patterns,omitempty"`
+	// PatternIgnores holds the ignore patterns of watch patterns by group
+	// and pattern.
+	PatternIgnores map[string]map[string][]string `json:"patternIgnores,omitempty"`
+	UploadedFiles  []UploadedFileData             `json:"uploadedFiles,omitempty"`
+	Reviews        []ReviewData                   `json:"reviews,omitempty"`
+	Archives       []ArchiveData                  `json:"archives,omitempty"`
 }
 
 // WriteRestoreFile writes RestoreData to a temporary file and returns the path.
@@ -836,12 +1177,24 @@ func (s *State) snapshotRestoreData() RestoreData {
 	for name, g := range s.groups {
 		paths := make([]string, 0, len(g.Files))
 		for _, f := range g.Files {
+			// Review and archive entries are recreated from data.Reviews
+			// and data.Archives on restore.
+			if f.GitStatus != "" || f.archive != nil {
+				continue
+			}
 			if f.Uploaded {
-				data.UploadedFiles = append(data.UploadedFiles, UploadedFileData{
+				uf := UploadedFileData{
 					Name:    f.Name,
 					Content: f.content,
 					Group:   name,
-				})
+					Git:     f.git,
+					Named:   f.named,
+				}
+				if f.remote != nil {
+					src := f.remote.src
+					uf.Remote = &src
+				}
+				data.UploadedFiles = append(data.UploadedFiles, uf)
 				continue
 			}
 			paths = append(paths, f.Path)
@@ -853,9 +1206,21 @@ func (s *State) snapshotRestoreData() RestoreData {
 		data.Patterns = make(map[string][]string)
 		for _, p := range s.patterns {
 			data.Patterns[p.Group] = append(data.Patterns[p.Group], p.Pattern)
+			if len(p.Ignore) > 0 {
+				if data.PatternIgnores == nil {
+					data.PatternIgnores = make(map[string]map[string][]string)
+				}
+				if data.PatternIgnores[p.Group] == nil {
+					data.PatternIgnores[p.Group] = make(map[string][]string)
+				}
+				data.PatternIgnores[p.Group][p.Pattern] = p.Ignore
+			}
 		}
 	}
 
+	data.Reviews = s.Reviews()
+	data.Archives = s.Archives()
+
 	return data
 }
 
@@ -951,6 +1316,7 @@ func (s *State) removeDirWatch(dir string) {
 		count--
 		if count <= 0 {
 			delete(s.watchedDirs, dir)
+			s.watchDiag.clear(dir)
 			if s.watcher != nil {
 				if err := s.watcher.Remove(dir); err != nil {
 					slog.Warn("failed to remove directory watch", "dir", dir, "error", err)
@@ -1013,9 +1379,10 @@ func (s *State) watchLoop() {
 							return
 						}
 						if err := s.watcher.Add(eventPath, watchOps); err != nil && !errors.Is(err, fswatcher.ErrAlreadyAdded) {
-							slog.Warn("failed to re-watch file", "path", eventPath, "error", err)
+							s.watchFailed("failed to re-watch file", eventPath, err)
 							return
 						}
+						s.watchDiag.clear(eventPath)
 						slog.Info("re-watching file", "path", eventPath)
 						if len(refsTranslated) > 0 {
 							s.scheduleFileChanged(eventPath)
@@ -1036,11 +1403,16 @@ func (s *State) watchLoop() {
 			if event.Op.Has(fswatcher.Create) {
 				s.handleCreateForGlobs(eventPath)
 			}
+			s.handleAssetEvent(eventPath, event.Op)
+			s.handleArchiveEvent(eventPath, event.Op)
+			s.handleGitDirEvent(eventPath, event.Name)
+			s.handleReviewEvent(eventPath, event.Name)
 		case err, ok := <-s.watcher.Errors:
 			if !ok {
 				return
 			}
 			slog.Warn("file watcher error", "error", err)
+			s.watchDiag.recordError(err)
 		}
 	}
 }
@@ -1074,7 +1446,7 @@ func (s *State) scheduleFileChanged(absPath string) {
 
 func (s *State) notifyFileChangedByPath(absPath string) {
 	// Extract the title outside the lock (file I/O should not hold the mutex).
-	newTitle, titleOK := extractTitleFromFile(absPath)
+	newTitle, titleOK := s.documentTitle(absPath)
 
 	// Single lock pass: collect IDs and update titles together.
 	var ids []string
@@ -1099,14 +1471,41 @@ func (s *State) notifyFileChangedByPath(absPath string) {
 	if titleChanged {
 		s.sendEvent(sseEvent{Name: eventUpdate, Data: "{}"})
 	}
-	s.notifyFileChanged(ids)
+	// An edit can change the work-tree status.
+	if root := findGitRoot(filepath.Dir(absPath)); root != "" {
+		s.scheduleGitRefresh(root)
+	}
+	s.notifyFileChanged(ids, s.recordFileRevision(absPath, ids))
 }
 
-func (s *State) notifyFileChanged(ids []string) {
+// recordFileRevision reads the file at absPath, records it as a new revision
+// for each of ids and returns the resulting change information by ID.
+func (s *State) recordFileRevision(absPath string, ids []string) map[string]fileChangedEvent {
+	data, err := s.readDocument(absPath)
+	if err != nil || strings.IndexByte(data, 0) >= 0 {
+		return nil
+	}
+	events := make(map[string]fileChangedEvent, len(ids))
 	for _, id := range ids {
-		b, err := json.Marshal(struct {
-			ID string `json:"id"`
-		}{ID: id})
+		if _, ok := events[id]; ok {
+			continue
+		}
+		prev, cur := s.recordRevision(id, data)
+		events[id] = changeEvent(id, prev, cur)
+	}
+	return events
+}
+
+// notifyFileChanged sends a file-changed event for each of ids. Change
+// information from changes is included when available.
+func (s *State) notifyFileChanged(ids []string, changes map[string]fileChangedEvent) {
+	s.forgetTables(ids...)
+	for _, id := range ids {
+		e, ok := changes[id]
+		if !ok {
+			e = fileChangedEvent{ID: id}
+		}
+		b, err := json.Marshal(e)
 		if err != nil {
 			slog.Error("notifyFileChanged", "err", err)
 			continue
@@ -1259,8 +1658,9 @@ func (s *State) addDirWatch(dir string) {
 	if s.watchedDirs[dir] == 1 && s.watcher != nil {
 		if err := s.watcher.Add(dir, watchOps); err != nil {
 			delete(s.watchedDirs, dir)
-			slog.Warn("failed to watch directory", "path", dir, "error", err)
+			s.watchFailed("failed to watch directory", dir, err)
 		} else {
+			s.watchDiag.clear(dir)
 			added = true
 		}
 	}
@@ -1332,6 +1732,12 @@ func (s *State) matchAndAddFile(path string, patterns []*GlobPattern) {
 			continue
 		}
 		if matched {
+			if rel, err := filepath.Rel(gp.BaseDir, path); err == nil && s.ignored(rel) {
+				return
+			}
+			if config.IgnoredAbs(gp.Ignore, path) {
+				return
+			}
 			if _, err := s.AddFile(path, gp.Group); err != nil {
 				slog.Warn("skipping file", "path", path, "error", err)
 				return
@@ -1350,18 +1756,28 @@ type moveFileRequest struct {
 	Group string `json:"group"`
 }
 
+type movePatternRequest struct {
+	Pattern string `json:"pattern"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Copy    bool   `json:"copy"`
+}
+
 type addFileRequest struct {
 	Path string `json:"path"`
 }
 
 type uploadFileRequest struct {
-	Name    string `json:"name"`
-	Content string `json:"content"`
+	Name    string     `json:"name"`
+	Content string     `json:"content"`
+	Git     *GitSource `json:"git,omitempty"`
+	Named   bool       `json:"named,omitempty"`
 }
 
 type patternRequest struct {
-	Pattern string `json:"pattern"`
-	Group   string `json:"group"`
+	Pattern string   `json:"pattern"`
+	Group   string   `json:"group"`
+	Ignore  []string `json:"ignore,omitempty"`
 }
 
 // AddPatternResponse is the JSON response for the add-pattern endpoint.
@@ -1371,8 +1787,9 @@ type AddPatternResponse struct {
 }
 
 type fileContentResponse struct {
-	Content string `json:"content"`
-	BaseDir string `json:"baseDir"`
+	Content string     `json:"content"`
+	BaseDir string     `json:"baseDir"`
+	Table   *TableData `json:"table,omitempty"`
 }
 
 type searchAnchor struct {
@@ -1423,22 +1840,37 @@ func NewHandler(state *State) http.Handler {
 
 	mux.HandleFunc("POST /_/api/groups/{group}/files", handleAddFile(state))
 	mux.HandleFunc("POST /_/api/groups/{group}/files/upload", handleUploadFile(state))
+	mux.HandleFunc("POST /_/api/groups/{group}/files/remote", handleAddRemoteFile(state))
+	mux.HandleFunc("POST /_/api/groups/{group}/streams", handleAddStream(state))
+	mux.HandleFunc("POST /_/api/groups/{group}/files/{id}/append", handleAppendStream(state))
+	mux.HandleFunc("PUT /_/api/groups/{group}/files/{id}/content", handleUpdateContent(state))
 	mux.HandleFunc("DELETE /_/api/groups/{group}/files/{id}", handleRemoveFile(state))
 	mux.HandleFunc("PUT /_/api/groups/{group}/files/{id}/group", handleMoveFile(state))
+	mux.HandleFunc("POST /_/api/groups/{group}/files/{id}/navigate", handleNavigate(state))
 	mux.HandleFunc("GET /_/api/groups", handleGroups(state))
 	mux.HandleFunc("PUT /_/api/groups/{group}/reorder", handleReorderFiles(state))
 	mux.HandleFunc("GET /_/api/groups/{group}/files/{id}/content", handleFileContent(state))
+	mux.HandleFunc("GET /_/api/groups/{group}/files/{id}/history", handleFileHistory(state))
+	mux.HandleFunc("GET /_/api/groups/{group}/files/{id}/diff", handleFileDiff(state))
+	mux.HandleFunc("GET /_/api/groups/{group}/files/{id}/worktree-diff", handleWorktreeDiff(state))
 	mux.HandleFunc("GET /_/api/search", handleSearch(state))
 	mux.HandleFunc("GET /_/api/groups/{group}/files/{id}/raw/{path...}", handleFileRaw(state))
+	mux.HandleFunc("GET /_/api/assets/{name}", handleDocumentAsset(state))
 	mux.HandleFunc("POST /_/api/groups/{group}/files/open", handleOpenFile(state))
 	mux.HandleFunc("POST /_/api/patterns", handleAddPattern(state))
 	mux.HandleFunc("DELETE /_/api/patterns", handleRemovePattern(state))
+	mux.HandleFunc("PUT /_/api/patterns/group", handleMovePattern(state))
+	mux.HandleFunc("POST /_/api/reviews", handleAddReview(state))
+	mux.HandleFunc("POST /_/api/archives", handleAddArchive(state))
+	mux.HandleFunc("DELETE /_/api/reviews/{group}", handleRemoveReview(state))
 	mux.HandleFunc("POST /_/api/restart", handleRestart(state))
 	mux.HandleFunc("POST /_/api/shutdown", handleShutdown(state))
+	mux.HandleFunc("GET /_/api/session", handleSession(state))
+	mux.HandleFunc("PUT /_/api/session", handleLoadSession(state))
 	mux.HandleFunc("GET /_/api/status", handleStatus(state))
 	mux.HandleFunc("GET /_/api/version", handleVersion())
 	mux.HandleFunc("GET /_/events", handleSSE(state))
-	mux.HandleFunc("GET /", handleSPA())
+	mux.HandleFunc("GET /", handleSPA(state))
 
 	return withCSP(mux)
 }
@@ -1529,7 +1961,24 @@ func handleUploadFile(state *State) http.HandlerFunc {
 			return
 		}
 
-		entry := state.AddUploadedFile(req.Name, req.Content, group)
+		if req.Git != nil && !git.IsCommitHash(req.Git.Commit) {
+			http.Error(w, "invalid git commit", http.StatusBadRequest)
+			return
+		}
+		if req.Git != nil && req.Named {
+			http.Error(w, "git files cannot be named uploads", http.StatusBadRequest)
+			return
+		}
+
+		var entry *FileEntry
+		switch {
+		case req.Git != nil:
+			entry = state.AddGitFile(*req.Git, req.Name, req.Content, group)
+		case req.Named:
+			entry = state.AddNamedFile(req.Name, req.Content, group)
+		default:
+			entry = state.AddUploadedFile(req.Name, req.Content, group)
+		}
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(entry); err != nil {
 			slog.Error("failed to encode response", "error", err)
@@ -1661,12 +2110,16 @@ func handleFileContent(state *State) http.HandlerFunc {
 
 		var resp fileContentResponse
 		if entry.Uploaded {
+			// Remote entries are refreshed in place.
+			state.mu.RLock()
+			content := entry.content
+			state.mu.RUnlock()
 			resp = fileContentResponse{
-				Content: entry.content,
+				Content: content,
 				BaseDir: "",
 			}
 		} else {
-			content, err := os.ReadFile(entry.Path) //nolint:gosec // Path is server-managed, not user-supplied
+			content, err := state.readDocument(entry.Path)
 			if err != nil {
 				if os.IsNotExist(err) {
 					// File is gone from disk: drop it from state so the group
@@ -1679,10 +2132,23 @@ func handleFileContent(state *State) http.HandlerFunc {
 				return
 			}
 			resp = fileContentResponse{
-				Content: string(content),
+				Content: content,
 				BaseDir: filepath.Dir(entry.Path),
 			}
 		}
+		if isTable, tsv := tableFormat(entry); isTable {
+			table, err := state.newTableData(entry.ID, resp.Content, tsv, r.URL.Query())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			resp.Table = table
+			// Paging requests only need rows; the raw text of big files is
+			// never sent.
+			if table != nil && (r.URL.Query().Has("offset") || len(resp.Content) > tableContentLimit) {
+				resp.Content = ""
+			}
+		}
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
 			slog.Error("failed to encode response", "error", err)
@@ -1759,7 +2225,7 @@ func handleSearch(state *State) http.HandlerFunc {
 			if remaining == 0 {
 				break
 			}
-			content, err := readSearchableContent(entry)
+			content, err := state.readSearchableContent(entry)
 			if err != nil {
 				slog.Warn("failed to read file for search", "id", entry.ID, "path", entry.Path, "error", err)
 				continue
@@ -1787,17 +2253,6 @@ func handleSearch(state *State) http.HandlerFunc {
 	}
 }
 
-func readSearchableContent(entry *FileEntry) (string, error) {
-	if entry.Uploaded {
-		return entry.content, nil
-	}
-	data, err := os.ReadFile(entry.Path) //nolint:gosec // Path is server-managed, not user-supplied
-	if err != nil {
-		return "", err
-	}
-	return string(data), nil
-}
-
 func findSearchMatches(content, needle string, contextLines, limit int) []searchMatch {
 	if needle == "" || limit <= 0 {
 		return nil
@@ -1911,6 +2366,27 @@ func handleFileRaw(state *State) http.HandlerFunc {
 			return
 		}
 
+		if entry.git != nil {
+			serveGitAsset(w, entry.git, r.PathValue("path"))
+			return
+		}
+
+		if entry.archive != nil {
+			state.serveArchiveAsset(w, entry.archive, r.PathValue("path"))
+			return
+		}
+
+		if entry.URL != "" {
+			// Let the browser fetch assets of a remote entry from their origin.
+			target, err := resolveRemote(entry.URL, r.PathValue("path"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Redirect(w, r, target, http.StatusFound)
+			return
+		}
+
 		if entry.Uploaded {
 			http.Error(w, "raw assets not available for uploaded files", http.StatusNotFound)
 			return
@@ -1922,6 +2398,12 @@ func handleFileRaw(state *State) http.HandlerFunc {
 
 		// No boundary check: mo serves local files to the user's own browser
 		// (like handleOpenFile); http.ServeFile already rejects "..".
+		if fi, err := os.Stat(absPath); err == nil && fi.Mode().IsRegular() {
+			state.trackAsset(entry.Path, absPath)
+		}
+		// Assets are watched and may be regenerated at any time, so make the
+		// browser revalidate instead of reusing a heuristically cached copy.
+		w.Header().Set("Cache-Control", "no-cache")
 		http.ServeFile(w, r, absPath)
 	}
 }
@@ -1946,6 +2428,50 @@ func handleOpenFile(state *State) http.HandlerFunc {
 			return
 		}
 
+		if entry.remote != nil {
+			state.mu.RLock()
+			src := entry.remote.src
+			state.mu.RUnlock()
+			target, err := resolveRemote(src.URL, req.Path)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			src.URL = target
+			newEntry, err := state.AddRemoteFile(r.Context(), src, groupName)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(newEntry); err != nil {
+				slog.Error("failed to encode response", "error", err)
+			}
+			return
+		}
+
+		if entry.archive != nil {
+			decodedPath, err := url.PathUnescape(req.Path)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			newEntry, err := state.openArchiveLink(entry.archive, groupName, decodedPath)
+			if err != nil {
+				if errors.Is(err, archive.ErrNotFound) {
+					http.Error(w, "file not found in archive", http.StatusNotFound)
+					return
+				}
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(newEntry); err != nil {
+				slog.Error("failed to encode response", "error", err)
+			}
+			return
+		}
+
 		if entry.Uploaded {
 			http.Error(w, "relative links not available for uploaded files", http.StatusBadRequest)
 			return
@@ -1994,7 +2520,14 @@ func handleAddPattern(state *State) http.HandlerFunc {
 			return
 		}
 
-		entries, err := state.AddPattern(req.Pattern, group)
+		for _, p := range req.Ignore {
+			if !doublestar.ValidatePattern(p) {
+				http.Error(w, fmt.Sprintf("invalid ignore pattern %q", p), http.StatusBadRequest)
+				return
+			}
+		}
+
+		entries, err := state.AddPatternWithIgnore(req.Pattern, group, req.Ignore)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -2030,6 +2563,46 @@ func handleRemovePattern(state *State) http.HandlerFunc {
 	}
 }
 
+func handleMovePattern(state *State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req movePatternRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		from, err := ResolveGroupName(req.From)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		to, err := ResolveGroupName(req.To)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if req.Copy {
+			var entries []*FileEntry
+			entries, err = state.CopyPattern(req.Pattern, from, to)
+			if err == nil {
+				w.Header().Set("Content-Type", "application/json")
+				if err := json.NewEncoder(w).Encode(AddPatternResponse{Matched: len(entries), Files: entries}); err != nil {
+					slog.Error("failed to encode response", "error", err)
+				}
+				return
+			}
+		} else if err = state.MovePattern(req.Pattern, from, to); err == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if errors.Is(err, ErrPatternNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusConflict)
+		}
+	}
+}
+
 func handleRestart(state *State) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		restoreFile, err := state.ExportState()
@@ -2080,11 +2653,13 @@ func handleStatus(state *State) http.HandlerFunc {
 			Revision string        `json:"revision"`
 			PID      int           `json:"pid"`
 			Groups   []statusGroup `json:"groups"`
+			Watcher  WatcherStatus `json:"watcher"`
 		}{
 			Version:  version.Version,
 			Revision: version.Revision,
 			PID:      os.Getpid(),
 			Groups:   statusGroups,
+			Watcher:  state.WatcherStatus(),
 		}
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
@@ -2140,7 +2715,7 @@ func handleSSE(state *State) http.HandlerFunc {
 	}
 }
 
-func handleSPA() http.HandlerFunc {
+func handleSPA(state *State) http.HandlerFunc {
 	distFS, err := fs.Sub(static.Frontend, "dist")
 	if err != nil {
 		slog.Error("failed to create sub filesystem", "error", err)
@@ -2158,11 +2733,16 @@ func handleSPA() http.HandlerFunc {
 		f, err := distFS.Open(strings.TrimPrefix(path, "/"))
 		if err == nil {
 			f.Close()
-			fileServer.ServeHTTP(w, r)
+			if path != "/index.html" || !state.serveIndex(w, distFS) {
+				fileServer.ServeHTTP(w, r)
+			}
 			return
 		}
 
 		// SPA fallback: serve index.html for all non-file routes
+		if state.serveIndex(w, distFS) {
+			return
+		}
 		r.URL.Path = "/"
 		fileServer.ServeHTTP(w, r)
 	}
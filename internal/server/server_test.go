@@ -29,19 +29,7 @@ var (
 
 func newTestState(t *testing.T) *State {
 	t.Helper()
-	ctx, cancel := context.WithCancel(context.Background())
-	t.Cleanup(cancel)
-	s := &State{
-		groups:             make(map[string]*Group),
-		subscribers:        make(map[chan sseEvent]struct{}),
-		restartCh:          make(chan string, 1),
-		shutdownCh:         make(chan struct{}, 1),
-		watchedDirs:        make(map[string]int),
-		fileChangeDebounce: defaultFileChangeDebounce,
-		fileChangeTimers:   make(map[string]*time.Timer),
-	}
-	_ = ctx
-	return s
+	return newState(nil)
 }
 
 func TestReorderFiles(t *testing.T) {
@@ -500,6 +488,36 @@ func TestAddPattern_InitialExpansion(t *testing.T) {
 	}
 }
 
+func TestAddPattern_ExtensionAlternation(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.md"), []byte("# A"), 0o600)       //nolint:errcheck
+	os.WriteFile(filepath.Join(dir, "b.qmd"), []byte("# B"), 0o600)      //nolint:errcheck
+	os.WriteFile(filepath.Join(dir, "c.txt"), []byte("text"), 0o600)     //nolint:errcheck
+	os.WriteFile(filepath.Join(dir, "d.go"), []byte("package d"), 0o600) //nolint:errcheck
+
+	s := newTestState(t)
+	pattern := filepath.Join(dir, "*.{md,qmd,txt}")
+	entries, err := s.AddPattern(pattern, DefaultGroup)
+	if err != nil {
+		t.Fatalf("AddPattern returned error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got matched=%d, want 3", len(entries))
+	}
+
+	// Files created later are matched against the same alternation.
+	newFile := filepath.Join(dir, "e.qmd")
+	os.WriteFile(newFile, []byte("# E"), 0o600) //nolint:errcheck
+	s.matchAndAddFile(newFile, s.Patterns())
+	if s.FindFile(FileID(newFile), DefaultGroup) == nil {
+		t.Fatalf("new file %s should be added via the pattern", newFile)
+	}
+
+	if got := s.PatternsForGroup(DefaultGroup); len(got) != 1 || got[0] != pattern {
+		t.Fatalf("got patterns %v, want [%s]", got, pattern)
+	}
+}
+
 func TestAddPattern_InitialExpansionNaturalOrder(t *testing.T) {
 	dir := t.TempDir()
 	for _, name := range []string{"i1.md", "i2.md", "i10.md", "i11.md"} {
@@ -668,6 +686,108 @@ func TestRemovePattern(t *testing.T) {
 	})
 }
 
+func TestMovePattern(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.md", "b.md", "c.md"} {
+		os.WriteFile(filepath.Join(dir, name), []byte("# "+name), 0o600) //nolint:errcheck
+	}
+	other := filepath.Join(t.TempDir(), "other.md")
+	os.WriteFile(other, []byte("# Other"), 0o600) //nolint:errcheck
+	pattern := filepath.Join(dir, "*.md")
+
+	t.Run("moves the pattern and its files in order", func(t *testing.T) {
+		s := newTestState(t)
+		if _, err := s.AddPattern(pattern, "a"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.AddFile(other, "a"); err != nil {
+			t.Fatal(err)
+		}
+		files := s.Groups()[0].Files
+		// Reorder so that the moved files keep an order that differs from a
+		// fresh expansion.
+		if !s.ReorderFiles("a", []string{files[2].ID, files[3].ID, files[0].ID, files[1].ID}) {
+			t.Fatal("ReorderFiles failed")
+		}
+
+		if err := s.MovePattern(pattern, "a", "b"); err != nil {
+			t.Fatal(err)
+		}
+		if ps := s.Patterns(); len(ps) != 1 || ps[0].Group != "b" {
+			t.Fatalf("unexpected patterns %+v", ps)
+		}
+		names := map[string][]string{}
+		for _, g := range s.Groups() {
+			for _, f := range g.Files {
+				names[g.Name] = append(names[g.Name], f.Name)
+			}
+		}
+		if got := strings.Join(names["b"], ","); got != "c.md,a.md,b.md" {
+			t.Errorf("got %s in b, want c.md,a.md,b.md", got)
+		}
+		if got := strings.Join(names["a"], ","); got != "other.md" {
+			t.Errorf("got %s in a, want other.md", got)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		s := newTestState(t)
+		if _, err := s.AddPattern(pattern, "a"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.AddPattern(pattern, "b"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.MovePattern(pattern, "a", "b"); err == nil {
+			t.Error("want an error when the target has the pattern")
+		}
+		if err := s.MovePattern("/nonexistent/*.md", "a", "c"); !errors.Is(err, ErrPatternNotFound) {
+			t.Errorf("got %v, want ErrPatternNotFound", err)
+		}
+		if err := s.MovePattern(pattern, "a", "a"); err == nil {
+			t.Error("want an error for the same group")
+		}
+	})
+}
+
+func TestHandleMovePattern_Copy(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.md"), []byte("# A"), 0o600)       //nolint:errcheck
+	os.WriteFile(filepath.Join(dir, "a.draft.md"), []byte("# A"), 0o600) //nolint:errcheck
+	pattern := filepath.Join(dir, "*.md")
+
+	s := newTestState(t)
+	if _, err := s.AddPatternWithIgnore(pattern, "a", []string{filepath.ToSlash(dir) + "/**/*.draft.md"}); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewHandler(s)
+	body, err := json.Marshal(movePatternRequest{Pattern: pattern, From: "a", To: "b", Copy: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("PUT", "/_/api/patterns/group", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp AddPatternResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Matched != 1 || resp.Files[0].Name != "a.md" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if got := len(s.Patterns()); got != 2 {
+		t.Errorf("got %d patterns, want 2", got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("PUT", "/_/api/patterns/group", bytes.NewReader(body)))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusConflict)
+	}
+}
+
 func TestGroupPersistsWithPatternsAfterFileRemoval(t *testing.T) {
 	dir := t.TempDir()
 	os.WriteFile(filepath.Join(dir, "a.md"), []byte("# A"), 0o600) //nolint:errcheck
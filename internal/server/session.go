@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// Snapshot returns the current state in the format of backups and named
+// sessions.
+func (s *State) Snapshot() RestoreData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snapshotRestoreData()
+}
+
+// Clear stops every review group and watch pattern and closes every file.
+func (s *State) Clear() {
+	for _, rd := range s.Reviews() {
+		s.RemoveReview(rd.Group)
+	}
+	for _, p := range s.Patterns() {
+		s.RemovePattern(p.Pattern, p.Group)
+	}
+	for _, g := range s.Groups() {
+		for _, f := range g.Files {
+			s.RemoveFile(f.ID, g.Name)
+		}
+	}
+}
+
+// RestoredEntry is an entry Restore opened in a group.
+type RestoredEntry struct {
+	Group string
+	Entry *FileEntry
+}
+
+// RestoreResult reports what Restore added. Entries lists the entries opened
+// from files, watch patterns, URLs fetched for the first time, reviews and
+// archives; uploaded files restored from their content are only counted.
+type RestoreResult struct {
+	Added   int
+	Entries []RestoredEntry
+	// FilesSkipped counts files that are gone and RemotesFailed URLs that
+	// could not be fetched.
+	FilesSkipped  int
+	RemotesFailed int
+	PatternsAdded int
+	ReviewsAdded  int
+	ArchivesAdded int
+}
+
+// Restore opens the files, watch patterns, uploaded files, reviews and
+// archives of data alongside the current state. Files that are gone are
+// skipped.
+func (s *State) Restore(ctx context.Context, data RestoreData) RestoreResult {
+	var res RestoreResult
+	for group, files := range data.Groups {
+		for _, f := range files {
+			entry, err := s.AddFile(f, group)
+			if err != nil {
+				res.FilesSkipped++
+				slog.Warn("skipping file", "path", f, "error", err)
+				continue
+			}
+			res.Entries = append(res.Entries, RestoredEntry{Group: group, Entry: entry})
+			res.Added++
+		}
+	}
+	for group, pats := range data.Patterns {
+		for _, pat := range pats {
+			entries, err := s.AddPatternWithIgnore(pat, group, data.PatternIgnores[group][pat])
+			if err != nil {
+				slog.Warn("failed to add pattern", "pattern", pat, "error", err)
+				continue
+			}
+			for _, entry := range entries {
+				res.Entries = append(res.Entries, RestoredEntry{Group: group, Entry: entry})
+			}
+			res.PatternsAdded++
+			res.Added++
+		}
+	}
+	for _, uf := range data.UploadedFiles {
+		switch {
+		case uf.Git != nil:
+			s.AddGitFile(*uf.Git, uf.Name, uf.Content, uf.Group)
+		case uf.Remote != nil && uf.Name == "":
+			// URLs given on the command line have not been fetched yet.
+			entry, err := s.AddRemoteFile(ctx, *uf.Remote, uf.Group)
+			if err != nil {
+				res.RemotesFailed++
+				slog.Warn("skipping remote file", "url", uf.Remote.URL, "error", err)
+				continue
+			}
+			res.Entries = append(res.Entries, RestoredEntry{Group: uf.Group, Entry: entry})
+		case uf.Remote != nil:
+			s.RestoreRemoteFile(*uf.Remote, uf.Name, uf.Content, uf.Group)
+		case uf.Named:
+			s.AddNamedFile(uf.Name, uf.Content, uf.Group)
+		default:
+			s.AddUploadedFile(uf.Name, uf.Content, uf.Group)
+		}
+		res.Added++
+	}
+	for _, rv := range data.Reviews {
+		entries, err := s.AddReview(rv)
+		if err != nil {
+			slog.Warn("failed to add review", "root", rv.Root, "base", rv.Base, "error", err)
+			continue
+		}
+		for _, entry := range entries {
+			res.Entries = append(res.Entries, RestoredEntry{Group: rv.Group, Entry: entry})
+		}
+		res.ReviewsAdded++
+		res.Added++
+	}
+	for _, ad := range data.Archives {
+		entries, err := s.AddArchive(ad)
+		if err != nil {
+			slog.Warn("failed to add archive", "path", ad.Path, "error", err)
+			continue
+		}
+		for _, entry := range entries {
+			res.Entries = append(res.Entries, RestoredEntry{Group: ad.Group, Entry: entry})
+		}
+		res.ArchivesAdded++
+		res.Added++
+	}
+	return res
+}
+
+type loadSessionRequest struct {
+	Data  RestoreData `json:"data"`
+	Merge bool        `json:"merge"`
+}
+
+// LoadSessionResponse reports how many items a loaded session added.
+type LoadSessionResponse struct {
+	Added int `json:"added"`
+}
+
+func handleSession(state *State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(state.Snapshot()); err != nil {
+			slog.Error("failed to encode response", "error", err)
+		}
+	}
+}
+
+func handleLoadSession(state *State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req loadSessionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !req.Merge {
+			state.Clear()
+		}
+		res := state.Restore(r.Context(), req.Data)
+		slog.Info("session loaded", "added", res.Added, "merge", req.Merge)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(LoadSessionResponse{Added: res.Added}); err != nil {
+			slog.Error("failed to encode response", "error", err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestHandleLoadSession(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"release.md", "welcome.md", "setup.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("# "+name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := newTestState(t)
+	handler := NewHandler(s)
+
+	if _, err := s.AddFile(filepath.Join(dir, "release.md"), "notes"); err != nil {
+		t.Fatal(err)
+	}
+	s.AddUploadedFile("draft.md", "# Draft", "notes")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/_/api/session", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d", rec.Code)
+	}
+	var saved RestoreData
+	if err := json.NewDecoder(rec.Body).Decode(&saved); err != nil {
+		t.Fatal(err)
+	}
+
+	openFiles := func() []string {
+		var names []string
+		for _, g := range s.Groups() {
+			for _, f := range g.Files {
+				names = append(names, g.Name+"/"+f.Name)
+			}
+		}
+		slices.Sort(names)
+		return names
+	}
+	load := func(t *testing.T, merge bool) LoadSessionResponse {
+		t.Helper()
+		body, err := json.Marshal(loadSessionRequest{Data: saved, Merge: merge})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("PUT", "/_/api/session", bytes.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp LoadSessionResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// Switch to another context.
+	s.Clear()
+	if _, err := s.AddPattern(filepath.Join(dir, "s*.md"), "onboarding"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddFile(filepath.Join(dir, "welcome.md"), "onboarding"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("merge", func(t *testing.T) {
+		if resp := load(t, true); resp.Added != 2 {
+			t.Errorf("got %d added, want 2", resp.Added)
+		}
+		want := []string{"notes/draft.md", "notes/release.md", "onboarding/setup.md", "onboarding/welcome.md"}
+		if got := openFiles(); !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		load(t, false)
+		want := []string{"notes/draft.md", "notes/release.md"}
+		if got := openFiles(); !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got := s.Patterns(); len(got) != 0 {
+			t.Errorf("got %d patterns, want none", len(got))
+		}
+	})
+}
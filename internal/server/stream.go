@@ -0,0 +1,248 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultStreamThrottle is the least time between two file-changed
+	// events of a stream.
+	defaultStreamThrottle = 250 * time.Millisecond
+	// maxStreamSize bounds the content kept for a stream; the oldest lines
+	// are dropped past it, as with an upload.
+	maxStreamSize = 10 << 20
+	// StreamEndedMarker is appended to a stream once its input closes.
+	StreamEndedMarker = "\n\n---\n\n> [!NOTE]\n> Stream ended.\n"
+)
+
+// ErrStreamNotFound is returned when appending to an entry that is not an
+// open stream.
+var ErrStreamNotFound = errors.New("stream not found")
+
+// stream is an uploaded entry that grows as input arrives. Its content is
+// kept here and copied to the entry at most once per streamThrottle.
+//
+// Each write to content bumps gen. Copies to the entry are made outside
+// streamMu, so applyMu and applied keep a copy of an older generation from
+// overwriting a newer one.
+type stream struct {
+	entry    *FileEntry
+	maxLines int
+	content  strings.Builder
+	gen      uint64
+	timer    *time.Timer
+
+	applyMu sync.Mutex
+	applied uint64
+}
+
+// AddStream adds an empty uploaded entry named name to group, to be grown
+// with AppendStream. When maxLines is positive, only the last maxLines lines
+// are kept.
+func (s *State) AddStream(name, groupName string, maxLines int) *FileEntry {
+	h := sha256.New()
+	h.Write([]byte("stream:"))
+	h.Write([]byte(name))
+	h.Write([]byte(strconv.FormatInt(time.Now().UnixNano(), 10)))
+	entry := s.addInMemoryFile(&FileEntry{
+		Name:     name,
+		ID:       "s" + hex.EncodeToString(h.Sum(nil))[:7],
+		Uploaded: true,
+	}, groupName)
+
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+	s.streams[entry.ID] = &stream{entry: entry, maxLines: maxLines}
+	return entry
+}
+
+// AppendStream appends content to the stream id. Clients are notified at
+// most once per throttle interval, and at once when end is set, which closes
+// the stream and appends StreamEndedMarker.
+func (s *State) AppendStream(id, content string, end bool) error {
+	s.streamMu.Lock()
+	st, ok := s.streams[id]
+	if !ok {
+		s.streamMu.Unlock()
+		return ErrStreamNotFound
+	}
+	st.content.WriteString(content)
+	st.trim()
+	st.gen++
+	if end {
+		delete(s.streams, id)
+		if st.timer != nil {
+			st.timer.Stop()
+		}
+		st.content.WriteString(StreamEndedMarker)
+		content, gen := st.content.String(), st.gen
+		s.streamMu.Unlock()
+		s.applyStream(st, content, gen)
+		slog.Info("stream ended", "name", st.entry.Name, "id", id)
+		return nil
+	}
+	if st.timer == nil {
+		st.timer = time.AfterFunc(s.streamThrottle, func() { s.flushStream(st) })
+	}
+	s.streamMu.Unlock()
+	return nil
+}
+
+// flushStream copies the content of st to its entry.
+func (s *State) flushStream(st *stream) {
+	content, gen, ok := s.snapshotStream(st)
+	if !ok {
+		return
+	}
+	s.applyStream(st, content, gen)
+}
+
+// snapshotStream returns the content of st and its generation for a flush,
+// or false once st has ended.
+func (s *State) snapshotStream(st *stream) (string, uint64, bool) {
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+	if _, ok := s.streams[st.entry.ID]; !ok {
+		// Ended, and flushed by AppendStream.
+		return "", 0, false
+	}
+	st.timer = nil
+	return st.content.String(), st.gen, true
+}
+
+// applyStream copies content, generation gen of st, to its entry unless a
+// newer generation was already copied.
+func (s *State) applyStream(st *stream, content string, gen uint64) {
+	st.applyMu.Lock()
+	defer st.applyMu.Unlock()
+	if gen <= st.applied {
+		return
+	}
+	st.applied = gen
+	s.replaceInMemoryContent(st.entry, content)
+}
+
+// closeStream stops the stream id, if it is one, once its entry is closed.
+func (s *State) closeStream(id string) {
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+	if st, ok := s.streams[id]; ok {
+		if st.timer != nil {
+			st.timer.Stop()
+		}
+		delete(s.streams, id)
+	}
+}
+
+// trim drops the oldest lines of st past its line window and the size
+// limit of a stream.
+func (st *stream) trim() {
+	c := st.content.String()
+	trimmed := c
+	if st.maxLines > 0 {
+		trimmed = lastLines(trimmed, st.maxLines)
+	}
+	if len(trimmed) > maxStreamSize {
+		trimmed = trimmed[len(trimmed)-maxStreamSize:]
+		if i := strings.IndexByte(trimmed, '\n'); i >= 0 {
+			trimmed = trimmed[i+1:]
+		}
+	}
+	if len(trimmed) != len(c) {
+		st.content.Reset()
+		st.content.WriteString(trimmed)
+	}
+}
+
+// lastLines returns the last n lines of s. A trailing partial line counts
+// as a line.
+func lastLines(s string, n int) string {
+	end := len(s)
+	if strings.HasSuffix(s, "\n") {
+		end--
+	}
+	for i := end - 1; i >= 0; i-- {
+		if s[i] == '\n' {
+			n--
+			if n == 0 {
+				return s[i+1:]
+			}
+		}
+	}
+	return s
+}
+
+type streamRequest struct {
+	Name     string `json:"name"`
+	MaxLines int    `json:"maxLines"`
+}
+
+type appendRequest struct {
+	Content string `json:"content"`
+	End     bool   `json:"end"`
+}
+
+func handleAddStream(state *State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		group, err := resolveGroupFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var req streamRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Name == "" {
+			http.Error(w, "missing file name", http.StatusBadRequest)
+			return
+		}
+		if req.MaxLines < 0 {
+			http.Error(w, "maxLines must not be negative", http.StatusBadRequest)
+			return
+		}
+		entry := state.AddStream(req.Name, group, req.MaxLines)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(entry); err != nil {
+			slog.Error("failed to encode response", "error", err)
+		}
+	}
+}
+
+func handleAppendStream(state *State) http.HandlerFunc {
+	const maxRequestSize = 12 << 20 // 12MB (headroom for JSON envelope)
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if id == "" {
+			http.Error(w, "missing file id", http.StatusBadRequest)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+		var req appendRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, "chunk too large (max 10MB)", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := state.AppendStream(id, req.Content, req.End); err != nil {
+			http.Error(w, fmt.Sprintf("%s: %s", err, id), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
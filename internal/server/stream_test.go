@@ -0,0 +1,161 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendStream(t *testing.T) {
+	s := newTestState(t)
+	s.streamThrottle = 50 * time.Millisecond
+	entry := s.AddStream("stdin.md", DefaultGroup, 0)
+
+	ch := s.Subscribe()
+	defer s.Unsubscribe(ch)
+
+	for _, chunk := range []string{"# Build\n", "step 1\n", "step 2\n"} {
+		if err := s.AppendStream(entry.ID, chunk, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The appends are coalesced into one file-changed event.
+	changed := 0
+	timeout := time.After(time.Second)
+	for changed == 0 {
+		select {
+		case e := <-ch:
+			if e.Name == eventFileChanged {
+				changed++
+			}
+		case <-timeout:
+			t.Fatal("no file-changed event was sent")
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	for len(ch) > 0 {
+		if e := <-ch; e.Name == eventFileChanged {
+			changed++
+		}
+	}
+	if changed != 1 {
+		t.Errorf("got %d file-changed events, want 1", changed)
+	}
+	if got, want := s.fileContent(entry), "# Build\nstep 1\nstep 2\n"; got != want {
+		t.Errorf("got content %q, want %q", got, want)
+	}
+
+	if err := s.AppendStream(entry.ID, "done\n", true); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.fileContent(entry), "# Build\nstep 1\nstep 2\ndone\n"+StreamEndedMarker; got != want {
+		t.Errorf("got content %q, want %q", got, want)
+	}
+	if err := s.AppendStream(entry.ID, "late\n", false); !errors.Is(err, ErrStreamNotFound) {
+		t.Errorf("got %v, want ErrStreamNotFound", err)
+	}
+}
+
+func TestAppendStream_LateFlush(t *testing.T) {
+	s := newTestState(t)
+	s.streamThrottle = time.Hour
+	entry := s.AddStream("stdin.md", DefaultGroup, 0)
+	if err := s.AppendStream(entry.ID, "step 1\n", false); err != nil {
+		t.Fatal(err)
+	}
+	s.streamMu.Lock()
+	st := s.streams[entry.ID]
+	s.streamMu.Unlock()
+
+	// The flush takes its copy, then the stream ends before the copy is
+	// written to the entry.
+	content, gen, ok := s.snapshotStream(st)
+	if !ok {
+		t.Fatal("stream ended before the flush")
+	}
+	if err := s.AppendStream(entry.ID, "done\n", true); err != nil {
+		t.Fatal(err)
+	}
+	s.applyStream(st, content, gen)
+
+	if got, want := s.fileContent(entry), "step 1\ndone\n"+StreamEndedMarker; got != want {
+		t.Errorf("got content %q, want %q", got, want)
+	}
+}
+
+func TestAppendStream_MaxLines(t *testing.T) {
+	s := newTestState(t)
+	entry := s.AddStream("stdin.md", DefaultGroup, 2)
+
+	for _, chunk := range []string{"one\ntwo\n", "three\nfo", "ur\n"} {
+		if err := s.AppendStream(entry.ID, chunk, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.AppendStream(entry.ID, "", true); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.fileContent(entry), "three\nfour\n"+StreamEndedMarker; got != want {
+		t.Errorf("got content %q, want %q", got, want)
+	}
+}
+
+func TestHandleStream(t *testing.T) {
+	s := newTestState(t)
+	handler := NewHandler(s)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/_/api/groups/logs/streams", strings.NewReader(`{"name":"build.md","maxLines":100}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+	}
+	var entry FileEntry
+	if err := json.NewDecoder(rec.Body).Decode(&entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Name != "build.md" || !entry.Uploaded {
+		t.Errorf("got %+v", entry)
+	}
+
+	appendChunk := func(req appendRequest) int {
+		body, err := json.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/_/api/groups/logs/files/"+entry.ID+"/append", bytes.NewReader(body)))
+		return rec.Code
+	}
+	if code := appendChunk(appendRequest{Content: "ok\n", End: true}); code != http.StatusNoContent {
+		t.Errorf("got status %d, want %d", code, http.StatusNoContent)
+	}
+	if code := appendChunk(appendRequest{Content: "late\n"}); code != http.StatusNotFound {
+		t.Errorf("got status %d after end, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestRemoveFile_ClosesStream(t *testing.T) {
+	s := newTestState(t)
+	entry := s.AddStream("stdin.md", DefaultGroup, 0)
+	if err := s.AppendStream(entry.ID, "line\n", false); err != nil {
+		t.Fatal(err)
+	}
+	if !s.RemoveFile(entry.ID, DefaultGroup) {
+		t.Fatal("RemoveFile returned false")
+	}
+	if err := s.AppendStream(entry.ID, "line\n", false); !errors.Is(err, ErrStreamNotFound) {
+		t.Errorf("got %v, want ErrStreamNotFound", err)
+	}
+}
+
+func (s *State) fileContent(entry *FileEntry) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return entry.content
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/k1LoW/mo/internal/csvtable"
+)
+
+const (
+	defaultTablePageSize = 500
+	maxTablePageSize     = 5000
+	// tableContentLimit is the largest file whose raw text is sent along
+	// with its first page; bigger files are only served page by page.
+	tableContentLimit = 1 << 20
+)
+
+// TableData is the table model of a CSV or TSV file, served one page of rows
+// at a time by the content API.
+type TableData struct {
+	Delimiter string     `json:"delimiter"`
+	Header    []string   `json:"header,omitempty"` // empty when the first row is data
+	Columns   int        `json:"columns"`
+	Rows      [][]string `json:"rows"`
+	TotalRows int        `json:"totalRows"`
+	Offset    int        `json:"offset"`
+	Limit     int        `json:"limit"`
+	Sort      *int       `json:"sort,omitempty"` // column the rows are sorted by
+	Desc      bool       `json:"desc,omitempty"`
+}
+
+// tableFormat reports whether entry is a CSV or TSV file, judged by the name
+// of the file it was read from.
+func tableFormat(entry *FileEntry) (isTable, tsv bool) {
+	name := entry.Name
+	switch {
+	case entry.git != nil:
+		name = entry.git.Path
+	case entry.Path != "":
+		name = entry.Path
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return true, false
+	case ".tsv":
+		return true, true
+	}
+	return false, false
+}
+
+func isTablePath(path string) bool {
+	isTable, _ := tableFormat(&FileEntry{Path: path})
+	return isTable
+}
+
+// tableOrder is a sort order of table rows.
+type tableOrder struct {
+	col  int
+	desc bool
+}
+
+// cachedTable is the parsed table of an entry, kept between page requests
+// along with its rows in each order requested so far.
+type cachedTable struct {
+	content string          // text the table was parsed from
+	table   *csvtable.Table // nil when content is not a table
+	sorted  map[tableOrder][][]string
+}
+
+// newTableData returns the page of rows of entry id selected by the offset,
+// limit, sort and desc query parameters. It returns nil when content cannot
+// be parsed as a table.
+func (s *State) newTableData(id, content string, tsv bool, q url.Values) (*TableData, error) {
+	offset, err := intParam(q, "offset", 0)
+	if err != nil || offset < 0 {
+		return nil, fmt.Errorf("invalid offset: %q", q.Get("offset"))
+	}
+	limit, err := intParam(q, "limit", defaultTablePageSize)
+	if err != nil || limit <= 0 {
+		return nil, fmt.Errorf("invalid limit: %q", q.Get("limit"))
+	}
+	limit = min(limit, maxTablePageSize)
+	sortCol, err := intParam(q, "sort", -1)
+	if err != nil || sortCol < -1 {
+		return nil, fmt.Errorf("invalid sort column: %q", q.Get("sort"))
+	}
+	desc := q.Get("desc") == "true" || q.Get("desc") == "1"
+
+	t, rows := s.tableRows(id, content, tsv, tableOrder{col: sortCol, desc: desc})
+	if t == nil {
+		// Not a table after all; the file is shown as plain text.
+		return nil, nil
+	}
+	td := &TableData{
+		Delimiter: string(t.Delimiter),
+		Header:    t.Header,
+		Columns:   t.Columns,
+		TotalRows: len(rows),
+		Offset:    offset,
+		Limit:     limit,
+	}
+	if sortCol >= 0 && sortCol < t.Columns {
+		td.Sort = &sortCol
+		td.Desc = desc
+	}
+	start := min(offset, len(rows))
+	td.Rows = rows[start:min(start+limit, len(rows))]
+	return td, nil
+}
+
+// tableRows returns the parsed table of entry id and its rows in order,
+// parsing and sorting only what is not cached yet. A cached table is used
+// only while content is unchanged, so a stale one is never served before
+// the file-changed notification drops it.
+func (s *State) tableRows(id, content string, tsv bool, order tableOrder) (*csvtable.Table, [][]string) {
+	s.tableMu.Lock()
+	c, ok := s.tables[id]
+	s.tableMu.Unlock()
+	if !ok || c.content != content {
+		t, err := csvtable.Parse([]byte(content), tsv)
+		if err != nil {
+			t = nil
+		}
+		c = &cachedTable{content: content, table: t, sorted: make(map[tableOrder][][]string)}
+		s.tableMu.Lock()
+		s.tables[id] = c
+		s.tableMu.Unlock()
+	}
+	if c.table == nil {
+		return nil, nil
+	}
+	if order.col < 0 || order.col >= c.table.Columns {
+		return c.table, c.table.Rows
+	}
+
+	s.tableMu.Lock()
+	rows, ok := c.sorted[order]
+	s.tableMu.Unlock()
+	if !ok {
+		sorted := &csvtable.Table{Columns: c.table.Columns, Rows: slices.Clone(c.table.Rows)}
+		sorted.Sort(order.col, order.desc)
+		rows = sorted.Rows
+		s.tableMu.Lock()
+		c.sorted[order] = rows
+		s.tableMu.Unlock()
+	}
+	return c.table, rows
+}
+
+// forgetTables drops the cached tables of the given entries.
+func (s *State) forgetTables(ids ...string) {
+	s.tableMu.Lock()
+	defer s.tableMu.Unlock()
+	for _, id := range ids {
+		delete(s.tables, id)
+	}
+}
+
+func intParam(q url.Values, key string, def int) (int, error) {
+	v := q.Get(key)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
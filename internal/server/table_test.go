@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestTableContent(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "scores.csv")
+	if err := os.WriteFile(p, []byte("name;score\nbob;7\nalice;12\ncarol;9\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestState(t)
+	handler := NewHandler(s)
+	entry, err := s.AddFile(p, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(t *testing.T, query string) (int, fileContentResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/_/api/groups/default/files/"+entry.ID+"/content"+query, nil))
+		var resp fileContentResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec.Code, resp
+	}
+
+	t.Run("first page", func(t *testing.T) {
+		code, resp := get(t, "")
+		if code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", code, http.StatusOK)
+		}
+		if resp.Content == "" {
+			t.Error("content of a small file should be included")
+		}
+		tbl := resp.Table
+		if tbl == nil {
+			t.Fatal("no table in response")
+		}
+		if tbl.Delimiter != ";" || !slices.Equal(tbl.Header, []string{"name", "score"}) || tbl.TotalRows != 3 {
+			t.Errorf("unexpected table %+v", tbl)
+		}
+	})
+
+	t.Run("sorted page", func(t *testing.T) {
+		code, resp := get(t, "?offset=1&limit=1&sort=1&desc=true")
+		if code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", code, http.StatusOK)
+		}
+		if resp.Content != "" {
+			t.Error("paging requests should not include content")
+		}
+		tbl := resp.Table
+		if tbl == nil || len(tbl.Rows) != 1 || tbl.Rows[0][0] != "carol" {
+			t.Errorf("got %+v, want the second row by descending score", tbl)
+		}
+		if tbl != nil && (tbl.Sort == nil || *tbl.Sort != 1 || !tbl.Desc) {
+			t.Errorf("sort is not echoed: %+v", tbl)
+		}
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		if code, _ := get(t, "?limit=0"); code != http.StatusBadRequest {
+			t.Errorf("got status %d, want %d", code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestTableUTF16(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "export.tsv")
+	data := []byte{0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune("id\tcity\n1\tZürich\n")) {
+		data = append(data, byte(u), byte(u>>8))
+	}
+	if err := os.WriteFile(p, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestState(t)
+	entry, err := s.AddFile(p, "default")
+	if err != nil {
+		t.Fatalf("UTF-16 table rejected: %v", err)
+	}
+	content, err := s.readSearchableContent(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "id\tcity\n1\tZürich\n" {
+		t.Errorf("got %q", content)
+	}
+}
+
+func TestTableCache(t *testing.T) {
+	s := newTestState(t)
+	const id = "t1"
+	content := "name,score\nbob,7\nalice,12\n"
+	q := url.Values{"sort": {"1"}}
+
+	first, err := s.newTableData(id, content, false, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.newTableData(id, content, false, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &first.Rows[0][0] != &second.Rows[0][0] {
+		t.Error("sorted rows were not reused")
+	}
+
+	// Changed content is parsed again even before the cache is dropped.
+	changed, err := s.newTableData(id, content+"carol,1\n", false, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.TotalRows != 3 || changed.Rows[0][0] != "carol" {
+		t.Errorf("got rows %v", changed.Rows)
+	}
+
+	s.notifyFileChanged([]string{id}, nil)
+	s.tableMu.Lock()
+	_, cached := s.tables[id]
+	s.tableMu.Unlock()
+	if cached {
+		t.Error("table is still cached after file-changed")
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// ErrNotUploaded is returned when replacing the content of an entry that is
+// not a plain uploaded file (e.g. a file on disk, one read from git or an
+// archive, or an open stream).
+var ErrNotUploaded = errors.New("not an uploaded file")
+
+// AddNamedFile adds an uploaded file whose ID is derived from its group and
+// name rather than its content. Uploading under the same name to the same
+// group again replaces the content of that entry in place.
+func (s *State) AddNamedFile(name, content, groupName string) *FileEntry {
+	h := sha256.New()
+	h.Write([]byte("named:"))
+	h.Write([]byte(groupName))
+	h.Write([]byte{0})
+	h.Write([]byte(name))
+	entry := s.addInMemoryFile(&FileEntry{
+		Name:     name,
+		ID:       "n" + hex.EncodeToString(h.Sum(nil))[:7],
+		Uploaded: true,
+		content:  content,
+		named:    true,
+	}, groupName)
+	// A no-op for a new entry.
+	s.replaceInMemoryContent(entry, content)
+	return entry
+}
+
+// ReplaceContent replaces the content of the uploaded file id in group,
+// keeping its ID, and notifies clients when it changed.
+func (s *State) ReplaceContent(id, groupName, content string) (*FileEntry, error) {
+	entry := s.FindFile(id, groupName)
+	if entry == nil {
+		return nil, ErrFileNotFound
+	}
+	s.mu.RLock()
+	uploaded := entry.Uploaded && entry.git == nil && entry.remote == nil && entry.archive == nil
+	s.mu.RUnlock()
+	s.streamMu.Lock()
+	_, streaming := s.streams[id]
+	s.streamMu.Unlock()
+	if !uploaded || streaming {
+		return nil, ErrNotUploaded
+	}
+	if s.replaceInMemoryContent(entry, content) {
+		slog.Info("uploaded file replaced", "name", entry.Name, "group", groupName, "id", id) //nolint:gosec // G706: structured logging fields, no injection risk
+	}
+	return entry, nil
+}
+
+type replaceContentRequest struct {
+	Content string `json:"content"`
+}
+
+func handleUpdateContent(state *State) http.HandlerFunc {
+	const maxRequestSize = 12 << 20 // 12MB (headroom for JSON envelope)
+	const maxContentSize = 10 << 20 // 10MB
+	return func(w http.ResponseWriter, r *http.Request) {
+		group, err := resolveGroupFromPath(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		id := r.PathValue("id")
+		if id == "" {
+			http.Error(w, "missing file id", http.StatusBadRequest)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+		var req replaceContentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, "file too large (max 10MB)", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(req.Content) > maxContentSize {
+			http.Error(w, "file too large (max 10MB)", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		entry, err := state.ReplaceContent(id, group, req.Content)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrFileNotFound):
+				http.Error(w, err.Error(), http.StatusNotFound)
+			case errors.Is(err, ErrNotUploaded):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(entry); err != nil {
+			slog.Error("failed to encode response", "error", err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddNamedFile(t *testing.T) {
+	s := newTestState(t)
+	first := s.AddNamedFile("report.md", "# Report\n\nv1\n", "ci")
+
+	ch := s.Subscribe()
+	defer s.Unsubscribe(ch)
+
+	second := s.AddNamedFile("report.md", "# Report\n\nv2\n", "ci")
+	if second.ID != first.ID {
+		t.Errorf("got ID %q, want %q", second.ID, first.ID)
+	}
+	if got := s.fileContent(second); got != "# Report\n\nv2\n" {
+		t.Errorf("got content %q", got)
+	}
+	if n := len(s.Groups()[0].Files); n != 1 {
+		t.Errorf("got %d files, want 1", n)
+	}
+	changed := false
+	for len(ch) > 0 {
+		if e := <-ch; e.Name == eventFileChanged {
+			changed = true
+		}
+	}
+	if !changed {
+		t.Error("no file-changed event was sent")
+	}
+
+	// The same name in another group is another entry.
+	if other := s.AddNamedFile("report.md", "# Report\n", "nightly"); other.ID == first.ID {
+		t.Errorf("got ID %q in another group, want a different one", other.ID)
+	}
+
+	// The ID survives a restart.
+	data := s.Snapshot()
+	restored := newTestState(t)
+	restored.Restore(context.Background(), data)
+	if restored.FindFile(first.ID, "ci") == nil {
+		t.Errorf("%s was not restored under the same ID", first.ID)
+	}
+	if again := restored.AddNamedFile("report.md", "# Report\n\nv3\n", "ci"); again.ID != first.ID {
+		t.Errorf("got ID %q after restore, want %q", again.ID, first.ID)
+	}
+}
+
+func TestHandleUpdateContent(t *testing.T) {
+	s := newTestState(t)
+	handler := NewHandler(s)
+	uploaded := s.AddUploadedFile("stdin.md", "# Old", DefaultGroup)
+	p := filepath.Join(t.TempDir(), "README.md")
+	if err := os.WriteFile(p, []byte("# Readme"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	onDisk, err := s.AddFile(p, DefaultGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archived := s.addArchiveFile(ArchiveData{Group: DefaultGroup, Path: filepath.Join(t.TempDir(), "docs.zip")}, "index.md", "# Index")
+	streaming := s.AddStream("build.log", DefaultGroup, 0)
+
+	put := func(id string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("PUT", "/_/api/groups/default/files/"+id+"/content", strings.NewReader(`{"content":"# New"}`)))
+		return rec.Code
+	}
+	if code := put(uploaded.ID); code != http.StatusOK {
+		t.Fatalf("got status %d", code)
+	}
+	if got := s.fileContent(uploaded); got != "# New" {
+		t.Errorf("got content %q", got)
+	}
+	if got := s.FindFile(uploaded.ID, DefaultGroup); got == nil || got.Title != "New" {
+		t.Errorf("got %+v, want the entry with title New", got)
+	}
+	if code := put(onDisk.ID); code != http.StatusConflict {
+		t.Errorf("got status %d for a file on disk, want %d", code, http.StatusConflict)
+	}
+	if code := put(archived.ID); code != http.StatusConflict {
+		t.Errorf("got status %d for an archive entry, want %d", code, http.StatusConflict)
+	}
+	if code := put(streaming.ID); code != http.StatusConflict {
+		t.Errorf("got status %d for an open stream, want %d", code, http.StatusConflict)
+	}
+	if code := put("missing"); code != http.StatusNotFound {
+		t.Errorf("got status %d for an unknown file, want %d", code, http.StatusNotFound)
+	}
+}
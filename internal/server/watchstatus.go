@@ -0,0 +1,137 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// maxWatchErrors is the number of recent watcher errors kept for diagnostics.
+const maxWatchErrors = 20
+
+// watchLimitHint and instanceLimitHint are reported when the OS refused more
+// watches. On Linux inotify returns ENOSPC once max_user_watches is reached
+// and EMFILE once max_user_instances is.
+const (
+	watchLimitHint    = "the OS watch limit was reached; on Linux raise fs.inotify.max_user_watches (e.g. sudo sysctl fs.inotify.max_user_watches=524288) and restart mo"
+	instanceLimitHint = "the OS watcher instance limit was reached; on Linux raise fs.inotify.max_user_instances (e.g. sudo sysctl fs.inotify.max_user_instances=1024) and restart mo"
+)
+
+// WatchError is a single watcher failure kept for diagnostics.
+type WatchError struct {
+	Time  time.Time `json:"time"`
+	Path  string    `json:"path,omitempty"`
+	Error string    `json:"error"`
+}
+
+// WatcherStatus summarizes the health of the file watcher. It is exposed via
+// GET /_/api/status so that broken live-reload can be diagnosed without
+// reading the log file.
+type WatcherStatus struct {
+	Available    bool         `json:"available"`
+	WatchedDirs  int          `json:"watchedDirs"`
+	WatchedFiles int          `json:"watchedFiles"`
+	FailedPaths  []WatchError `json:"failedPaths,omitempty"`
+	RecentErrors []WatchError `json:"recentErrors,omitempty"`
+	LimitReached bool         `json:"limitReached"`
+	Hint         string       `json:"hint,omitempty"`
+}
+
+// watchDiagnostics records watcher failures. It has its own mutex so it can be
+// updated both from code paths holding State.mu and from the watch loop.
+type watchDiagnostics struct {
+	mu     sync.Mutex
+	failed map[string]WatchError // path → most recent failure
+	recent []WatchError          // ring of the latest maxWatchErrors errors
+	// watchLimit and instanceLimit record that ENOSPC and EMFILE were seen.
+	watchLimit    bool
+	instanceLimit bool
+}
+
+// recordFailure remembers that watching path failed.
+func (d *watchDiagnostics) recordFailure(path string, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	we := WatchError{Time: time.Now(), Path: path, Error: err.Error()}
+	if d.failed == nil {
+		d.failed = make(map[string]WatchError)
+	}
+	d.failed[path] = we
+	d.appendLocked(we, err)
+}
+
+// recordError remembers an error reported by the watcher that is not tied to
+// a specific Add call (e.g. a queue overflow).
+func (d *watchDiagnostics) recordError(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.appendLocked(WatchError{Time: time.Now(), Error: err.Error()}, err)
+}
+
+// clear forgets any failure recorded for path, e.g. after a successful
+// re-watch or once the path is no longer tracked.
+func (d *watchDiagnostics) clear(path string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.failed, path)
+}
+
+func (d *watchDiagnostics) appendLocked(we WatchError, err error) {
+	switch {
+	case errors.Is(err, syscall.ENOSPC):
+		d.watchLimit = true
+	case errors.Is(err, syscall.EMFILE):
+		d.instanceLimit = true
+	}
+	d.recent = append(d.recent, we)
+	if len(d.recent) > maxWatchErrors {
+		d.recent = slices.Delete(d.recent, 0, len(d.recent)-maxWatchErrors)
+	}
+}
+
+// snapshot copies the recorded failures into st.
+func (d *watchDiagnostics) snapshot(st *WatcherStatus) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, we := range d.failed {
+		st.FailedPaths = append(st.FailedPaths, we)
+	}
+	sort.Slice(st.FailedPaths, func(i, j int) bool {
+		return st.FailedPaths[i].Path < st.FailedPaths[j].Path
+	})
+	st.RecentErrors = slices.Clone(d.recent)
+	st.LimitReached = d.watchLimit || d.instanceLimit
+	var hints []string
+	if d.watchLimit {
+		hints = append(hints, watchLimitHint)
+	}
+	if d.instanceLimit {
+		hints = append(hints, instanceLimitHint)
+	}
+	st.Hint = strings.Join(hints, "; ")
+}
+
+// watchFailed logs and records a failed watcher.Add for path.
+func (s *State) watchFailed(msg, path string, err error) {
+	slog.Warn(msg, "path", path, "error", err)
+	s.watchDiag.recordFailure(path, err)
+}
+
+// WatcherStatus returns a snapshot of the watcher's health.
+func (s *State) WatcherStatus() WatcherStatus {
+	s.mu.RLock()
+	st := WatcherStatus{
+		Available:    s.watcher != nil,
+		WatchedDirs:  len(s.watchedDirs),
+		WatchedFiles: len(s.watchedFiles),
+	}
+	s.mu.RUnlock()
+
+	s.watchDiag.snapshot(&st)
+	return st
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/k1LoW/donegroup"
+)
+
+func TestWatcherStatus(t *testing.T) {
+	t.Run("reports watched dirs and files", func(t *testing.T) {
+		s := newTestState(t)
+		s.watchedDirs["/docs"] = 2
+		s.watchedFiles["/docs/a.md"] = struct{}{}
+		s.watchedFiles["/docs/b.md"] = struct{}{}
+
+		st := s.WatcherStatus()
+		if st.WatchedDirs != 1 {
+			t.Errorf("got watchedDirs=%d, want 1", st.WatchedDirs)
+		}
+		if st.WatchedFiles != 2 {
+			t.Errorf("got watchedFiles=%d, want 2", st.WatchedFiles)
+		}
+		if st.Available {
+			t.Error("watcher should be reported unavailable without a watcher")
+		}
+		if st.LimitReached || st.Hint != "" {
+			t.Errorf("got limitReached=%v hint=%q, want no limit", st.LimitReached, st.Hint)
+		}
+	})
+
+	t.Run("records failed paths and clears them", func(t *testing.T) {
+		s := newTestState(t)
+		s.watchFailed("failed to watch file", "/docs/a.md", errors.New("permission denied"))
+
+		st := s.WatcherStatus()
+		if len(st.FailedPaths) != 1 || st.FailedPaths[0].Path != "/docs/a.md" {
+			t.Fatalf("got failedPaths=%v, want /docs/a.md", st.FailedPaths)
+		}
+		if len(st.RecentErrors) != 1 {
+			t.Fatalf("got %d recent errors, want 1", len(st.RecentErrors))
+		}
+
+		s.watchDiag.clear("/docs/a.md")
+		st = s.WatcherStatus()
+		if len(st.FailedPaths) != 0 {
+			t.Errorf("got failedPaths=%v, want none after clear", st.FailedPaths)
+		}
+		if len(st.RecentErrors) != 1 {
+			t.Errorf("recent errors should be kept after clear, got %d", len(st.RecentErrors))
+		}
+	})
+
+	t.Run("detects inotify limit", func(t *testing.T) {
+		s := newTestState(t)
+		err := fmt.Errorf("fswatcher: add /docs: %w", syscall.ENOSPC)
+		s.watchFailed("failed to watch directory", "/docs", err)
+
+		st := s.WatcherStatus()
+		if !st.LimitReached {
+			t.Fatal("limitReached should be true for ENOSPC")
+		}
+		if st.Hint != watchLimitHint {
+			t.Errorf("got hint %q, want %q", st.Hint, watchLimitHint)
+		}
+	})
+
+	t.Run("detects inotify instance limit", func(t *testing.T) {
+		s := newTestState(t)
+		err := fmt.Errorf("fswatcher: %w", syscall.EMFILE)
+		s.watchDiag.recordError(err)
+
+		st := s.WatcherStatus()
+		if !st.LimitReached {
+			t.Fatal("limitReached should be true for EMFILE")
+		}
+		if st.Hint != instanceLimitHint {
+			t.Errorf("got hint %q, want %q", st.Hint, instanceLimitHint)
+		}
+	})
+
+	t.Run("keeps only the latest errors", func(t *testing.T) {
+		s := newTestState(t)
+		for i := range maxWatchErrors + 5 {
+			s.watchDiag.recordError(fmt.Errorf("error %d", i))
+		}
+		st := s.WatcherStatus()
+		if len(st.RecentErrors) != maxWatchErrors {
+			t.Fatalf("got %d recent errors, want %d", len(st.RecentErrors), maxWatchErrors)
+		}
+		want := fmt.Sprintf("error %d", maxWatchErrors+4)
+		if got := st.RecentErrors[len(st.RecentErrors)-1].Error; got != want {
+			t.Errorf("got last error %q, want %q", got, want)
+		}
+	})
+}
+
+func TestHandleStatus_IncludesWatcher(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.md"), []byte("# A"), 0o600) //nolint:errcheck
+
+	ctx, cancel := donegroup.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewState(ctx)
+	t.Cleanup(s.CloseAllSubscribers)
+	if _, err := s.AddPattern(filepath.Join(dir, "*.md"), DefaultGroup); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(s)
+	req := httptest.NewRequest("GET", "/_/api/status", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	var resp struct {
+		Watcher WatcherStatus `json:"watcher"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Watcher.Available {
+		t.Fatal("watcher should be available")
+	}
+	if resp.Watcher.WatchedDirs != 1 {
+		t.Errorf("got watchedDirs=%d, want 1", resp.Watcher.WatchedDirs)
+	}
+	if resp.Watcher.WatchedFiles != 1 {
+		t.Errorf("got watchedFiles=%d, want 1", resp.Watcher.WatchedFiles)
+	}
+}
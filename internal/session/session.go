@@ -0,0 +1,148 @@
+// Package session stores named snapshots of a mo server's state, so that a
+// set of groups, files and watch patterns can be saved and brought back
+// later. Snapshots use the same format as backups.
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/k1LoW/mo/internal/atomicfile"
+	"github.com/k1LoW/mo/internal/xdg"
+)
+
+const ext = ".json"
+
+// ErrNotFound is returned when no session has the given name.
+var ErrNotFound = errors.New("session not found")
+
+var nameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Info describes a saved session.
+type Info struct {
+	Name    string
+	ModTime time.Time
+}
+
+// Dir returns the path to the session directory.
+func Dir() (string, error) {
+	stateHome, err := xdg.StateHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateHome, "mo", "sessions"), nil
+}
+
+// ValidateName reports whether name can name a session: letters, digits,
+// dots, hyphens and underscores, not starting with a dot, hyphen or
+// underscore.
+func ValidateName(name string) error {
+	if !nameRe.MatchString(name) {
+		return fmt.Errorf("invalid session name %q: use letters, digits, '.', '-' and '_'", name)
+	}
+	return nil
+}
+
+// Path returns the file path of the session name.
+func Path(name string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+ext), nil
+}
+
+// Save atomically writes data as the session name, replacing any session of
+// the same name.
+func Save(name string, data any) error {
+	p, err := Path(name)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(p)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session data: %w", err)
+	}
+
+	return atomicfile.Write(p, "mo-session-*.tmp", b)
+}
+
+// Load reads the session name into dest. It returns ErrNotFound if there is
+// no such session.
+func Load(name string, dest any) error {
+	p, err := Path(name)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(p) //nolint:gosec // Path is validated by Path
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return fmt.Errorf("failed to read session file: %w", err)
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		return fmt.Errorf("failed to unmarshal session data: %w", err)
+	}
+	return nil
+}
+
+// List returns the saved sessions ordered by name.
+func List() ([]Info, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var sessions []Info
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ext)
+		if !ok || !e.Type().IsRegular() || ValidateName(name) != nil {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, Info{Name: name, ModTime: fi.ModTime()})
+	}
+	slices.SortFunc(sessions, func(a, b Info) int { return strings.Compare(a.Name, b.Name) })
+	return sessions, nil
+}
+
+// Remove deletes the session name. It returns ErrNotFound if there is no
+// such session.
+func Remove(name string) error {
+	p, err := Path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return fmt.Errorf("failed to remove session file: %w", err)
+	}
+	return nil
+}
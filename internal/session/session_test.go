@@ -0,0 +1,89 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testData struct {
+	Groups map[string][]string `json:"groups"`
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	data := testData{Groups: map[string][]string{"notes": {"/path/to/release.md"}}}
+	if err := Save("release-notes", data); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	var loaded testData
+	if err := Load("release-notes", &loaded); err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if got := loaded.Groups["notes"]; len(got) != 1 || got[0] != "/path/to/release.md" {
+		t.Errorf("got %v", loaded.Groups)
+	}
+
+	var missing testData
+	if err := Load("onboarding", &missing); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	stateHome := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", stateHome)
+
+	sessions, err := List()
+	if err != nil || len(sessions) != 0 {
+		t.Fatalf("got %v, %v; want no sessions", sessions, err)
+	}
+
+	for _, name := range []string{"release-notes", "onboarding"} {
+		if err := Save(name, testData{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Leftover temp files are not sessions.
+	if err := os.WriteFile(filepath.Join(stateHome, "mo", "sessions", "mo-session-1.tmp"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err = List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 || sessions[0].Name != "onboarding" || sessions[1].Name != "release-notes" {
+		t.Errorf("got %+v", sessions)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	if err := Save("onboarding", testData{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove("onboarding"); err != nil {
+		t.Fatalf("Remove returned error: %v", err)
+	}
+	if err := Remove("onboarding"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"onboarding", "release-notes", "v1.2_draft"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", ".hidden", "../escape", "a/b", "with space"} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("ValidateName(%q) succeeded, want an error", name)
+		}
+	}
+}
@@ -16,3 +16,15 @@ func StateHome() (string, error) {
 	}
 	return filepath.Join(home, ".local", "state"), nil
 }
+
+// ConfigHome returns $XDG_CONFIG_HOME, defaulting to ~/.config.
+func ConfigHome() (string, error) {
+	if v := os.Getenv("XDG_CONFIG_HOME"); v != "" {
+		return v, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config"), nil
+}